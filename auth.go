@@ -0,0 +1,219 @@
+package main
+
+import (
+    "encoding/base64"
+    "fmt"
+    "log/slog"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/PuerkitoBio/goquery"
+    "github.com/valyala/fasthttp"
+)
+
+// forumAuth 附加到每个论坛请求上的登录信息，用于访问需要登录才能查看的版块
+type forumAuth struct {
+    // Cookie 是浏览器中复制的会话 Cookie，格式为 "name=value; name2=value2"
+    Cookie   string
+    Username string
+    Password string
+}
+
+// fetchAuth 全局的论坛登录信息，为零值时不附加任何认证
+var fetchAuth forumAuth
+
+// parseBasicAuth 解析 "user:pass" 形式的 Basic 认证信息
+func parseBasicAuth(s string) (string, string, error) {
+    user, pass, ok := strings.Cut(s, ":")
+    if !ok || user == "" {
+        return "", "", fmt.Errorf("invalid basic auth: expected user:pass")
+    }
+    return user, pass, nil
+}
+
+// apply 将 Cookie 和 Basic 认证写入请求头
+func (a forumAuth) apply(req *fasthttp.Request) {
+    for _, pair := range strings.Split(a.Cookie, ";") {
+        name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+        if ok && name != "" {
+            req.Header.SetCookie(name, value)
+        }
+    }
+    if a.Username != "" {
+        credentials := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+        req.Header.Set("Authorization", "Basic "+credentials)
+    }
+}
+
+// discuzSession 使用论坛账号登录 Discuz 并保存登录后得到的 Cookie，会话过期时自动重新登录
+type discuzSession struct {
+    Username string
+    Password string
+
+    // loginMu 保证同一时间只有一个登录请求
+    loginMu sync.Mutex
+    mu      sync.Mutex
+    // cookies 按站点保存 Cookie
+    cookies map[string]map[string]string
+}
+
+// forumSession 全局的论坛登录会话，未配置论坛账号时为 nil
+var forumSession *discuzSession
+
+// newDiscuzSession 创建尚未登录的会话
+func newDiscuzSession(username, password string) *discuzSession {
+    return &discuzSession{
+        Username: username,
+        Password: password,
+        cookies:  make(map[string]map[string]string),
+    }
+}
+
+// apply 将请求站点的会话 Cookie 写入请求头。s 为 nil 时不做任何事
+func (s *discuzSession) apply(req *fasthttp.Request) {
+    if s == nil {
+        return
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for name, value := range s.cookies[string(req.URI().Host())] {
+        req.Header.SetCookie(name, value)
+    }
+}
+
+// store 保存响应中 Set-Cookie 设置的 Cookie，删除已过期的 Cookie。s 为 nil 时不做任何事
+func (s *discuzSession) store(host string, resp *fasthttp.Response) {
+    if s == nil {
+        return
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    jar := s.cookies[host]
+    if jar == nil {
+        jar = make(map[string]string)
+        s.cookies[host] = jar
+    }
+    resp.Header.VisitAllCookie(func(_, value []byte) {
+        c := fasthttp.AcquireCookie()
+        defer fasthttp.ReleaseCookie(c)
+        if c.ParseBytes(value) != nil {
+            return
+        }
+
+        name := string(c.Key())
+        expired := c.MaxAge() < 0 || (c.Expire() != fasthttp.CookieExpireUnlimited && c.Expire().Before(time.Now()))
+        if len(c.Value()) == 0 || string(c.Value()) == "deleted" || expired {
+            delete(jar, name)
+            return
+        }
+        jar[name] = string(c.Value())
+    })
+}
+
+// authenticated 判断是否持有站点的登录 Cookie，Discuz 登录成功后会设置名为 <前缀>_auth 的 Cookie
+func (s *discuzSession) authenticated(host string) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for name := range s.cookies[host] {
+        if strings.HasSuffix(name, "_auth") {
+            return true
+        }
+    }
+    return false
+}
+
+// login 登录页面所在的论坛。force 为 false 时已登录则直接返回，为 true 时总是重新登录
+func (s *discuzSession) login(pageURL, userAgent string, timeout time.Duration, force bool) error {
+    s.loginMu.Lock()
+    defer s.loginMu.Unlock()
+
+    u, err := url.Parse(pageURL)
+    if err != nil {
+        return err
+    }
+    if !force && s.authenticated(u.Host) {
+        return nil
+    }
+    loginURL := u.ResolveReference(&url.URL{Path: "member.php", RawQuery: "mod=logging&action=login"})
+
+    // 登录表单中的 formhash 与当前会话绑定，必须先获取登录页
+    form, err := doFetch(loginURL.String(), userAgent, timeout, page{}, requestSpec{})
+    if err != nil {
+        return fmt.Errorf("load login form: %w", err)
+    }
+    doc, err := goquery.NewDocumentFromReader(strings.NewReader(form.Content))
+    if err != nil {
+        return fmt.Errorf("parse login form: %w", err)
+    }
+    formhash, _ := doc.Find(`input[name="formhash"]`).First().Attr("value")
+
+    data := url.Values{}
+    data.Set("formhash", formhash)
+    data.Set("loginfield", "username")
+    data.Set("username", s.Username)
+    data.Set("password", s.Password)
+    data.Set("questionid", "0")
+    data.Set("answer", "")
+    data.Set("cookietime", "2592000")
+
+    req := fasthttp.AcquireRequest()
+    defer fasthttp.ReleaseRequest(req)
+    req.SetRequestURI(loginURL.String() + "&loginsubmit=yes&inajax=1")
+    req.Header.SetMethod(fasthttp.MethodPost)
+    req.Header.SetContentType("application/x-www-form-urlencoded")
+    req.Header.Set("User-Agent", userAgent)
+    req.SetBodyString(data.Encode())
+    s.apply(req)
+
+    resp := fasthttp.AcquireResponse()
+    defer fasthttp.ReleaseResponse(resp)
+    if err := httpClient.DoTimeout(req, resp, timeout); err != nil {
+        return fmt.Errorf("login to %s: %w", u.Host, err)
+    }
+    s.store(u.Host, resp)
+
+    if !s.authenticated(u.Host) {
+        return fmt.Errorf("login to %s failed: check forum_username and forum_password", u.Host)
+    }
+    slog.Info("论坛登录成功", "host", u.Host, "username", s.Username)
+    return nil
+}
+
+// isLoginPage 判断地址是否是 Discuz 的登录页，未登录访问受限页面时论坛会重定向到这里
+func isLoginPage(pageURL string) bool {
+    u, err := url.Parse(pageURL)
+    if err != nil {
+        return false
+    }
+    q := u.Query()
+    return q.Get("mod") == "logging" && q.Get("action") == "login"
+}
+
+// doFetchWithLogin 执行请求。配置了论坛账号时先确保已登录，被重定向到登录页说明会话已过期，重新登录后再请求一次
+func doFetchWithLogin(pageURL, userAgent string, timeout time.Duration, cached page, spec requestSpec) (page, error) {
+    s := forumSession
+    if s == nil {
+        return doFetch(pageURL, userAgent, timeout, cached, spec)
+    }
+    if err := s.login(pageURL, userAgent, timeout, false); err != nil {
+        return page{}, err
+    }
+
+    p, err := doFetch(pageURL, userAgent, timeout, cached, spec)
+    if err != nil || !isLoginPage(p.URL) {
+        return p, err
+    }
+
+    slog.Info("论坛会话已过期，重新登录", "url", pageURL)
+    if err := s.login(pageURL, userAgent, timeout, true); err != nil {
+        return page{}, err
+    }
+    p, err = doFetch(pageURL, userAgent, timeout, cached, spec)
+    if err == nil && isLoginPage(p.URL) {
+        return page{}, fmt.Errorf("still redirected to login page after login: %s", pageURL)
+    }
+    return p, err
+}
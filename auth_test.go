@@ -0,0 +1,134 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "sync/atomic"
+    "testing"
+)
+
+func TestParseBasicAuth(t *testing.T) {
+    tests := []struct {
+        in         string
+        user, pass string
+        wantErr    bool
+    }{
+        {"user:pass", "user", "pass", false},
+        {"user:p:a:ss", "user", "p:a:ss", false},
+        {"user:", "user", "", false},
+        {"user", "", "", true},
+        {":pass", "", "", true},
+    }
+    for _, tt := range tests {
+        user, pass, err := parseBasicAuth(tt.in)
+        if (err != nil) != tt.wantErr || user != tt.user || pass != tt.pass {
+            t.Errorf("parseBasicAuth(%q) = %q, %q, %v", tt.in, user, pass, err)
+        }
+    }
+}
+
+func TestIsLoginPage(t *testing.T) {
+    tests := []struct {
+        url  string
+        want bool
+    }{
+        {"https://fishc.com.cn/member.php?mod=logging&action=login", true},
+        {"https://fishc.com.cn/member.php?mod=logging&action=logout", false},
+        {"https://fishc.com.cn/forum.php?mod=guide", false},
+    }
+    for _, tt := range tests {
+        if got := isLoginPage(tt.url); got != tt.want {
+            t.Errorf("isLoginPage(%q) = %v, want %v", tt.url, got, tt.want)
+        }
+    }
+}
+
+// discuzServer 模拟 Discuz 的登录流程：未登录或会话被作废时访问版块会重定向到登录页
+type discuzServer struct {
+    logins atomic.Int32
+    // session 当前有效的会话编号，登录时递增
+    session atomic.Int32
+}
+
+func (s *discuzServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    switch {
+    case r.URL.Path == "/member.php" && r.Method == http.MethodGet:
+        fmt.Fprint(w, `<form><input name="formhash" value="f123"></form>`)
+    case r.URL.Path == "/member.php":
+        r.ParseForm()
+        if r.Form.Get("formhash") != "f123" || r.Form.Get("username") != "fish" || r.Form.Get("password") != "secret" {
+            fmt.Fprint(w, "login failed")
+            return
+        }
+        s.logins.Add(1)
+        http.SetCookie(w, &http.Cookie{Name: "x_auth", Value: fmt.Sprint(s.session.Add(1))})
+    default:
+        c, err := r.Cookie("x_auth")
+        if err != nil || c.Value != fmt.Sprint(s.session.Load()) {
+            http.Redirect(w, r, "/member.php?mod=logging&action=login", http.StatusFound)
+            return
+        }
+        fmt.Fprint(w, "members only")
+    }
+}
+
+func TestFetchSendsForumAuth(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        user, pass, _ := r.BasicAuth()
+        cookie, _ := r.Cookie("auth")
+        fmt.Fprintf(w, "%s:%s %v", user, pass, cookie)
+    })
+    saved := fetchAuth
+    t.Cleanup(func() { fetchAuth = saved })
+    fetchAuth = forumAuth{Cookie: "auth=abc; sid=1", Username: "fish", Password: "secret"}
+
+    p, err := fetchWithRetry(context.Background(), srv.URL, defaultUserAgent, 1, page{}, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p.Content != "fish:secret auth=abc" {
+        t.Errorf("server saw %q, want the cookie and basic auth", p.Content)
+    }
+}
+
+func TestDiscuzSessionLogsInAndRefreshes(t *testing.T) {
+    forum := &discuzServer{}
+    srv := newTestServer(t, forum.ServeHTTP)
+    old := forumSession
+    forumSession = newDiscuzSession("fish", "secret")
+    t.Cleanup(func() { forumSession = old })
+
+    f := FastHTTPFetcher{Attempts: 1}
+    content, err := f.Fetch(context.Background(), srv.URL+"/forum.php")
+    if err != nil || content != "members only" {
+        t.Fatalf("Fetch = %q, %v", content, err)
+    }
+    if _, err := f.Fetch(context.Background(), srv.URL+"/forum.php"); err != nil {
+        t.Fatal(err)
+    }
+    if n := forum.logins.Load(); n != 1 {
+        t.Errorf("logins = %d, the session must be reused", n)
+    }
+
+    // 服务器作废会话后重定向到登录页，应自动重新登录
+    forum.session.Add(1)
+    content, err = f.Fetch(context.Background(), srv.URL+"/forum.php")
+    if err != nil || content != "members only" {
+        t.Fatalf("Fetch after expiry = %q, %v", content, err)
+    }
+    if n := forum.logins.Load(); n != 2 {
+        t.Errorf("logins = %d, want a second login", n)
+    }
+}
+
+func TestDiscuzSessionWrongPassword(t *testing.T) {
+    srv := newTestServer(t, (&discuzServer{}).ServeHTTP)
+    old := forumSession
+    forumSession = newDiscuzSession("fish", "wrong")
+    t.Cleanup(func() { forumSession = old })
+
+    if _, err := (FastHTTPFetcher{Attempts: 1}).Fetch(context.Background(), srv.URL+"/forum.php"); err == nil {
+        t.Error("Fetch succeeded with a wrong password")
+    }
+}
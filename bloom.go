@@ -0,0 +1,66 @@
+package main
+
+import (
+    "encoding/binary"
+    "hash/fnv"
+    "math"
+)
+
+// BloomStore 用布隆过滤器记录已处理的帖子，内存占用只取决于容量和误判率，与记录数量无关。
+// 不会漏判已记录的帖子，但有约 fpRate 的概率把新帖误判为已记录而漏掉通知；记录数超过容量后误判率会上升
+type BloomStore struct {
+    bits  []uint64
+    m     uint64
+    k     uint64
+    count int
+}
+
+// newBloomStore 创建可容纳 capacity 条记录、误判率约为 fpRate 的布隆过滤器
+func newBloomStore(capacity int, fpRate float64) *BloomStore {
+    n := float64(max(capacity, 1))
+    m := uint64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+    k := uint64(max(1, math.Round(float64(m)/n*math.Ln2)))
+    return &BloomStore{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// positions 用双重哈希计算 key 对应的 k 个位
+func (s *BloomStore) positions(key string) []uint64 {
+    h := fnv.New128a()
+    h.Write([]byte(key))
+    sum := h.Sum(nil)
+    h1 := binary.BigEndian.Uint64(sum[:8])
+    h2 := binary.BigEndian.Uint64(sum[8:]) | 1
+    positions := make([]uint64, s.k)
+    for i := range positions {
+        positions[i] = (h1 + uint64(i)*h2) % s.m
+    }
+    return positions
+}
+
+func (s *BloomStore) Seen(key string) (bool, error) {
+    for _, p := range s.positions(key) {
+        if s.bits[p/64]&(1<<(p%64)) == 0 {
+            return false, nil
+        }
+    }
+    return true, nil
+}
+
+// Mark 记录 key，key 的所有位都已置位时视为重复记录，不计入数量
+func (s *BloomStore) Mark(key string, _ Post) error {
+    added := false
+    for _, p := range s.positions(key) {
+        if s.bits[p/64]&(1<<(p%64)) == 0 {
+            s.bits[p/64] |= 1 << (p % 64)
+            added = true
+        }
+    }
+    if added {
+        s.count++
+    }
+    return nil
+}
+
+func (s *BloomStore) Len() (int, error) {
+    return s.count, nil
+}
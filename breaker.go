@@ -0,0 +1,108 @@
+package main
+
+import (
+    "sync"
+    "time"
+)
+
+// breakerMaxCooldown 断路器连续打开时冷却时间翻倍的上限
+const breakerMaxCooldown = time.Hour
+
+// 断路器状态
+const (
+    breakerClosed   = "closed"
+    breakerOpen     = "open"
+    breakerHalfOpen = "half-open"
+)
+
+// circuitBreaker 连续失败 threshold 次后打开，冷却期间跳过请求；冷却结束后半开放行一次探测，
+// 探测成功则关闭，失败则重新打开并将冷却时间翻倍
+type circuitBreaker struct {
+    mu        sync.Mutex
+    threshold int
+    base      time.Duration
+    cooldown  time.Duration
+    failures  int
+    state     string
+    openedAt  time.Time
+    now       func() time.Time
+}
+
+// newCircuitBreaker 创建断路器，threshold 为 0 时返回 nil 表示不启用
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+    if threshold <= 0 {
+        return nil
+    }
+    return &circuitBreaker{threshold: threshold, base: cooldown, cooldown: cooldown, state: breakerClosed, now: time.Now}
+}
+
+// Allow 判断本轮是否可以发出请求，冷却结束时转为半开。b 为 nil 时总是允许
+func (b *circuitBreaker) Allow() bool {
+    if b == nil {
+        return true
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.state == breakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+        b.state = breakerHalfOpen
+    }
+    return b.state != breakerOpen
+}
+
+// Success 记录一次成功请求，返回断路器是否因此从打开或半开恢复为关闭
+func (b *circuitBreaker) Success() bool {
+    if b == nil {
+        return false
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    recovered := b.state != breakerClosed
+    b.state = breakerClosed
+    b.failures = 0
+    b.cooldown = b.base
+    return recovered
+}
+
+// Failure 记录一次失败请求，返回断路器是否因此从关闭变为打开。半开探测失败时重新打开并延长冷却时间
+func (b *circuitBreaker) Failure() bool {
+    if b == nil {
+        return false
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    switch b.state {
+    case breakerHalfOpen:
+        b.cooldown = min(2*b.cooldown, max(b.base, breakerMaxCooldown))
+        b.state = breakerOpen
+        b.openedAt = b.now()
+        return false
+    case breakerClosed:
+        b.failures++
+        if b.failures >= b.threshold {
+            b.state = breakerOpen
+            b.openedAt = b.now()
+            return true
+        }
+    }
+    return false
+}
+
+// Remaining 返回断路器打开时距冷却结束的剩余时间，未打开或 b 为 nil 时返回 0
+func (b *circuitBreaker) Remaining() time.Duration {
+    if b == nil {
+        return 0
+    }
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.state != breakerOpen {
+        return 0
+    }
+    return max(b.cooldown-b.now().Sub(b.openedAt), 0)
+}
+
+// Cooldown 返回当前的冷却时间
+func (b *circuitBreaker) Cooldown() time.Duration {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.cooldown
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+    "testing"
+    "time"
+)
+
+// newTestBreaker 返回使用可控时钟的断路器和推进时钟的函数
+func newTestBreaker(threshold int, cooldown time.Duration) (*circuitBreaker, func(time.Duration)) {
+    now := time.Date(2024, 5, 12, 12, 0, 0, 0, time.UTC)
+    b := newCircuitBreaker(threshold, cooldown)
+    b.now = func() time.Time { return now }
+    return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+    b, advance := newTestBreaker(3, time.Minute)
+    for i := 0; i < 2; i++ {
+        if b.Failure() {
+            t.Fatalf("breaker opened after %d failures", i+1)
+        }
+    }
+    if !b.Allow() {
+        t.Fatal("breaker blocked requests before reaching the threshold")
+    }
+    if !b.Failure() {
+        t.Fatal("breaker did not report opening at the threshold")
+    }
+    if b.Allow() {
+        t.Error("open breaker allowed a request")
+    }
+    if got := b.Remaining(); got != time.Minute {
+        t.Errorf("Remaining() = %v, want the full cooldown", got)
+    }
+
+    advance(40 * time.Second)
+    if got := b.Remaining(); got != 20*time.Second {
+        t.Errorf("Remaining() = %v, want 20s", got)
+    }
+    advance(20 * time.Second)
+    if !b.Allow() {
+        t.Fatal("breaker did not half-open after the cooldown")
+    }
+    if b.Remaining() != 0 {
+        t.Error("half-open breaker reported remaining cooldown")
+    }
+    if !b.Success() {
+        t.Error("successful probe did not report recovery")
+    }
+    if b.Success() {
+        t.Error("closed breaker reported recovery again")
+    }
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+    b, _ := newTestBreaker(2, time.Minute)
+    b.Failure()
+    b.Success()
+    if b.Failure() {
+        t.Error("failures were not reset by a success")
+    }
+}
+
+func TestCircuitBreakerFailedProbeDoublesCooldown(t *testing.T) {
+    b, advance := newTestBreaker(1, 40*time.Minute)
+    b.Failure()
+    advance(40 * time.Minute)
+    b.Allow()
+    if b.Failure() {
+        t.Error("failed probe reported a fresh opening")
+    }
+    if got := b.Cooldown(); got != breakerMaxCooldown {
+        t.Errorf("Cooldown() = %v, want doubled and capped at %v", got, breakerMaxCooldown)
+    }
+    if b.Allow() {
+        t.Error("breaker allowed a request right after a failed probe")
+    }
+
+    advance(breakerMaxCooldown)
+    b.Allow()
+    b.Success()
+    if got := b.Cooldown(); got != 40*time.Minute {
+        t.Errorf("Cooldown() after recovery = %v, want the base cooldown", got)
+    }
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+    b := newCircuitBreaker(0, time.Minute)
+    if b != nil {
+        t.Fatal("threshold 0 should disable the breaker")
+    }
+    if !b.Allow() || b.Failure() || b.Success() || b.Remaining() != 0 {
+        t.Error("nil breaker should always allow and never change state")
+    }
+}
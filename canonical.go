@@ -0,0 +1,49 @@
+package main
+
+import (
+    "net/url"
+    "path"
+    "strings"
+)
+
+// defaultCanonicalStrip 规范化帖子地址时默认去掉的查询参数，支持 path.Match 通配符。
+// 包括常见的跟踪参数和 Discuz 的手机版、推广人、会话、表单令牌等不影响帖子本身的参数
+var defaultCanonicalStrip = []string{"utm_*", "spm", "from", "fromuid", "mobile", "sid", "formhash", "extra"}
+
+// canonicalURL 返回用于去重的规范地址：协议和主机名转为小写，去掉默认端口和片段，
+// 去掉名称匹配 strip 中任一模式的查询参数，其余参数按名称排序。无法解析的地址原样返回
+func canonicalURL(raw string, strip []string) string {
+    u, err := url.Parse(raw)
+    if err != nil || u.Host == "" {
+        return raw
+    }
+    u.Scheme = strings.ToLower(u.Scheme)
+    u.Host = strings.ToLower(u.Host)
+    if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+        u.Host = u.Hostname()
+    }
+    u.Fragment, u.RawFragment = "", ""
+    if u.Path == "" {
+        u.Path = "/"
+    }
+
+    query := u.Query()
+    for key := range query {
+        if matchParam(key, strip) {
+            query.Del(key)
+        }
+    }
+    u.RawQuery = query.Encode()
+    return u.String()
+}
+
+// matchParam 判断查询参数名是否匹配 patterns 中的任一模式，不区分大小写
+func matchParam(key string, patterns []string) bool {
+    key = strings.ToLower(key)
+    for _, pattern := range patterns {
+        if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+            return true
+        }
+    }
+    return false
+}
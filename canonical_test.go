@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestCanonicalURL(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        {"unchanged", "https://fishc.com.cn/thread-1-1-1.html", "https://fishc.com.cn/thread-1-1-1.html"},
+        {"lowercase host and scheme", "HTTPS://FishC.com.cn/Thread-1.html", "https://fishc.com.cn/Thread-1.html"},
+        {"default https port", "https://fishc.com.cn:443/t", "https://fishc.com.cn/t"},
+        {"default http port", "http://fishc.com.cn:80/t", "http://fishc.com.cn/t"},
+        {"custom port kept", "https://fishc.com.cn:8443/t", "https://fishc.com.cn:8443/t"},
+        {"fragment removed", "https://fishc.com.cn/t#pid123", "https://fishc.com.cn/t"},
+        {"empty path", "https://fishc.com.cn", "https://fishc.com.cn/"},
+        {"tracking params removed", "https://fishc.com.cn/forum.php?tid=1&utm_source=x&UTM_Medium=y&from=home", "https://fishc.com.cn/forum.php?tid=1"},
+        {"params sorted", "https://fishc.com.cn/forum.php?tid=1&mod=viewthread", "https://fishc.com.cn/forum.php?mod=viewthread&tid=1"},
+        {"discuz session params", "https://fishc.com.cn/forum.php?mod=viewthread&tid=1&mobile=2&formhash=ab12&extra=page%3D1", "https://fishc.com.cn/forum.php?mod=viewthread&tid=1"},
+        {"relative kept", "thread-1.html", "thread-1.html"},
+        {"unparsable kept", "https://fishc.com.cn/%zz", "https://fishc.com.cn/%zz"},
+    }
+    for _, tt := range tests {
+        if got := canonicalURL(tt.in, defaultCanonicalStrip); got != tt.want {
+            t.Errorf("%s: canonicalURL(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestCanonicalURLCustomStrip(t *testing.T) {
+    const in = "https://fishc.com.cn/forum.php?tid=1&from=home&ref=x"
+    if got := canonicalURL(in, nil); got != "https://fishc.com.cn/forum.php?from=home&ref=x&tid=1" {
+        t.Errorf("no strip = %q", got)
+    }
+    if got := canonicalURL(in, []string{"re?"}); got != "https://fishc.com.cn/forum.php?from=home&tid=1" {
+        t.Errorf("custom strip = %q", got)
+    }
+}
@@ -0,0 +1,749 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "slices"
+    "strings"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+// Config 程序的全部配置。优先级从低到高依次为：内置默认值、配置文件、环境变量、命令行参数
+type Config struct {
+    Token              string        `yaml:"token"`
+    ChatIDs            []string      `yaml:"chat_ids"`
+    ParseMode          string        `yaml:"parse_mode"`
+    Template           string        `yaml:"template"`
+    Silent             bool          `yaml:"silent"`
+    NoPreview          bool          `yaml:"no_preview"`
+    ThreadID           int           `yaml:"thread_id"`
+    Buttons            bool          `yaml:"buttons"`
+    Format             string        `yaml:"format"`
+    MaxLen             int           `yaml:"max_len"`
+    Include            []string      `yaml:"include"`
+    Exclude            []string      `yaml:"exclude"`
+    CaseSensitive      bool          `yaml:"case_sensitive"`
+    Dedup              string        `yaml:"dedup"`
+    CanonicalStrip     []string      `yaml:"canonical_strip"`
+    Batch              bool          `yaml:"batch"`
+    QuietHours         string        `yaml:"quiet_hours"`
+    QuietTimezone      string        `yaml:"quiet_timezone"`
+    QuietBatch         bool          `yaml:"quiet_batch"`
+    BatchSort          string        `yaml:"batch_sort"`
+    MinReplies         int           `yaml:"min_replies"`
+    SkipSticky         bool          `yaml:"skip_sticky"`
+    MinAge             time.Duration `yaml:"min_age"`
+    MaxAge             time.Duration `yaml:"max_age"`
+    AgeUnknown         string        `yaml:"age_unknown"`
+    SkipInitial        bool          `yaml:"skip_initial"`
+    Since              string        `yaml:"since"`
+    CatchUpPages       int           `yaml:"catchup_pages"`
+    Concurrency        int           `yaml:"concurrency"`
+    CycleTimeout       time.Duration `yaml:"cycle_timeout"`
+    MinSleep           time.Duration `yaml:"min_sleep"`
+    DiscordWebhook     string        `yaml:"discord_webhook"`
+    SlackWebhook       string        `yaml:"slack_webhook"`
+    Webhook            string        `yaml:"webhook"`
+    WebhookHeaders     []string      `yaml:"webhook_headers"`
+    DeadLetter         string        `yaml:"deadletter"`
+    SMTPHost           string        `yaml:"smtp_host"`
+    SMTPPort           int           `yaml:"smtp_port"`
+    SMTPUsername       string        `yaml:"smtp_username"`
+    SMTPPassword       string        `yaml:"smtp_password"`
+    SMTPFrom           string        `yaml:"smtp_from"`
+    SMTPTo             []string      `yaml:"smtp_to"`
+    SMTPTLS            string        `yaml:"smtp_tls"`
+    SMTPHTML           bool          `yaml:"smtp_html"`
+    Exec               string        `yaml:"exec"`
+    ExecTimeout        time.Duration `yaml:"exec_timeout"`
+    UserAgent          string        `yaml:"user_agent"`
+    Method             string        `yaml:"method"`
+    Body               string        `yaml:"body"`
+    ContentType        string        `yaml:"content_type"`
+    Headers            []string      `yaml:"headers"`
+    Interval           time.Duration `yaml:"interval"`
+    Jitter             time.Duration `yaml:"jitter"`
+    URLs               []string      `yaml:"urls"`
+    TargetsFile        string        `yaml:"targets_file"`
+    Watch              []string      `yaml:"watch"`
+    WatchInterval      time.Duration `yaml:"watch_interval"`
+    Retries            int           `yaml:"retries"`
+    BreakerThreshold   int           `yaml:"breaker_threshold"`
+    BreakerCooldown    time.Duration `yaml:"breaker_cooldown"`
+    EmptyAlertCycles   int           `yaml:"empty_alert_cycles"`
+    State              string        `yaml:"state"`
+    Store              string        `yaml:"store"`
+    StoreMaxAge        time.Duration `yaml:"store_max_age"`
+    BloomCapacity      int           `yaml:"bloom_capacity"`
+    BloomFPRate        float64       `yaml:"bloom_fp_rate"`
+    Proxy              string        `yaml:"proxy"`
+    IPVersion          string        `yaml:"ip_version"`
+    CAFile             string        `yaml:"ca_file"`
+    InsecureSkipVerify bool          `yaml:"insecure_skip_verify"`
+    Cookie             string        `yaml:"cookie"`
+    BasicAuth          string        `yaml:"basic_auth"`
+    ForumUsername      string        `yaml:"forum_username"`
+    ForumPassword      string        `yaml:"forum_password"`
+    IgnoreRobots       bool          `yaml:"ignore_robots"`
+    Rate               float64       `yaml:"rate"`
+    RetryBudget        float64       `yaml:"retry_budget"`
+    MaxConcurrency     int           `yaml:"max_concurrency"`
+    MaxBody            int           `yaml:"max_body"`
+    CacheDir           string        `yaml:"cache_dir"`
+    CacheTTL           time.Duration `yaml:"cache_ttl"`
+    Selectors          Selectors     `yaml:"selectors"`
+    Forums             []ForumConfig `yaml:"forums"`
+    Profiles           []yaml.Node   `yaml:"profiles"`
+    LogLevel           string        `yaml:"log_level"`
+    Trace              bool          `yaml:"trace"`
+    Lang               string        `yaml:"lang"`
+    ShowVersion        bool          `yaml:"-"`
+    SelfCheck          bool          `yaml:"-"`
+    SelfCheckSend      bool          `yaml:"-"`
+    ListSelectors      bool          `yaml:"-"`
+    PrintConfig        bool          `yaml:"-"`
+    ReplayDeadLetter   bool          `yaml:"-"`
+    Output             string        `yaml:"output"`
+    OutputFile         string        `yaml:"output_file"`
+    DryRun             bool          `yaml:"dry_run"`
+    Once               bool          `yaml:"once"`
+    MetricsAddr        string        `yaml:"metrics_addr"`
+    FeedAddr           string        `yaml:"feed_addr"`
+    FeedSize           int           `yaml:"feed_size"`
+    HealthAddr         string        `yaml:"health_addr"`
+}
+
+// ForumConfig 单个论坛的配置，未设置的选择器使用全局选择器
+type ForumConfig struct {
+    URL       string    `yaml:"url"`
+    Selectors Selectors `yaml:"selectors"`
+}
+
+// defaultConfig 返回内置默认配置
+func defaultConfig() *Config {
+    return &Config{
+        UserAgent:        defaultUserAgent,
+        Interval:         30 * time.Second,
+        Retries:          3,
+        FeedSize:         50,
+        BreakerThreshold: 5,
+        BreakerCooldown:  5 * time.Minute,
+        CacheTTL:         10 * time.Minute,
+        MinSleep:         time.Second,
+        QuietTimezone:    "Local",
+        BloomCapacity:    1000000,
+        BloomFPRate:      0.001,
+        Method:           http.MethodGet,
+        ContentType:      "application/x-www-form-urlencoded",
+        RetryBudget:      5,
+        MaxConcurrency:   16,
+        CanonicalStrip:   defaultCanonicalStrip,
+        IPVersion:        ipAuto,
+        SMTPPort:         587,
+        SMTPTLS:          smtpStartTLS,
+        ExecTimeout:      30 * time.Second,
+        SkipSticky:       true,
+        WatchInterval:    5 * time.Minute,
+        MaxBody:          defaultMaxBody,
+        AgeUnknown:       agePass,
+        Store:            storeFile,
+        StoreMaxAge:      30 * 24 * time.Hour,
+        EmptyAlertCycles: 5,
+        CatchUpPages:     1,
+        Concurrency:      4,
+        Selectors:        defaultSelectors,
+        LogLevel:         "info",
+        Lang:             langZH,
+        Format:           formatPlain,
+        Dedup:            dedupURL,
+    }
+}
+
+// listFlag 绑定到字符串切片的命令行参数，命令行中出现时覆盖配置文件中的值。
+// split 为 true 时按逗号拆分，否则每次出现追加一项
+type listFlag struct {
+    values *[]string
+    split  bool
+    set    bool
+}
+
+func (f *listFlag) String() string {
+    if f.values == nil {
+        return ""
+    }
+    return strings.Join(*f.values, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+    if !f.set {
+        *f.values = nil
+        f.set = true
+    }
+    if f.split {
+        *f.values = append(*f.values, splitList(value)...)
+    } else {
+        *f.values = append(*f.values, value)
+    }
+    return nil
+}
+
+// newFlagSet 定义所有命令行参数并绑定到 cfg，参数的默认值取 cfg 中的当前值
+func newFlagSet(cfg *Config) (*flag.FlagSet, *string) {
+    fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+    configPath := fs.String("config", "", "YAML 或 JSON 格式的配置文件路径")
+    fs.StringVar(&cfg.Token, "token", cfg.Token, "Telegram Bot API Token")
+    fs.Var(&listFlag{values: &cfg.ChatIDs, split: true}, "chatid", "Telegram Chat ID，多个频道用逗号分隔")
+    fs.StringVar(&cfg.DiscordWebhook, "discord-webhook", cfg.DiscordWebhook, "Discord Webhook URL，设置后同时推送到 Discord")
+    fs.StringVar(&cfg.SlackWebhook, "slack-webhook", cfg.SlackWebhook, "Slack Incoming Webhook URL，设置后同时推送到 Slack")
+    fs.StringVar(&cfg.Webhook, "webhook", cfg.Webhook, "自定义 Webhook URL，设置后以 JSON 格式推送帖子")
+    fs.Var(&listFlag{values: &cfg.WebhookHeaders}, "webhook-header", "Webhook 请求附加的请求头，格式为 \"Key: Value\"，可重复指定")
+    fs.StringVar(&cfg.DeadLetter, "deadletter", cfg.DeadLetter, "死信文件路径，重试用尽后仍发送失败的帖子以 JSON Lines 格式追加到此文件，为空时不记录")
+    fs.StringVar(&cfg.SMTPHost, "smtp-host", cfg.SMTPHost, "SMTP 服务器地址，设置后同时通过邮件推送帖子")
+    fs.IntVar(&cfg.SMTPPort, "smtp-port", cfg.SMTPPort, "SMTP 服务器端口")
+    fs.StringVar(&cfg.SMTPUsername, "smtp-username", cfg.SMTPUsername, "SMTP 登录用户名，为空时不认证")
+    fs.StringVar(&cfg.SMTPPassword, "smtp-password", cfg.SMTPPassword, "SMTP 登录密码，建议使用环境变量 YUC_SMTP_PASSWORD")
+    fs.StringVar(&cfg.SMTPFrom, "smtp-from", cfg.SMTPFrom, "发件人地址")
+    fs.Var(&listFlag{values: &cfg.SMTPTo, split: true}, "smtp-to", "收件人地址，多个用逗号分隔")
+    fs.StringVar(&cfg.SMTPTLS, "smtp-tls", cfg.SMTPTLS, "SMTP 加密方式: starttls、tls（隐式 TLS，一般用于 465 端口）或 none")
+    fs.BoolVar(&cfg.SMTPHTML, "smtp-html", cfg.SMTPHTML, "发送 HTML 格式的邮件，默认发送纯文本")
+    fs.StringVar(&cfg.Exec, "exec", cfg.Exec, "每个新帖执行的外部命令，通过 sh -c 执行，帖子以 JSON 写入标准输入并以 YUC_POST_* 环境变量提供")
+    fs.DurationVar(&cfg.ExecTimeout, "exec-timeout", cfg.ExecTimeout, "外部命令的超时时间")
+    fs.StringVar(&cfg.ParseMode, "parse-mode", cfg.ParseMode, "Telegram 消息格式: Markdown、MarkdownV2 或 HTML，为空时发送纯文本")
+    fs.StringVar(&cfg.Template, "template", cfg.Template, "自定义消息模板（Go text/template），可用字段 {{.Title}} {{.URL}} {{.Message}} {{.Author}} {{.Time}} {{.Replies}} {{.Views}}，\\n 表示换行")
+    fs.BoolVar(&cfg.Silent, "silent", cfg.Silent, "静默发送 Telegram 消息，接收者不会收到提醒")
+    fs.BoolVar(&cfg.NoPreview, "no-preview", cfg.NoPreview, "关闭 Telegram 消息中的链接预览")
+    fs.IntVar(&cfg.ThreadID, "thread-id", cfg.ThreadID, "发送到开启话题的群组中的指定话题 ID，0 表示不指定")
+    fs.BoolVar(&cfg.Buttons, "buttons", cfg.Buttons, "在 Telegram 消息下方附加打开帖子的按钮")
+    fs.StringVar(&cfg.Format, "format", cfg.Format, "帖子内容格式: plain 为纯文本，markdown 转换为 Telegram MarkdownV2 并以 MarkdownV2 发送，html 保留 Telegram 支持的 HTML 标签（需配合 -parse-mode HTML）")
+    fs.IntVar(&cfg.MaxLen, "max-len", cfg.MaxLen, "帖子内容的最大字符数，超出部分截断并附上帖子链接，0 表示不截断")
+    fs.Var(&listFlag{values: &cfg.Include, split: true}, "include", "只通知标题或内容包含这些关键词的帖子，以 re: 开头或写成 /…/ 的按正则匹配，多个用逗号分隔")
+    fs.Var(&listFlag{values: &cfg.Exclude, split: true}, "exclude", "不通知标题或内容包含这些关键词的帖子，以 re: 开头或写成 /…/ 的按正则匹配，多个用逗号分隔")
+    fs.BoolVar(&cfg.CaseSensitive, "case-sensitive", cfg.CaseSensitive, "关键词过滤区分大小写")
+    fs.StringVar(&cfg.Dedup, "dedup", cfg.Dedup, "去重方式: url 按帖子链接，hash 按标题和内容的哈希（每轮需获取列表中全部帖子的内容）")
+    fs.Var(&listFlag{values: &cfg.CanonicalStrip, split: true}, "canonical-strip", "按链接去重前从帖子链接中去掉的查询参数，逗号分隔，支持 * 通配符，默认去掉跟踪参数和 mobile、sid 等 Discuz 参数")
+    fs.BoolVar(&cfg.Batch, "batch", cfg.Batch, "将每轮检查发现的新帖子合并为一条消息发送")
+    fs.StringVar(&cfg.QuietHours, "quiet-hours", cfg.QuietHours, "免打扰时段，例如 22:00-07:00，期间的通知暂存到时段结束后发送，为空时不启用")
+    fs.StringVar(&cfg.QuietTimezone, "quiet-timezone", cfg.QuietTimezone, "免打扰时段使用的时区，例如 Asia/Shanghai，Local 为本机时区")
+    fs.BoolVar(&cfg.QuietBatch, "quiet-batch", cfg.QuietBatch, "免打扰时段结束后把暂存的通知合并为一条发送")
+    fs.StringVar(&cfg.BatchSort, "batch-sort", cfg.BatchSort, "合并通知中帖子的排序方式: 留空按发帖顺序，replies 按回复数，views 按查看数从多到少")
+    fs.IntVar(&cfg.MinReplies, "min-replies", cfg.MinReplies, "只通知列表页上回复数不少于该值的帖子，未达到的帖子下一轮继续检查，0 表示不限制")
+    fs.BoolVar(&cfg.SkipSticky, "skip-sticky", cfg.SkipSticky, "跳过列表页上的置顶帖，设为 false 时置顶帖也按新帖通知")
+    fs.DurationVar(&cfg.MinAge, "min-age", cfg.MinAge, "帖子发布超过该时长后才通知，让作者先完成编辑，未到时间的帖子下一轮继续检查，0 表示不限制")
+    fs.DurationVar(&cfg.MaxAge, "max-age", cfg.MaxAge, "不通知发布时间早于该时长的帖子，避免被顶起的旧帖，0 表示不限制")
+    fs.StringVar(&cfg.AgeUnknown, "age-unknown", cfg.AgeUnknown, "设置 -min-age 或 -max-age 时如何处理无法解析发帖时间的帖子: pass 照常通知，drop 不通知")
+    fs.BoolVar(&cfg.SkipInitial, "skip-initial", cfg.SkipInitial, "首次运行时把页面上已有的帖子全部记为已读，只通知之后出现的新帖")
+    fs.StringVar(&cfg.Since, "since", cfg.Since, "首次运行时通知该时间之后发布的全部帖子，会向后翻页直到更早的帖子，RFC3339 格式，例如 2024-05-01T00:00:00+08:00")
+    fs.IntVar(&cfg.CatchUpPages, "catchup-pages", cfg.CatchUpPages, "启动时为补发停机期间的新帖最多向后翻的列表页数，1 表示只检查第一页")
+    fs.StringVar(&cfg.UserAgent, "ua", cfg.UserAgent, "请求论坛时使用的 User-Agent")
+    fs.StringVar(&cfg.Method, "method", cfg.Method, "请求列表页使用的 HTTP 方法: GET 或 POST，帖子页总是使用 GET")
+    fs.StringVar(&cfg.Body, "body", cfg.Body, "请求列表页时发送的请求体，例如 POST 表单 \"srchtxt=python&searchsubmit=yes\"")
+    fs.StringVar(&cfg.ContentType, "content-type", cfg.ContentType, "请求体的 Content-Type")
+    fs.Var(&listFlag{values: &cfg.Headers}, "header", "请求论坛时附加的请求头，格式为 \"Key: Value\"，可重复指定")
+    fs.DurationVar(&cfg.Interval, "interval", cfg.Interval, "监控间隔时间，例如 30s、1m")
+    fs.DurationVar(&cfg.Jitter, "jitter", cfg.Jitter, "监控间隔的随机抖动范围，实际间隔为 interval ± jitter")
+    fs.Var(&listFlag{values: &cfg.URLs}, "url", "要监控的论坛页面 URL，可重复指定以同时监控多个论坛（默认 "+defaultForumURL+"）")
+    fs.StringVar(&cfg.TargetsFile, "targets-file", cfg.TargetsFile, "目标文件路径，每行一个要监控的论坛 URL，可用 | 追加 list=、title=、message= 选择器，修改后自动生效")
+    fs.Var(&listFlag{values: &cfg.Watch}, "watch", "关注的帖子 URL，首帖内容被编辑时发送通知，可重复指定")
+    fs.DurationVar(&cfg.WatchInterval, "watch-interval", cfg.WatchInterval, "检查关注帖子是否被编辑的间隔")
+    fs.IntVar(&cfg.Retries, "retries", cfg.Retries, "获取页面失败时的最大尝试次数")
+    fs.IntVar(&cfg.BreakerThreshold, "breaker-threshold", cfg.BreakerThreshold, "论坛连续请求失败多少次后暂停检查，0 表示不启用断路器")
+    fs.DurationVar(&cfg.BreakerCooldown, "breaker-cooldown", cfg.BreakerCooldown, "断路器打开后暂停检查的时间，再次失败时翻倍，最长 1 小时")
+    fs.IntVar(&cfg.EmptyAlertCycles, "empty-alert-cycles", cfg.EmptyAlertCycles, "列表页连续多少轮没有找到帖子时发送一次选择器可能失效的提醒，0 表示不提醒")
+    fs.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "每轮并发获取帖子内容的最大数量")
+    fs.DurationVar(&cfg.CycleTimeout, "cycle-timeout", cfg.CycleTimeout, "每轮抓取列表页和帖子内容的总时长上限，超时前已获取的帖子照常通知，其余留到下一轮，0 表示不限制")
+    fs.DurationVar(&cfg.MinSleep, "min-sleep", cfg.MinSleep, "两轮检查之间的最短等待时间，检查耗时超过监控间隔时至少等待这么久再开始下一轮")
+    fs.StringVar(&cfg.State, "state", cfg.State, "保存已通知帖子的状态文件或 SQLite 数据库路径，为空时不持久化")
+    fs.StringVar(&cfg.Store, "store", cfg.Store, "已通知帖子的存储方式: file 为 JSON 状态文件，sqlite 为 SQLite 数据库，memory 为只保存在内存中，bloom 为只保存在内存中的布隆过滤器（内存占用固定，有少量漏通知的概率）")
+    fs.DurationVar(&cfg.StoreMaxAge, "store-max-age", cfg.StoreMaxAge, "使用 SQLite 存储时自动清理早于该时长的记录，0 表示不清理")
+    fs.IntVar(&cfg.BloomCapacity, "bloom-capacity", cfg.BloomCapacity, "使用布隆过滤器存储时每个论坛预计记录的帖子数量，超过后误判率上升")
+    fs.Float64Var(&cfg.BloomFPRate, "bloom-fp-rate", cfg.BloomFPRate, "使用布隆过滤器存储时的目标误判率，误判的新帖不会通知")
+    fs.StringVar(&cfg.Proxy, "proxy", cfg.Proxy, "请求论坛时使用的代理，例如 http://host:port 或 socks5://host:port")
+    fs.StringVar(&cfg.IPVersion, "ip-version", cfg.IPVersion, "请求论坛时使用的 IP 版本: 4、6 或 auto，双栈网络中某一地址族不通时可以强制使用另一个")
+    fs.StringVar(&cfg.CAFile, "ca-file", cfg.CAFile, "额外信任的 CA 证书文件（PEM 格式），用于自签名证书的论坛")
+    fs.BoolVar(&cfg.InsecureSkipVerify, "insecure-skip-verify", cfg.InsecureSkipVerify, "跳过论坛 TLS 证书校验（不安全，仅用于测试）")
+    fs.StringVar(&cfg.Cookie, "cookie", cfg.Cookie, "请求论坛时附带的 Cookie，格式为 \"name=value; name2=value2\"，用于访问需要登录的版块")
+    fs.StringVar(&cfg.BasicAuth, "basic-auth", cfg.BasicAuth, "请求论坛时使用的 HTTP Basic 认证，格式为 user:pass")
+    fs.BoolVar(&cfg.IgnoreRobots, "ignore-robots", cfg.IgnoreRobots, "忽略 robots.txt 的限制，仅用于自己管理的论坛")
+    fs.Float64Var(&cfg.Rate, "rate", cfg.Rate, "每个站点每秒最多发出的请求数，例如 0.5 表示每 2 秒一次，0 表示不限速")
+    fs.Float64Var(&cfg.RetryBudget, "retry-budget", cfg.RetryBudget, "整个进程每秒最多重试的次数，抓取和通知共用，超出时放弃重试，0 表示不限制")
+    fs.IntVar(&cfg.MaxConcurrency, "max-concurrency", cfg.MaxConcurrency, "整个进程同时进行的页面请求数上限，所有论坛共用，0 表示不限制")
+    fs.IntVar(&cfg.MaxBody, "max-body", cfg.MaxBody, "论坛响应体的最大字节数，超出时本次抓取失败")
+    fs.StringVar(&cfg.CacheDir, "cache-dir", cfg.CacheDir, "把抓取的页面缓存到该目录，调试选择器时避免反复请求论坛，为空时不缓存")
+    fs.DurationVar(&cfg.CacheTTL, "cache-ttl", cfg.CacheTTL, "页面缓存的有效期")
+    fs.StringVar(&cfg.Selectors.List, "list-selector", cfg.Selectors.List, "论坛列表页中帖子链接的 CSS 选择器")
+    fs.Var(&listFlag{values: &cfg.Selectors.ListFallback}, "list-selector-fallback", "列表选择器没有匹配到帖子时依次尝试的备用选择器，可重复指定")
+    fs.StringVar(&cfg.Selectors.Title, "title-selector", cfg.Selectors.Title, "帖子页中标题的 CSS 选择器")
+    fs.Var(&listFlag{values: &cfg.Selectors.TitleFallback}, "title-selector-fallback", "标题选择器没有匹配到标题时依次尝试的备用选择器，可重复指定，都没有时使用页面 <title> 中的标题")
+    fs.StringVar(&cfg.Selectors.Message, "message-selector", cfg.Selectors.Message, "帖子页中正文的 CSS 选择器")
+    fs.StringVar(&cfg.Output, "output", cfg.Output, "额外的输出方式: ndjson 将每个新帖子写成一行 JSON")
+    fs.StringVar(&cfg.OutputFile, "output-file", cfg.OutputFile, "-output 追加写入的文件，为空时写到标准输出")
+    fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "只将通知内容打印到标准输出，不真正发送")
+    fs.BoolVar(&cfg.Once, "once", cfg.Once, "只检查一次后退出，适合配合 cron 使用")
+    fs.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "Prometheus 指标的监听地址，例如 :9090，为空时不启用")
+    fs.StringVar(&cfg.FeedAddr, "feed-addr", cfg.FeedAddr, "RSS 订阅源 /feed 的监听地址，例如 :8080，为空时不启用")
+    fs.IntVar(&cfg.FeedSize, "feed-size", cfg.FeedSize, "RSS 订阅源保留的最近帖子数量")
+    fs.StringVar(&cfg.HealthAddr, "health-addr", cfg.HealthAddr, "/healthz 健康检查的监听地址，例如 :8080，为空时不启用")
+    fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "日志级别: debug、info、warn 或 error")
+    fs.BoolVar(&cfg.Trace, "trace", cfg.Trace, "在 debug 日志中记录每次抓取的请求和响应详情（隐藏 Cookie 等敏感头），会同时把日志级别设为 debug")
+    fs.StringVar(&cfg.Lang, "lang", cfg.Lang, "通知标签和主要日志的语言: zh 或 en")
+    fs.BoolVar(&cfg.ShowVersion, "version", false, "打印版本信息后退出")
+    fs.BoolVar(&cfg.SelfCheck, "selfcheck", false, "检查机器人令牌并试抓取每个论坛页面，输出诊断结果后退出")
+    fs.BoolVar(&cfg.SelfCheckSend, "selfcheck-send", false, "自检时通过配置的通知渠道发送一条测试消息")
+    fs.BoolVar(&cfg.ListSelectors, "list-selectors", false, "输出各选择器在论坛页面上匹配到的元素数量和文本后退出，用于调试选择器")
+    fs.BoolVar(&cfg.PrintConfig, "print-config", false, "以 YAML 输出合并配置文件、环境变量和命令行参数后实际生效的配置（隐藏令牌等敏感值）后退出")
+    fs.BoolVar(&cfg.ReplayDeadLetter, "replay-deadletter", false, "重新发送死信文件中的所有帖子后退出，仍然失败的保留在文件中")
+    return fs, configPath
+}
+
+// Profile 一个独立运行的配置方案，有自己的论坛、选择器、间隔、过滤规则和通知渠道
+type Profile struct {
+    Name   string
+    Config *Config
+}
+
+// profileMeta 配置方案中用于识别和启停的字段
+type profileMeta struct {
+    Name    string `yaml:"name"`
+    Enabled *bool  `yaml:"enabled"`
+}
+
+// processKeys 只在顶层配置中生效的进程级设置，由所有配置方案共用
+var processKeys = []string{
+    "proxy", "ip_version", "ca_file", "insecure_skip_verify", "headers", "cookie", "basic_auth",
+    "forum_username", "forum_password", "ignore_robots", "rate", "retry_budget", "max_concurrency", "max_body",
+    "log_level", "trace", "lang", "metrics_addr", "health_addr", "feed_addr", "feed_size", "once",
+}
+
+// processKey 返回配置方案中设置的第一个进程级设置的键，没有时返回空字符串
+func processKey(node *yaml.Node) string {
+    if node.Kind != yaml.MappingNode {
+        return ""
+    }
+    for i := 0; i+1 < len(node.Content); i += 2 {
+        if key := node.Content[i].Value; slices.Contains(processKeys, key) {
+            return key
+        }
+    }
+    return ""
+}
+
+// profiles 返回需要运行的配置方案。没有配置 profiles 时只有顶层配置本身；
+// 否则以顶层配置为基础分别叠加每个方案的设置并校验，跳过 enabled: false 的方案。
+// 代理、日志、指标等进程级设置只取顶层配置，Validate 会拒绝在方案中设置它们
+func (c *Config) profiles() ([]Profile, error) {
+    if len(c.Profiles) == 0 {
+        return []Profile{{Config: c}}, nil
+    }
+    var profiles []Profile
+    names := make(map[string]bool)
+    for i := range c.Profiles {
+        var meta profileMeta
+        if err := c.Profiles[i].Decode(&meta); err != nil {
+            return nil, fmt.Errorf("parse profile %d: %w", i+1, err)
+        }
+        if meta.Name == "" {
+            return nil, fmt.Errorf("profile %d has no name", i+1)
+        }
+        if names[meta.Name] {
+            return nil, fmt.Errorf("duplicate profile name %q", meta.Name)
+        }
+        names[meta.Name] = true
+        if meta.Enabled != nil && !*meta.Enabled {
+            continue
+        }
+
+        // yaml 解码切片时整体替换，方案之间不会共享列表
+        pc := *c
+        pc.Profiles = nil
+        if err := c.Profiles[i].Decode(&pc); err != nil {
+            return nil, fmt.Errorf("parse profile %s: %w", meta.Name, err)
+        }
+        if err := pc.Validate(); err != nil {
+            return nil, fmt.Errorf("profile %s: %w", meta.Name, err)
+        }
+        profiles = append(profiles, Profile{Name: meta.Name, Config: &pc})
+    }
+    if len(profiles) == 0 {
+        return nil, errors.New("all profiles are disabled")
+    }
+    return profiles, nil
+}
+
+// loadConfig 合并默认值、配置文件和命令行参数得到最终配置
+func loadConfig(args []string) (*Config, error) {
+    // 第一次解析只为取得配置文件路径
+    fs, configPath := newFlagSet(defaultConfig())
+    if err := fs.Parse(args); err != nil {
+        return nil, err
+    }
+
+    cfg := defaultConfig()
+    if *configPath != "" {
+        if err := loadConfigFile(*configPath, cfg); err != nil {
+            return nil, err
+        }
+    }
+    applyEnv(cfg)
+
+    // 第二次解析时参数默认值取自配置文件和环境变量，只有命令行中显式给出的参数会覆盖
+    fs, _ = newFlagSet(cfg)
+    if err := fs.Parse(args); err != nil {
+        return nil, err
+    }
+    return cfg, nil
+}
+
+// loadConfigFile 读取 YAML 配置文件覆盖 cfg 中的值，JSON 是 YAML 的子集，同样可以读取
+func loadConfigFile(path string, cfg *Config) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("read config %s: %w", path, err)
+    }
+    if err := yaml.Unmarshal(data, cfg); err != nil {
+        return fmt.Errorf("parse config %s: %w", path, err)
+    }
+    return nil
+}
+
+// applyEnv 从环境变量读取敏感配置，避免 token 出现在命令行历史和 ps 输出中
+func applyEnv(cfg *Config) {
+    if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+        cfg.Token = token
+    }
+    if chatIDs := splitList(os.Getenv("TELEGRAM_CHAT_ID")); len(chatIDs) > 0 {
+        cfg.ChatIDs = chatIDs
+    }
+    if username := os.Getenv("YUC_FORUM_USERNAME"); username != "" {
+        cfg.ForumUsername = username
+    }
+    if password := os.Getenv("YUC_FORUM_PASSWORD"); password != "" {
+        cfg.ForumPassword = password
+    }
+    if password := os.Getenv("YUC_SMTP_PASSWORD"); password != "" {
+        cfg.SMTPPassword = password
+    }
+}
+
+// sinceTime 返回 -since 对应的时间，未设置时返回零值，格式已由 Validate 检查
+func (c *Config) sinceTime() time.Time {
+    t, _ := time.Parse(time.RFC3339, c.Since)
+    return t
+}
+
+// listRequest 返回请求列表页使用的方法和请求体，普通 GET 请求时返回零值
+func (c *Config) listRequest() requestSpec {
+    if c.Method == http.MethodGet {
+        return requestSpec{}
+    }
+    spec := requestSpec{Method: c.Method, Body: c.Body}
+    if c.Body != "" {
+        spec.ContentType = c.ContentType
+    }
+    return spec
+}
+
+// forums 合并 urls、forums 和目标文件得到需要监控的全部论坛，都未配置时监控鱼C论坛。
+// 目标文件已由 Validate 检查，之后读取失败时只返回配置文件中的论坛
+func (c *Config) forums() []ForumConfig {
+    targets, _ := c.targets()
+    return append(c.staticForums(), targets...)
+}
+
+// staticForums 合并 urls 和 forums 得到配置文件中直接列出的论坛，未配置目标文件且都未配置时监控鱼C论坛
+func (c *Config) staticForums() []ForumConfig {
+    var forums []ForumConfig
+    for _, u := range c.URLs {
+        forums = append(forums, ForumConfig{URL: u})
+    }
+    forums = append(forums, c.Forums...)
+    if len(forums) == 0 && c.TargetsFile == "" {
+        forums = append(forums, ForumConfig{URL: defaultForumURL})
+    }
+
+    for i := range forums {
+        forums[i].Selectors = forums[i].Selectors.withDefaults(c.Selectors)
+    }
+    return forums
+}
+
+// targets 读取目标文件中的论坛，未配置目标文件时返回 nil
+func (c *Config) targets() ([]ForumConfig, error) {
+    if c.TargetsFile == "" {
+        return nil, nil
+    }
+    return readTargets(c.TargetsFile, c.Selectors)
+}
+
+// forumLink 返回第一个论坛的地址，目标文件为空时返回鱼C论坛的地址，用作 RSS 等的链接
+func (c *Config) forumLink() string {
+    if forums := c.forums(); len(forums) > 0 {
+        return forums[0].URL
+    }
+    return defaultForumURL
+}
+
+// logLevel 返回配置的日志级别，无法识别时使用 info
+func (c *Config) logLevel() slog.Level {
+    if c.Trace {
+        return slog.LevelDebug
+    }
+    var level slog.Level
+    if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+        return slog.LevelInfo
+    }
+    return level
+}
+
+// telegramEnabled 是否配置了 Telegram 推送
+func (c *Config) telegramEnabled() bool {
+    return c.Token != "" || len(c.ChatIDs) > 0
+}
+
+// Validate 检查合并后的配置是否完整合法，并规范化 Chat ID
+func (c *Config) Validate() error {
+    if c.telegramEnabled() && (c.Token == "" || len(c.ChatIDs) == 0) {
+        return errors.New("telegram requires both token (-token or TELEGRAM_BOT_TOKEN) and chat id (-chatid or TELEGRAM_CHAT_ID)")
+    }
+    // 调试选择器时不发送通知，使用配置方案时由各方案分别检查
+    if len(c.Profiles) == 0 && !c.DryRun && !c.ListSelectors && !c.PrintConfig && !c.telegramEnabled() && c.DiscordWebhook == "" && c.SlackWebhook == "" && c.Webhook == "" && c.SMTPHost == "" && c.Exec == "" && c.Output == "" && c.FeedAddr == "" {
+        return errors.New("no notifier configured: set telegram token and chat id, discord webhook, slack webhook, webhook, smtp host, exec command, output or feed address")
+    }
+    if c.ReplayDeadLetter && c.DeadLetter == "" {
+        return errors.New("-replay-deadletter requires a dead-letter file (-deadletter)")
+    }
+    for i := range c.Profiles {
+        if key := processKey(&c.Profiles[i]); key != "" {
+            return fmt.Errorf("profile %d sets %s, which can only be set at the top level", i+1, key)
+        }
+    }
+    if c.SMTPHost != "" {
+        if c.SMTPFrom == "" || len(c.SMTPTo) == 0 {
+            return errors.New("smtp requires both a sender (-smtp-from) and recipients (-smtp-to)")
+        }
+        if c.SMTPPort < 1 || c.SMTPPort > 65535 {
+            return fmt.Errorf("invalid smtp port %d", c.SMTPPort)
+        }
+        if c.SMTPTLS != smtpStartTLS && c.SMTPTLS != smtpTLS && c.SMTPTLS != smtpNone {
+            return fmt.Errorf("unsupported smtp tls mode %q", c.SMTPTLS)
+        }
+    }
+    if c.Exec != "" && c.ExecTimeout <= 0 {
+        return fmt.Errorf("exec timeout must be positive, got %s", c.ExecTimeout)
+    }
+    if c.IPVersion != ipAuto && c.IPVersion != ipV4 && c.IPVersion != ipV6 {
+        return fmt.Errorf("unsupported ip version %q, must be 4, 6 or auto", c.IPVersion)
+    }
+    // 使用代理时由代理服务器连接论坛，本地只连接代理
+    if c.IPVersion != ipAuto && c.Proxy != "" {
+        return errors.New("-ip-version cannot be used together with -proxy")
+    }
+    for _, pattern := range c.CanonicalStrip {
+        if _, err := path.Match(pattern, ""); err != nil {
+            return fmt.Errorf("invalid canonical strip pattern %q: %w", pattern, err)
+        }
+    }
+    c.Method = strings.ToUpper(c.Method)
+    if c.Method != http.MethodGet && c.Method != http.MethodPost {
+        return fmt.Errorf("unsupported method %q, must be GET or POST", c.Method)
+    }
+    if c.Body != "" && c.Method == http.MethodGet {
+        return errors.New("-body requires -method POST")
+    }
+    if c.Since != "" {
+        if _, err := time.Parse(time.RFC3339, c.Since); err != nil {
+            return fmt.Errorf("invalid since %q, expected RFC3339 like 2024-05-01T00:00:00+08:00: %w", c.Since, err)
+        }
+    }
+    if c.QuietHours != "" {
+        if _, err := parseQuietWindow(c.QuietHours, c.QuietTimezone); err != nil {
+            return err
+        }
+    }
+    if c.FeedSize < 1 {
+        return fmt.Errorf("feed size must be at least 1, got %d", c.FeedSize)
+    }
+    if c.Output != "" && c.Output != outputNDJSON {
+        return fmt.Errorf("unsupported output %q", c.Output)
+    }
+    for i, chatID := range c.ChatIDs {
+        normalized, err := normalizeChatID(chatID)
+        if err != nil {
+            return err
+        }
+        c.ChatIDs[i] = normalized
+    }
+    if !validParseMode(c.ParseMode) {
+        return fmt.Errorf("unsupported parse mode %q", c.ParseMode)
+    }
+    // markdown 格式的正文是 MarkdownV2，html 格式的正文是 HTML，明确指定其他消息格式时正文无法正确显示；
+    // markdown 格式未指定消息格式时默认以 MarkdownV2 发送
+    switch {
+    case c.Format == formatMarkdown && c.ParseMode == "":
+        c.ParseMode = parseModeMarkdownV2
+    case c.Format == formatMarkdown && c.ParseMode != parseModeMarkdownV2:
+        return fmt.Errorf("format markdown requires parse mode %s, got %q", parseModeMarkdownV2, c.ParseMode)
+    case c.Format == formatHTML && c.ParseMode != "" && c.ParseMode != parseModeHTML:
+        return fmt.Errorf("format html requires parse mode %s, got %q", parseModeHTML, c.ParseMode)
+    }
+    if c.ThreadID < 0 {
+        return fmt.Errorf("thread id must be a positive integer, got %d", c.ThreadID)
+    }
+    if _, err := parseTemplate(c.Template); err != nil {
+        return err
+    }
+    if c.Format != formatPlain && c.Format != formatMarkdown && c.Format != formatHTML {
+        return fmt.Errorf("unsupported format %q", c.Format)
+    }
+    if c.Dedup != dedupURL && c.Dedup != dedupHash {
+        return fmt.Errorf("unsupported dedup mode %q", c.Dedup)
+    }
+    switch c.BatchSort {
+    case "", "replies", "views":
+    default:
+        return fmt.Errorf("unsupported batch sort %q", c.BatchSort)
+    }
+    if c.MinAge < 0 || c.MaxAge < 0 {
+        return fmt.Errorf("min age and max age must not be negative, got %v and %v", c.MinAge, c.MaxAge)
+    }
+    if c.MaxAge > 0 && c.MinAge >= c.MaxAge {
+        return fmt.Errorf("min age %v must be less than max age %v", c.MinAge, c.MaxAge)
+    }
+    if c.AgeUnknown != agePass && c.AgeUnknown != ageDrop {
+        return fmt.Errorf("unsupported age-unknown value %q", c.AgeUnknown)
+    }
+    if c.MinReplies < 0 {
+        return fmt.Errorf("min replies must not be negative, got %d", c.MinReplies)
+    }
+    if c.MaxLen < 0 {
+        return fmt.Errorf("max len must not be negative, got %d", c.MaxLen)
+    }
+    var level slog.Level
+    if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+        return fmt.Errorf("invalid log level %q", c.LogLevel)
+    }
+    if !validLang(c.Lang) {
+        return fmt.Errorf("unsupported language %q", c.Lang)
+    }
+    if c.Interval <= 0 {
+        return fmt.Errorf("interval must be positive, got %v", c.Interval)
+    }
+    if c.Jitter < 0 || c.Jitter >= c.Interval {
+        return fmt.Errorf("jitter must be in [0, interval), got %v", c.Jitter)
+    }
+    if (c.ForumUsername == "") != (c.ForumPassword == "") {
+        return errors.New("forum login requires both forum_username and forum_password (or YUC_FORUM_USERNAME and YUC_FORUM_PASSWORD)")
+    }
+    if c.BasicAuth != "" {
+        if _, _, err := parseBasicAuth(c.BasicAuth); err != nil {
+            return err
+        }
+    }
+    for _, w := range c.Watch {
+        if u, err := url.Parse(w); err != nil || u.Scheme == "" || u.Host == "" {
+            return fmt.Errorf("invalid watch url %q", w)
+        }
+    }
+    if len(c.Watch) > 0 && c.WatchInterval <= 0 {
+        return fmt.Errorf("watch interval must be positive, got %v", c.WatchInterval)
+    }
+    if c.MaxBody <= 0 {
+        return fmt.Errorf("max body must be positive, got %d", c.MaxBody)
+    }
+    if c.MaxConcurrency < 0 {
+        return fmt.Errorf("max concurrency must not be negative, got %d", c.MaxConcurrency)
+    }
+    if c.RetryBudget < 0 {
+        return fmt.Errorf("retry budget must not be negative, got %v", c.RetryBudget)
+    }
+    if c.Rate < 0 {
+        return fmt.Errorf("rate must not be negative, got %v", c.Rate)
+    }
+    if c.CacheDir != "" && c.CacheTTL <= 0 {
+        return fmt.Errorf("cache ttl must be positive, got %v", c.CacheTTL)
+    }
+    switch c.Store {
+    case storeFile, storeMemory:
+    case storeBloom:
+        // 布隆过滤器只保存在内存中，同时指定 -state 会让人误以为记录已持久化
+        if c.State != "" {
+            return errors.New("bloom store is memory-only and cannot be combined with -state")
+        }
+        if c.BloomCapacity < 1 {
+            return fmt.Errorf("bloom capacity must be at least 1, got %d", c.BloomCapacity)
+        }
+        if c.BloomFPRate <= 0 || c.BloomFPRate >= 1 {
+            return fmt.Errorf("bloom false positive rate must be between 0 and 1, got %v", c.BloomFPRate)
+        }
+    case storeSQLite:
+        if c.State == "" {
+            return errors.New("sqlite store requires a database path (-state)")
+        }
+    default:
+        return fmt.Errorf("unsupported store %q", c.Store)
+    }
+    if c.StoreMaxAge < 0 {
+        return fmt.Errorf("store max age must not be negative, got %v", c.StoreMaxAge)
+    }
+    if c.Retries < 1 {
+        return fmt.Errorf("retries must be at least 1, got %d", c.Retries)
+    }
+    if c.CatchUpPages < 1 {
+        return fmt.Errorf("catchup pages must be at least 1, got %d", c.CatchUpPages)
+    }
+    if c.BreakerThreshold < 0 {
+        return fmt.Errorf("breaker threshold must not be negative, got %d", c.BreakerThreshold)
+    }
+    if c.BreakerThreshold > 0 && c.BreakerCooldown <= 0 {
+        return fmt.Errorf("breaker cooldown must be positive, got %v", c.BreakerCooldown)
+    }
+    if c.EmptyAlertCycles < 0 {
+        return fmt.Errorf("empty alert cycles must not be negative, got %d", c.EmptyAlertCycles)
+    }
+    if c.MinSleep < 0 {
+        return fmt.Errorf("min sleep must not be negative, got %s", c.MinSleep)
+    }
+    if c.CycleTimeout < 0 {
+        return fmt.Errorf("cycle timeout must not be negative, got %s", c.CycleTimeout)
+    }
+    if c.Concurrency < 1 {
+        return fmt.Errorf("concurrency must be at least 1, got %d", c.Concurrency)
+    }
+    if _, err := parseHeaders(c.WebhookHeaders); err != nil {
+        return err
+    }
+    if _, err := parseHeaders(c.Headers); err != nil {
+        return err
+    }
+    if err := c.Selectors.Validate(); err != nil {
+        return err
+    }
+    if _, err := newFilter(c.Include, c.Exclude, c.CaseSensitive); err != nil {
+        return err
+    }
+
+    if _, err := c.targets(); err != nil {
+        return err
+    }
+    seen := make(map[string]bool)
+    for _, forum := range c.forums() {
+        if u, err := url.Parse(forum.URL); err != nil || u.Scheme == "" || u.Host == "" {
+            return fmt.Errorf("invalid forum url %q", forum.URL)
+        }
+        if seen[forum.URL] {
+            return fmt.Errorf("duplicate forum url %q", forum.URL)
+        }
+        seen[forum.URL] = true
+        if err := forum.Selectors.Validate(); err != nil {
+            return fmt.Errorf("forum %s: %w", forum.URL, err)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+    "log/slog"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// validConfig 返回一份可以通过 Validate 的最小配置
+func validConfig() *Config {
+    cfg := defaultConfig()
+    cfg.Webhook = "http://example.com/hook"
+    return cfg
+}
+
+// writeConfig 把内容写入临时目录中的配置文件并返回路径
+func writeConfig(t *testing.T, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestConfigValidate(t *testing.T) {
+    tests := []struct {
+        name   string
+        modify func(*Config)
+        err    string
+    }{
+        {"valid", func(c *Config) {}, ""},
+        {"no notifier", func(c *Config) { c.Webhook = "" }, "no notifier configured"},
+        {"dry run needs no notifier", func(c *Config) { c.Webhook = ""; c.DryRun = true }, ""},
+        {"telegram token only", func(c *Config) { c.Token = "t" }, "telegram requires both"},
+        {"telegram chat only", func(c *Config) { c.ChatIDs = []string{"123"} }, "telegram requires both"},
+        {"telegram complete", func(c *Config) { c.Token = "t"; c.ChatIDs = []string{"123"} }, ""},
+        {"invalid chat id", func(c *Config) { c.Token = "t"; c.ChatIDs = []string{"abc"} }, "invalid chat id"},
+        {"replay without deadletter", func(c *Config) { c.ReplayDeadLetter = true }, "-replay-deadletter requires"},
+        {"smtp without recipients", func(c *Config) { c.SMTPHost = "mail"; c.SMTPFrom = "a@b.c" }, "smtp requires both"},
+        {"smtp bad tls", func(c *Config) {
+            c.SMTPHost = "mail"
+            c.SMTPFrom = "a@b.c"
+            c.SMTPTo = []string{"d@e.f"}
+            c.SMTPTLS = "ssl"
+        }, "unsupported smtp tls mode"},
+        {"ip version with proxy", func(c *Config) { c.IPVersion = ipV4; c.Proxy = "http://proxy:8080" }, "-ip-version cannot be used"},
+        {"bad ip version", func(c *Config) { c.IPVersion = "5" }, "unsupported ip version"},
+        {"bad canonical pattern", func(c *Config) { c.CanonicalStrip = []string{"["} }, "invalid canonical strip pattern"},
+        {"bad method", func(c *Config) { c.Method = "PUT" }, "unsupported method"},
+        {"body with get", func(c *Config) { c.Body = "a=1" }, "-body requires -method POST"},
+        {"post with body", func(c *Config) { c.Method = "post"; c.Body = "a=1" }, ""},
+        {"bad since", func(c *Config) { c.Since = "yesterday" }, "invalid since"},
+        {"bad quiet hours", func(c *Config) { c.QuietHours = "25:00-07:00" }, "quiet"},
+        {"exec without timeout", func(c *Config) { c.Exec = "true"; c.ExecTimeout = 0 }, "exec timeout must be positive"},
+        {"negative min sleep", func(c *Config) { c.MinSleep = -time.Second }, "min sleep must not be negative"},
+        {"bad output", func(c *Config) { c.Output = "csv" }, "unsupported output"},
+        {"bad parse mode", func(c *Config) { c.ParseMode = "BBCode" }, "unsupported parse mode"},
+        {"markdown with html parse mode", func(c *Config) { c.Format = formatMarkdown; c.ParseMode = parseModeHTML }, "format markdown requires parse mode MarkdownV2"},
+        {"markdown with legacy parse mode", func(c *Config) { c.Format = formatMarkdown; c.ParseMode = parseModeMarkdown }, "format markdown requires parse mode MarkdownV2"},
+        {"markdown with markdownv2", func(c *Config) { c.Format = formatMarkdown; c.ParseMode = parseModeMarkdownV2 }, ""},
+        {"html with markdown parse mode", func(c *Config) { c.Format = formatHTML; c.ParseMode = parseModeMarkdownV2 }, "format html requires parse mode HTML"},
+        {"html with html parse mode", func(c *Config) { c.Format = formatHTML; c.ParseMode = parseModeHTML }, ""},
+        {"html without parse mode", func(c *Config) { c.Format = formatHTML }, ""},
+        {"bad format", func(c *Config) { c.Format = "rtf" }, "unsupported format"},
+        {"bad dedup", func(c *Config) { c.Dedup = "title" }, "unsupported dedup mode"},
+        {"bad batch sort", func(c *Config) { c.BatchSort = "time" }, "unsupported batch sort"},
+        {"min age not below max age", func(c *Config) { c.MinAge = time.Hour; c.MaxAge = time.Hour }, "must be less than max age"},
+        {"bad age unknown", func(c *Config) { c.AgeUnknown = "keep" }, "unsupported age-unknown"},
+        {"negative cycle timeout", func(c *Config) { c.CycleTimeout = -time.Second }, "cycle timeout must not be negative"},
+        {"bad log level", func(c *Config) { c.LogLevel = "verbose" }, "invalid log level"},
+        {"bad lang", func(c *Config) { c.Lang = "fr" }, "unsupported language"},
+        {"zero interval", func(c *Config) { c.Interval = 0 }, "interval must be positive"},
+        {"jitter not below interval", func(c *Config) { c.Jitter = c.Interval }, "jitter must be in"},
+        {"forum username only", func(c *Config) { c.ForumUsername = "u" }, "forum login requires both"},
+        {"bad basic auth", func(c *Config) { c.BasicAuth = "nocolon" }, "basic"},
+        {"bad watch url", func(c *Config) { c.Watch = []string{"not a url"} }, "invalid watch url"},
+        {"missing targets file", func(c *Config) { c.TargetsFile = "/nonexistent/targets.txt" }, "read targets"},
+        {"sqlite without state", func(c *Config) { c.Store = storeSQLite }, "sqlite store requires a database path"},
+        {"sqlite with state", func(c *Config) { c.Store = storeSQLite; c.State = "seen.db" }, ""},
+        {"unknown store", func(c *Config) { c.Store = "redis" }, "unsupported store"},
+        {"bloom with state", func(c *Config) { c.Store = storeBloom; c.State = "seen.txt" }, "bloom store is memory-only"},
+        {"bloom bad rate", func(c *Config) { c.Store = storeBloom; c.BloomFPRate = 1 }, "bloom false positive rate"},
+        {"zero retries", func(c *Config) { c.Retries = 0 }, "retries must be at least 1"},
+        {"negative retry budget", func(c *Config) { c.RetryBudget = -1 }, "retry budget must not be negative"},
+        {"zero catchup pages", func(c *Config) { c.CatchUpPages = 0 }, "catchup pages must be at least 1"},
+        {"breaker without cooldown", func(c *Config) { c.BreakerCooldown = 0 }, "breaker cooldown must be positive"},
+        {"zero concurrency", func(c *Config) { c.Concurrency = 0 }, "concurrency must be at least 1"},
+        {"negative max concurrency", func(c *Config) { c.MaxConcurrency = -1 }, "max concurrency must not be negative"},
+        {"bad header", func(c *Config) { c.Headers = []string{"NoColon"} }, "header"},
+        {"bad include regex", func(c *Config) { c.Include = []string{"re:("} }, "invalid filter pattern"},
+        {"bad forum url", func(c *Config) { c.URLs = []string{"/relative"} }, "invalid forum url"},
+        {"duplicate forum url", func(c *Config) {
+            c.URLs = []string{"https://a.example/"}
+            c.Forums = []ForumConfig{{URL: "https://a.example/"}}
+        }, "duplicate forum url"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := validConfig()
+            tt.modify(cfg)
+            err := cfg.Validate()
+            if tt.err == "" {
+                if err != nil {
+                    t.Fatalf("Validate() = %v, want nil", err)
+                }
+                return
+            }
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Fatalf("Validate() = %v, want error containing %q", err, tt.err)
+            }
+        })
+    }
+}
+
+func TestConfigValidateNormalizes(t *testing.T) {
+    cfg := validConfig()
+    cfg.Token = "t"
+    cfg.ChatIDs = []string{" https://t.me/some_channel/ ", "-1001234567890"}
+    cfg.Method = "post"
+    cfg.Format = formatMarkdown
+    if err := cfg.Validate(); err != nil {
+        t.Fatal(err)
+    }
+    if cfg.ChatIDs[0] != "@some_channel" || cfg.ChatIDs[1] != "-1001234567890" {
+        t.Errorf("chat ids = %q", cfg.ChatIDs)
+    }
+    if cfg.Method != http.MethodPost {
+        t.Errorf("method = %q, want POST", cfg.Method)
+    }
+    if cfg.ParseMode != parseModeMarkdownV2 {
+        t.Errorf("parse mode = %q, want %q for markdown format", cfg.ParseMode, parseModeMarkdownV2)
+    }
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+    path := writeConfig(t, `
+token: file-token
+chat_ids: ["1", "2"]
+interval: 1m
+include: [python]
+forum_username: file-user
+`)
+    t.Setenv("TELEGRAM_BOT_TOKEN", "env-token")
+    t.Setenv("TELEGRAM_CHAT_ID", "")
+    t.Setenv("YUC_FORUM_USERNAME", "env-user")
+    t.Setenv("YUC_FORUM_PASSWORD", "")
+    t.Setenv("YUC_SMTP_PASSWORD", "")
+
+    cfg, err := loadConfig([]string{"-config", path, "-chatid", "3,4", "-include", "go"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cfg.Token != "env-token" {
+        t.Errorf("token = %q, want env value to override file", cfg.Token)
+    }
+    if strings.Join(cfg.ChatIDs, ",") != "3,4" {
+        t.Errorf("chat ids = %q, want flag value to override file", cfg.ChatIDs)
+    }
+    if strings.Join(cfg.Include, ",") != "go" {
+        t.Errorf("include = %q, want flag value to replace file list", cfg.Include)
+    }
+    if cfg.Interval != time.Minute {
+        t.Errorf("interval = %v, want file value", cfg.Interval)
+    }
+    if cfg.ForumUsername != "env-user" {
+        t.Errorf("forum username = %q, want env value", cfg.ForumUsername)
+    }
+    if cfg.Retries != 3 {
+        t.Errorf("retries = %d, want default", cfg.Retries)
+    }
+
+    cfg, err = loadConfig([]string{"-config", path, "-token", "flag-token"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cfg.Token != "flag-token" {
+        t.Errorf("token = %q, want flag value to override env", cfg.Token)
+    }
+    if strings.Join(cfg.ChatIDs, ",") != "1,2" {
+        t.Errorf("chat ids = %q, want file value", cfg.ChatIDs)
+    }
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+    if _, err := loadConfig([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil || !strings.Contains(err.Error(), "read config") {
+        t.Errorf("missing file: err = %v", err)
+    }
+    path := writeConfig(t, "interval: [not a duration]\n")
+    if _, err := loadConfig([]string{"-config", path}); err == nil || !strings.Contains(err.Error(), "parse config") {
+        t.Errorf("bad yaml: err = %v", err)
+    }
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+    path := writeConfig(t, `{"webhook": "http://example.com/hook", "urls": ["https://a.example/"], "min_replies": 2}`)
+    cfg, err := loadConfig([]string{"-config", path})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cfg.Webhook != "http://example.com/hook" || cfg.MinReplies != 2 || len(cfg.URLs) != 1 {
+        t.Errorf("config = webhook %q, min replies %d, urls %q", cfg.Webhook, cfg.MinReplies, cfg.URLs)
+    }
+}
+
+func TestConfigProfiles(t *testing.T) {
+    tests := []struct {
+        name  string
+        yaml  string
+        names []string
+        err   string
+    }{
+        {"none", "webhook: http://example.com/hook\n", []string{""}, ""},
+        {"inherit and override", `
+webhook: http://example.com/hook
+interval: 1m
+profiles:
+  - name: a
+  - name: b
+    interval: 2m
+    include: [go]
+`, []string{"a", "b"}, ""},
+        {"disabled skipped", `
+webhook: http://example.com/hook
+profiles:
+  - name: a
+    enabled: false
+  - name: b
+`, []string{"b"}, ""},
+        {"all disabled", `
+webhook: http://example.com/hook
+profiles:
+  - name: a
+    enabled: false
+`, nil, "all profiles are disabled"},
+        {"missing name", `
+webhook: http://example.com/hook
+profiles:
+  - interval: 1m
+`, nil, "profile 1 has no name"},
+        {"duplicate name", `
+webhook: http://example.com/hook
+profiles:
+  - name: a
+  - name: a
+`, nil, `duplicate profile name "a"`},
+        {"invalid profile", `
+webhook: http://example.com/hook
+profiles:
+  - name: a
+    dedup: title
+`, nil, "profile a: unsupported dedup mode"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := defaultConfig()
+            if err := loadConfigFile(writeConfig(t, tt.yaml), cfg); err != nil {
+                t.Fatal(err)
+            }
+            profiles, err := cfg.profiles()
+            if tt.err != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.err) {
+                    t.Fatalf("profiles() = %v, want error containing %q", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            var names []string
+            for _, p := range profiles {
+                names = append(names, p.Name)
+            }
+            if strings.Join(names, ",") != strings.Join(tt.names, ",") {
+                t.Errorf("profiles = %q, want %q", names, tt.names)
+            }
+        })
+    }
+}
+
+func TestConfigValidateProcessSettingsInProfile(t *testing.T) {
+    for _, key := range []string{"proxy: socks5://127.0.0.1:1080", "lang: en", "trace: true", "forum_password: secret"} {
+        cfg := defaultConfig()
+        if err := loadConfigFile(writeConfig(t, "webhook: http://example.com/hook\nprofiles:\n  - name: a\n  - name: b\n    "+key+"\n"), cfg); err != nil {
+            t.Fatal(err)
+        }
+        name := strings.SplitN(key, ":", 2)[0]
+        if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "profile 2 sets "+name) {
+            t.Errorf("%s: Validate() = %v, want an error naming the process-level setting", name, err)
+        }
+    }
+
+    cfg := defaultConfig()
+    if err := loadConfigFile(writeConfig(t, "webhook: http://example.com/hook\nproxy: socks5://127.0.0.1:1080\nprofiles:\n  - name: a\n    interval: 1m\n"), cfg); err != nil {
+        t.Fatal(err)
+    }
+    if err := cfg.Validate(); err != nil {
+        t.Errorf("top-level proxy: Validate() = %v", err)
+    }
+}
+
+func TestConfigProfilesInherit(t *testing.T) {
+    cfg := defaultConfig()
+    err := loadConfigFile(writeConfig(t, `
+webhook: http://example.com/hook
+interval: 1m
+include: [python]
+profiles:
+  - name: a
+  - name: b
+    interval: 2m
+    include: [go]
+`), cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    profiles, err := cfg.profiles()
+    if err != nil {
+        t.Fatal(err)
+    }
+    a, b := profiles[0].Config, profiles[1].Config
+    if a.Interval != time.Minute || strings.Join(a.Include, ",") != "python" {
+        t.Errorf("profile a = interval %v, include %q, want top-level values", a.Interval, a.Include)
+    }
+    if b.Interval != 2*time.Minute || strings.Join(b.Include, ",") != "go" {
+        t.Errorf("profile b = interval %v, include %q, want its own values", b.Interval, b.Include)
+    }
+    if b.Webhook != cfg.Webhook {
+        t.Errorf("profile b webhook = %q, want inherited %q", b.Webhook, cfg.Webhook)
+    }
+    if strings.Join(cfg.Include, ",") != "python" {
+        t.Errorf("top-level include changed to %q", cfg.Include)
+    }
+}
+
+func TestConfigListRequest(t *testing.T) {
+    tests := []struct {
+        name   string
+        method string
+        body   string
+        want   requestSpec
+    }{
+        {"get", http.MethodGet, "", requestSpec{}},
+        {"post without body", http.MethodPost, "", requestSpec{Method: http.MethodPost}},
+        {"post with body", http.MethodPost, "a=1", requestSpec{Method: http.MethodPost, Body: "a=1", ContentType: "application/x-www-form-urlencoded"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            cfg := defaultConfig()
+            cfg.Method = tt.method
+            cfg.Body = tt.body
+            if got := cfg.listRequest(); got != tt.want {
+                t.Errorf("listRequest() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestConfigLogLevel(t *testing.T) {
+    tests := []struct {
+        level string
+        trace bool
+        want  slog.Level
+    }{
+        {"info", false, slog.LevelInfo},
+        {"warn", false, slog.LevelWarn},
+        {"DEBUG", false, slog.LevelDebug},
+        {"bogus", false, slog.LevelInfo},
+        {"error", true, slog.LevelDebug},
+    }
+    for _, tt := range tests {
+        cfg := defaultConfig()
+        cfg.LogLevel = tt.level
+        cfg.Trace = tt.trace
+        if got := cfg.logLevel(); got != tt.want {
+            t.Errorf("logLevel(%q, trace=%v) = %v, want %v", tt.level, tt.trace, got, tt.want)
+        }
+    }
+}
+
+func TestConfigForumsDefault(t *testing.T) {
+    cfg := defaultConfig()
+    forums := cfg.forums()
+    if len(forums) != 1 || forums[0].URL != defaultForumURL {
+        t.Fatalf("forums() = %+v, want only the default forum", forums)
+    }
+    if forums[0].Selectors.List == "" {
+        t.Error("default forum selectors were not filled in")
+    }
+
+    cfg.URLs = []string{"https://a.example/"}
+    if forums := cfg.forums(); len(forums) != 1 || forums[0].URL != "https://a.example/" {
+        t.Errorf("forums() = %+v, want only the configured url", forums)
+    }
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "sync"
+    "time"
+)
+
+// deadLetterMu 串行化对死信文件的读写，多个通知渠道可能同时写入同一个文件
+var deadLetterMu sync.Mutex
+
+// deadLetterRecord 死信文件中的一行，记录重试用尽后仍发送失败的帖子
+type deadLetterRecord struct {
+    webhookPayload
+    Images   []string  `json:"images,omitempty"`
+    Format   string    `json:"format,omitempty"`
+    Target   string    `json:"target"`
+    Error    string    `json:"error"`
+    FailedAt time.Time `json:"failed_at"`
+}
+
+// post 还原记录中的帖子
+func (r deadLetterRecord) post() Post {
+    return Post{
+        URL:     r.URL,
+        Title:   r.Title,
+        Message: r.Message,
+        Author:  r.Author,
+        Time:    r.Timestamp,
+        Images:  r.Images,
+        Format:  r.Format,
+    }
+}
+
+// deadLetterNotifier 包装单个通知渠道，发送失败时把帖子和错误追加到死信文件，之后可以用 -replay-deadletter 重新发送
+type deadLetterNotifier struct {
+    Notifier
+    Target string
+    Path   string
+}
+
+func (n *deadLetterNotifier) Notify(ctx context.Context, p Post) error {
+    err := n.Notifier.Notify(ctx, p)
+    if err != nil {
+        n.record([]Post{p}, err)
+    }
+    return err
+}
+
+// NotifyBatch 渠道支持合并推送时失败会记录整批帖子，否则逐个推送并只记录失败的帖子
+func (n *deadLetterNotifier) NotifyBatch(ctx context.Context, posts []Post) error {
+    if _, ok := n.Notifier.(BatchNotifier); !ok {
+        var errs []error
+        for _, p := range posts {
+            if err := n.Notify(ctx, p); err != nil {
+                errs = append(errs, fmt.Errorf("%s: %w", p.URL, err))
+            }
+        }
+        return errors.Join(errs...)
+    }
+    err := notifyBatch(ctx, n.Notifier, posts)
+    if err != nil {
+        n.record(posts, err)
+    }
+    return err
+}
+
+// record 追加死信记录，写入失败时只记录日志
+func (n *deadLetterNotifier) record(posts []Post, sendErr error) {
+    records := make([]deadLetterRecord, 0, len(posts))
+    for _, p := range posts {
+        records = append(records, newDeadLetterRecord(p, n.Target, sendErr))
+    }
+    deadLetterMu.Lock()
+    defer deadLetterMu.Unlock()
+    if err := appendDeadLetters(n.Path, records); err != nil {
+        slog.Error("写入死信文件失败", "path", n.Path, "err", err)
+    }
+}
+
+// newDeadLetterRecord 创建发送到 target 失败的记录
+func newDeadLetterRecord(p Post, target string, err error) deadLetterRecord {
+    return deadLetterRecord{
+        webhookPayload: webhookPayload{
+            URL:       p.URL,
+            Title:     p.Title,
+            Message:   p.Message,
+            Author:    p.Author,
+            Timestamp: p.Time,
+        },
+        Images:   p.Images,
+        Format:   p.Format,
+        Target:   target,
+        Error:    err.Error(),
+        FailedAt: time.Now().UTC(),
+    }
+}
+
+// appendDeadLetters 把记录逐行追加到 path
+func appendDeadLetters(path string, records []deadLetterRecord) error {
+    f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    for _, r := range records {
+        line, err := json.Marshal(r)
+        if err != nil {
+            return err
+        }
+        if _, err := f.Write(append(line, '\n')); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// replayDeadLetters 重新发送死信文件中的所有记录，每条记录只发给当初失败的渠道。
+// 成功的记录从文件中删除，仍然失败的更新错误信息后保留，全部成功时删除文件
+func replayDeadLetters(ctx context.Context, path string, targets map[string]Notifier) (sent, failed int, err error) {
+    deadLetterMu.Lock()
+    defer deadLetterMu.Unlock()
+
+    f, err := os.Open(path)
+    if err != nil {
+        return 0, 0, err
+    }
+    var records []deadLetterRecord
+    scanner := bufio.NewScanner(f)
+    scanner.Buffer(nil, 16<<20)
+    for line := 1; scanner.Scan(); line++ {
+        if len(scanner.Bytes()) == 0 {
+            continue
+        }
+        var r deadLetterRecord
+        if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+            f.Close()
+            return 0, 0, fmt.Errorf("parse %s line %d: %w", path, line, err)
+        }
+        records = append(records, r)
+    }
+    f.Close()
+    if err := scanner.Err(); err != nil {
+        return 0, 0, fmt.Errorf("read %s: %w", path, err)
+    }
+
+    var remaining []deadLetterRecord
+    for _, r := range records {
+        n, ok := targets[r.Target]
+        if !ok {
+            slog.Warn("死信记录的通知渠道未配置，保留记录", "target", r.Target, "post_url", r.URL)
+            remaining = append(remaining, r)
+            continue
+        }
+        if err := n.Notify(ctx, r.post()); err != nil {
+            slog.Error("重新发送失败", "target", r.Target, "post_url", r.URL, "err", err)
+            remaining = append(remaining, newDeadLetterRecord(r.post(), r.Target, err))
+            continue
+        }
+        slog.Info("重新发送成功", "target", r.Target, "post_url", r.URL)
+        sent++
+    }
+
+    if len(remaining) == 0 {
+        return sent, 0, os.Remove(path)
+    }
+    tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+    os.Remove(tmp)
+    if err := appendDeadLetters(tmp, remaining); err != nil {
+        return sent, len(remaining), err
+    }
+    return sent, len(remaining), os.Rename(tmp, path)
+}
+
+// deadLetterTargets 从 buildNotifier 创建的通知渠道中取出被死信包装的各渠道，按名称索引
+func deadLetterTargets(n Notifier) map[string]Notifier {
+    targets := make(map[string]Notifier)
+    multi, _ := n.(multiNotifier)
+    for _, target := range multi {
+        if dl, ok := target.(*deadLetterNotifier); ok {
+            targets[dl.Target] = dl.Notifier
+        }
+    }
+    return targets
+}
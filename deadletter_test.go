@@ -0,0 +1,165 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// readDeadLetters 读取死信文件中的全部记录
+func readDeadLetters(t *testing.T, path string) []deadLetterRecord {
+    t.Helper()
+    f, err := os.Open(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+    var records []deadLetterRecord
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        var r deadLetterRecord
+        if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+            t.Fatal(err)
+        }
+        records = append(records, r)
+    }
+    return records
+}
+
+func TestDeadLetterNotifierRecordsFailures(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "dead.ndjson")
+    n := &deadLetterNotifier{Notifier: &recordingNotifier{err: errors.New("boom")}, Target: "Webhook", Path: path}
+    post := Post{URL: "https://a.example/t1", Title: "标题", Message: "正文", Author: "fish", Time: "2024-5-12 10:00", Images: []string{"https://a.example/1.png"}, Format: formatHTML}
+    if err := n.Notify(context.Background(), post); err == nil {
+        t.Fatal("Notify() = nil, want the send error")
+    }
+
+    records := readDeadLetters(t, path)
+    if len(records) != 1 {
+        t.Fatalf("records = %d, want 1", len(records))
+    }
+    r := records[0]
+    if r.Target != "Webhook" || r.Error != "boom" || r.FailedAt.IsZero() {
+        t.Errorf("record = %+v", r)
+    }
+    got := r.post()
+    if got.URL != post.URL || got.Title != post.Title || got.Message != post.Message || got.Author != post.Author ||
+        got.Time != post.Time || got.Format != post.Format || len(got.Images) != 1 {
+        t.Errorf("restored post = %+v, want %+v", got, post)
+    }
+
+    ok := &deadLetterNotifier{Notifier: &recordingNotifier{}, Target: "Webhook", Path: path}
+    if err := ok.Notify(context.Background(), post); err != nil {
+        t.Fatal(err)
+    }
+    if len(readDeadLetters(t, path)) != 1 {
+        t.Error("successful notification was recorded")
+    }
+}
+
+func TestDeadLetterNotifierBatch(t *testing.T) {
+    posts := []Post{{URL: "u1"}, {URL: "u2"}}
+
+    path := filepath.Join(t.TempDir(), "batch.ndjson")
+    batch := &deadLetterNotifier{Notifier: &recordingBatchNotifier{recordingNotifier{err: errors.New("boom")}}, Target: "Slack", Path: path}
+    if err := batch.NotifyBatch(context.Background(), posts); err == nil {
+        t.Fatal("NotifyBatch() = nil, want the send error")
+    }
+    if n := len(readDeadLetters(t, path)); n != 2 {
+        t.Errorf("batch records = %d, want the whole batch", n)
+    }
+
+    path = filepath.Join(t.TempDir(), "single.ndjson")
+    single := &deadLetterNotifier{Notifier: &failingFor{url: "u2"}, Target: "Webhook", Path: path}
+    err := single.NotifyBatch(context.Background(), posts)
+    if err == nil || !strings.Contains(err.Error(), "u2") {
+        t.Fatalf("NotifyBatch() = %v, want the failed post named", err)
+    }
+    records := readDeadLetters(t, path)
+    if len(records) != 1 || records[0].URL != "u2" {
+        t.Errorf("records = %+v, want only the failed post", records)
+    }
+}
+
+// failingFor 只在发送 url 对应的帖子时失败
+type failingFor struct {
+    url string
+}
+
+func (n *failingFor) Notify(_ context.Context, p Post) error {
+    if p.URL == n.url {
+        return errors.New("rejected")
+    }
+    return nil
+}
+
+func TestReplayDeadLetters(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "dead.ndjson")
+    records := []deadLetterRecord{
+        newDeadLetterRecord(Post{URL: "u1", Title: "one"}, "Webhook", errors.New("old")),
+        newDeadLetterRecord(Post{URL: "u2", Title: "two"}, "Webhook", errors.New("old")),
+        newDeadLetterRecord(Post{URL: "u3", Title: "three"}, "Discord", errors.New("old")),
+    }
+    if err := appendDeadLetters(path, records); err != nil {
+        t.Fatal(err)
+    }
+
+    webhook := &failingFor{url: "u2"}
+    sent, failed, err := replayDeadLetters(context.Background(), path, map[string]Notifier{"Webhook": webhook})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if sent != 1 || failed != 2 {
+        t.Errorf("sent, failed = %d, %d, want 1, 2", sent, failed)
+    }
+    remaining := readDeadLetters(t, path)
+    if len(remaining) != 2 || remaining[0].URL != "u2" || remaining[0].Error != "rejected" || remaining[1].Target != "Discord" {
+        t.Errorf("remaining = %+v, want u2 with the new error and the unconfigured Discord record", remaining)
+    }
+
+    discord := &recordingNotifier{}
+    sent, failed, err = replayDeadLetters(context.Background(), path, map[string]Notifier{"Webhook": &recordingNotifier{}, "Discord": discord})
+    if err != nil || sent != 2 || failed != 0 {
+        t.Fatalf("replay = %d, %d, %v, want all sent", sent, failed, err)
+    }
+    if len(discord.posts) != 1 || discord.posts[0].Title != "three" {
+        t.Errorf("discord posts = %+v", discord.posts)
+    }
+    if _, err := os.Stat(path); !os.IsNotExist(err) {
+        t.Errorf("dead-letter file still exists after everything was sent: %v", err)
+    }
+}
+
+func TestReplayDeadLettersErrors(t *testing.T) {
+    dir := t.TempDir()
+    if _, _, err := replayDeadLetters(context.Background(), filepath.Join(dir, "missing"), nil); !os.IsNotExist(err) {
+        t.Errorf("missing file = %v", err)
+    }
+    path := filepath.Join(dir, "bad.ndjson")
+    if err := os.WriteFile(path, []byte("{}\n\nnot json\n"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    if _, _, err := replayDeadLetters(context.Background(), path, nil); err == nil || !strings.Contains(err.Error(), "line 3") {
+        t.Errorf("bad line = %v, want the line number", err)
+    }
+}
+
+func TestDeadLetterTargets(t *testing.T) {
+    webhook, discord := &recordingNotifier{}, &recordingNotifier{}
+    targets := deadLetterTargets(multiNotifier{
+        &deadLetterNotifier{Notifier: webhook, Target: "Webhook"},
+        &deadLetterNotifier{Notifier: discord, Target: "Discord"},
+        &StdoutNotifier{},
+    })
+    if len(targets) != 2 || targets["Webhook"] != Notifier(webhook) || targets["Discord"] != Notifier(discord) {
+        t.Errorf("targets = %v", targets)
+    }
+    if len(deadLetterTargets(webhook)) != 0 {
+        t.Error("plain notifier produced targets")
+    }
+}
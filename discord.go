@@ -0,0 +1,103 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// discordMessageLimit Discord 单条消息 content 的最大字符数
+const discordMessageLimit = 2000
+
+// discordReplacer 转义 Discord Markdown 中的特殊字符
+var discordReplacer = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "|", `\|`, ">", `\>`)
+
+// DiscordNotifier 通过 Discord Webhook 推送帖子
+type DiscordNotifier struct {
+    WebhookURL string
+}
+
+// discordPayload Discord Webhook 的请求体
+type discordPayload struct {
+    Content string `json:"content"`
+}
+
+// Notify 格式化帖子并发送到 Discord，超过长度限制时拆分为多条
+func (n *DiscordNotifier) Notify(ctx context.Context, p Post) error {
+    return n.sendContent(ctx, formatDiscordPost(p))
+}
+
+// NotifyBatch 将多个帖子合并为一条消息发送，超过长度限制时拆分
+func (n *DiscordNotifier) NotifyBatch(ctx context.Context, posts []Post) error {
+    parts := make([]string, len(posts))
+    for i, p := range posts {
+        parts[i] = formatDiscordPost(p)
+    }
+    return n.sendContent(ctx, strings.Join(parts, batchSeparator))
+}
+
+// sendContent 按长度限制拆分消息并依次发送
+func (n *DiscordNotifier) sendContent(ctx context.Context, content string) error {
+    for _, part := range splitMessage(content, discordMessageLimit) {
+        err := retryNotify(ctx, "Discord", func() (time.Duration, bool, error) {
+            return n.send(ctx, part)
+        })
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// formatDiscordPost 将帖子格式化为 Discord 消息，链接用尖括号包裹以关闭预览
+func formatDiscordPost(p Post) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "**%s**\n<%s>\n", discordReplacer.Replace(p.Title), p.URL)
+    if p.Author != "" {
+        fmt.Fprintf(&b, "%s: %s\n", msg("label.author"), discordReplacer.Replace(p.Author))
+    }
+    if p.Time != "" {
+        fmt.Fprintf(&b, "%s: %s\n", msg("label.time"), discordReplacer.Replace(p.Time))
+    }
+    fmt.Fprintf(&b, "%s: %s", msg("label.content"), discordReplacer.Replace(plainMessage(p)))
+    return b.String()
+}
+
+// send 发送单条消息，返回建议的等待时间以及该错误是否值得重试
+func (n *DiscordNotifier) send(ctx context.Context, content string) (time.Duration, bool, error) {
+    resp, err := postJSON(ctx, n.WebhookURL, nil, discordPayload{Content: content})
+    if err != nil {
+        return 0, true, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+        return 0, false, nil
+    }
+
+    err = fmt.Errorf("failed to send message to Discord, status code: %d", resp.StatusCode)
+    if resp.StatusCode == http.StatusTooManyRequests {
+        return discordRetryAfter(resp), true, err
+    }
+    return 0, resp.StatusCode >= 500, err
+}
+
+// discordRetryAfter 读取 Discord 限流响应中的 retry_after（单位为秒，可能是小数）
+func discordRetryAfter(resp *http.Response) time.Duration {
+    var result struct {
+        RetryAfter float64 `json:"retry_after"`
+    }
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+    if json.Unmarshal(body, &result) == nil && result.RetryAfter > 0 {
+        return time.Duration(result.RetryAfter * float64(time.Second))
+    }
+    if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+        return time.Duration(seconds * float64(time.Second))
+    }
+    return 0
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+    "unicode/utf8"
+)
+
+func TestDiscordNotifierFormatsAndSplits(t *testing.T) {
+    hook, url := newFakeWebhook(t)
+    n := &DiscordNotifier{WebhookURL: url}
+    post := Post{URL: "https://fishc.com.cn/t", Title: "a*b_c", Author: "鱼油", Message: strings.Repeat("正文内容。", 500)}
+    if err := n.Notify(context.Background(), post); err != nil {
+        t.Fatal(err)
+    }
+    if len(hook.bodies) < 2 {
+        t.Fatalf("requests = %d, want the message split", len(hook.bodies))
+    }
+    var first discordPayload
+    hook.decode(t, 0, &first)
+    if !strings.HasPrefix(first.Content, `**a\*b\_c**`+"\n<https://fishc.com.cn/t>") {
+        t.Errorf("content = %q", first.Content[:80])
+    }
+    for i := range hook.bodies {
+        var p discordPayload
+        hook.decode(t, i, &p)
+        if utf8.RuneCountInString(p.Content) > discordMessageLimit {
+            t.Errorf("part %d exceeds the limit", i)
+        }
+    }
+}
+
+func TestDiscordNotifierStripsTelegramMarkup(t *testing.T) {
+    tests := []struct {
+        name string
+        post Post
+        want string
+    }{
+        {"html", Post{Message: "<b>粗体</b> &lt;tag&gt;", Format: formatHTML}, `粗体 <tag\>`},
+        {"markdown", Post{Message: `1\+1\=2`, Format: formatMarkdown}, "1+1=2"},
+    }
+    for _, tt := range tests {
+        got := formatDiscordPost(tt.post)
+        if !strings.HasSuffix(got, tt.want) {
+            t.Errorf("%s: formatDiscordPost = %q, want suffix %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestDiscordRetryAfter(t *testing.T) {
+    hook, url := newFakeWebhook(t)
+    hook.status = func(n int) int {
+        if n == 1 {
+            return http.StatusInternalServerError
+        }
+        return http.StatusNoContent
+    }
+    if err := (&DiscordNotifier{WebhookURL: url}).Notify(context.Background(), Post{Title: "t"}); err != nil {
+        t.Fatal(err)
+    }
+    if len(hook.bodies) != 2 {
+        t.Errorf("requests = %d, want a retry after the server error", len(hook.bodies))
+    }
+
+    resp := &http.Response{Header: http.Header{"Retry-After": {"1.5"}}, Body: http.NoBody}
+    if got := discordRetryAfter(resp); got.Seconds() != 1.5 {
+        t.Errorf("discordRetryAfter = %v", got)
+    }
+}
+
+func TestDiscordNotifyBatch(t *testing.T) {
+    hook, url := newFakeWebhook(t)
+    if err := (&DiscordNotifier{WebhookURL: url}).NotifyBatch(context.Background(), []Post{{Title: "一"}, {Title: "二"}}); err != nil {
+        t.Fatal(err)
+    }
+    var p discordPayload
+    hook.decode(t, 0, &p)
+    if len(hook.bodies) != 1 || !strings.Contains(p.Content, batchSeparator) {
+        t.Errorf("requests = %d, content = %q", len(hook.bodies), p.Content)
+    }
+}
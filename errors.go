@@ -0,0 +1,52 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+)
+
+// 抓取、解析、通知三个阶段失败时返回的错误都包装了对应的哨兵错误，
+// 调用方用 errors.Is 判断出错阶段，用 errors.As 取得 *stageError 或底层原因
+var (
+    ErrFetch  = errors.New("fetch failed")
+    ErrParse  = errors.New("parse failed")
+    ErrNotify = errors.New("notify failed")
+)
+
+// stageError 记录出错的阶段、相关的地址和底层原因
+type stageError struct {
+    Stage error
+    URL   string
+    Err   error
+}
+
+func (e *stageError) Error() string {
+    return fmt.Sprintf("%v: %v", e.Stage, e.Err)
+}
+
+// Unwrap 同时返回阶段和原因，errors.Is 对两者都能匹配
+func (e *stageError) Unwrap() []error {
+    return []error{e.Stage, e.Err}
+}
+
+// wrapStage 把 err 包装为 stage 阶段的错误，err 为 nil 或已属于该阶段时原样返回
+func wrapStage(stage error, url string, err error) error {
+    if err == nil || errors.Is(err, stage) {
+        return err
+    }
+    return &stageError{Stage: stage, URL: url, Err: err}
+}
+
+// errorStage 返回错误所属的阶段名，用作监控指标的标签
+func errorStage(err error) string {
+    switch {
+    case errors.Is(err, ErrFetch):
+        return "fetch"
+    case errors.Is(err, ErrParse):
+        return "parse"
+    case errors.Is(err, ErrNotify):
+        return "notify"
+    default:
+        return "other"
+    }
+}
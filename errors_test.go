@@ -0,0 +1,49 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "testing"
+)
+
+func TestWrapStage(t *testing.T) {
+    cause := errors.New("connection reset")
+    err := wrapStage(ErrFetch, "https://a.example/", cause)
+    if !errors.Is(err, ErrFetch) || !errors.Is(err, cause) {
+        t.Fatalf("wrapped error %v does not match both the stage and the cause", err)
+    }
+    if err.Error() != "fetch failed: connection reset" {
+        t.Errorf("Error() = %q", err.Error())
+    }
+    var se *stageError
+    if !errors.As(err, &se) || se.URL != "https://a.example/" {
+        t.Errorf("errors.As = %+v", se)
+    }
+
+    if wrapStage(ErrFetch, "u", nil) != nil {
+        t.Error("nil error was wrapped")
+    }
+    if again := wrapStage(ErrFetch, "other", err); again != err {
+        t.Error("error already in the stage was wrapped twice")
+    }
+    if parse := wrapStage(ErrParse, "u", err); !errors.Is(parse, ErrParse) || !errors.Is(parse, ErrFetch) {
+        t.Error("wrapping in another stage lost a stage")
+    }
+}
+
+func TestErrorStage(t *testing.T) {
+    tests := []struct {
+        err  error
+        want string
+    }{
+        {wrapStage(ErrFetch, "u", errors.New("x")), "fetch"},
+        {wrapStage(ErrParse, "u", errors.New("x")), "parse"},
+        {fmt.Errorf("poll: %w", wrapStage(ErrNotify, "u", errors.New("x"))), "notify"},
+        {errors.New("x"), "other"},
+    }
+    for _, tt := range tests {
+        if got := errorStage(tt.err); got != tt.want {
+            t.Errorf("errorStage(%v) = %q, want %q", tt.err, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+    "time"
+)
+
+// execStderrLimit 命令失败时错误信息中保留的标准错误输出的最大字符数
+const execStderrLimit = 500
+
+// ExecNotifier 对每个帖子执行一次外部命令，用于对接短信网关、本地脚本等没有内置支持的渠道。
+// 命令通过 sh -c 执行，帖子以与 Webhook 相同的 JSON 写入标准输入，同时以 YUC_POST_* 环境变量提供
+type ExecNotifier struct {
+    Command string
+    Timeout time.Duration
+}
+
+// Notify 执行命令，退出码非 0 或超时视为发送失败并重试
+func (n *ExecNotifier) Notify(ctx context.Context, p Post) error {
+    payload, err := json.Marshal(newWebhookPayload(p))
+    if err != nil {
+        return fmt.Errorf("encode payload: %w", err)
+    }
+
+    return retryNotify(ctx, "Exec", func() (time.Duration, bool, error) {
+        runCtx, cancel := context.WithTimeout(ctx, n.Timeout)
+        defer cancel()
+
+        cmd := exec.CommandContext(runCtx, "sh", "-c", n.Command)
+        cmd.Stdin = bytes.NewReader(payload)
+        cmd.Env = append(os.Environ(),
+            "YUC_POST_URL="+p.URL,
+            "YUC_POST_TITLE="+p.Title,
+            "YUC_POST_MESSAGE="+plainMessage(p),
+            "YUC_POST_AUTHOR="+p.Author,
+            "YUC_POST_TIME="+p.Time,
+        )
+        var stderr bytes.Buffer
+        cmd.Stderr = &stderr
+        // sh 被杀死后其子进程可能仍占用标准错误，不再等待输出读完
+        cmd.WaitDelay = time.Second
+
+        if err := cmd.Run(); err != nil {
+            if runCtx.Err() == context.DeadlineExceeded {
+                err = fmt.Errorf("timed out after %s", n.Timeout)
+            }
+            if out := strings.TrimSpace(stderr.String()); out != "" {
+                return 0, true, fmt.Errorf("exec notifier command failed: %w: %s", err, truncateRunes(out, execStderrLimit))
+            }
+            return 0, true, fmt.Errorf("exec notifier command failed: %w", err)
+        }
+        return 0, false, nil
+    })
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestExecNotifierPassesPost(t *testing.T) {
+    dir := t.TempDir()
+    n := &ExecNotifier{
+        Command: `cat > "$OUT/stdin.json"; printf '%s|%s' "$YUC_POST_TITLE" "$YUC_POST_MESSAGE" > "$OUT/env.txt"`,
+        Timeout: 5 * time.Second,
+    }
+    t.Setenv("OUT", dir)
+    post := Post{URL: "https://fishc.com.cn/t", Title: "标题", Message: "<i>正文</i>", Format: formatHTML}
+    if err := n.Notify(context.Background(), post); err != nil {
+        t.Fatal(err)
+    }
+
+    data, err := os.ReadFile(filepath.Join(dir, "stdin.json"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    var payload webhookPayload
+    if err := json.Unmarshal(data, &payload); err != nil {
+        t.Fatal(err)
+    }
+    if payload.URL != post.URL || payload.Message != "正文" {
+        t.Errorf("stdin = %+v", payload)
+    }
+    env, err := os.ReadFile(filepath.Join(dir, "env.txt"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(env) != "标题|正文" {
+        t.Errorf("env = %q", env)
+    }
+}
+
+func TestExecNotifierFailures(t *testing.T) {
+    old := notifyMaxAttempts
+    notifyMaxAttempts = 1
+    t.Cleanup(func() { notifyMaxAttempts = old })
+    tests := []struct {
+        name    string
+        command string
+        timeout time.Duration
+        want    string
+    }{
+        {"exit status", "echo broken pipe >&2; exit 3", 5 * time.Second, "broken pipe"},
+        {"timeout", "exec sleep 5", 50 * time.Millisecond, "timed out"},
+    }
+    for _, tt := range tests {
+        err := (&ExecNotifier{Command: tt.command, Timeout: tt.timeout}).Notify(context.Background(), Post{})
+        if err == nil || !strings.Contains(err.Error(), tt.want) {
+            t.Errorf("%s: err = %v, want it to contain %q", tt.name, err, tt.want)
+        }
+    }
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+    "context"
+    "encoding/xml"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// feedNotifier 在环形缓冲区中保存最近发现的帖子，并以 RSS 2.0 格式提供给阅读器订阅
+type feedNotifier struct {
+    mu    sync.Mutex
+    title string
+    link  string
+    items []feedItem
+    // next 是下一个写入位置，count 是已保存的帖子数量
+    next  int
+    count int
+    now   func() time.Time
+}
+
+// feedItem 缓冲区中的一个帖子及其发现时间
+type feedItem struct {
+    post         Post
+    discoveredAt time.Time
+}
+
+// newFeedNotifier 创建最多保存 size 个帖子的订阅源，link 是频道链接
+func newFeedNotifier(title, link string, size int) *feedNotifier {
+    return &feedNotifier{title: title, link: link, items: make([]feedItem, size), now: time.Now}
+}
+
+// Notify 将帖子加入订阅源，缓冲区已满时覆盖最早的帖子
+func (f *feedNotifier) Notify(_ context.Context, p Post) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.items[f.next] = feedItem{post: p, discoveredAt: f.now()}
+    f.next = (f.next + 1) % len(f.items)
+    f.count = min(f.count+1, len(f.items))
+    return nil
+}
+
+// recent 按从新到旧的顺序返回缓冲区中的帖子
+func (f *feedNotifier) recent() []feedItem {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    items := make([]feedItem, 0, f.count)
+    for i := 1; i <= f.count; i++ {
+        items = append(items, f.items[(f.next-i+len(f.items))%len(f.items)])
+    }
+    return items
+}
+
+// rssFeed RSS 2.0 文档的根元素
+type rssFeed struct {
+    XMLName xml.Name   `xml:"rss"`
+    Version string     `xml:"version,attr"`
+    Channel rssChannel `xml:"channel"`
+}
+
+// rssChannel RSS 频道信息及其条目
+type rssChannel struct {
+    Title         string    `xml:"title"`
+    Link          string    `xml:"link"`
+    Description   string    `xml:"description"`
+    LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+    Items         []rssItem `xml:"item"`
+}
+
+// rssItem 对应一个帖子
+type rssItem struct {
+    Title       string  `xml:"title"`
+    Link        string  `xml:"link"`
+    Description string  `xml:"description"`
+    GUID        rssGUID `xml:"guid"`
+    PubDate     string  `xml:"pubDate"`
+}
+
+// rssGUID 条目的唯一标识，这里直接使用帖子链接
+type rssGUID struct {
+    IsPermaLink bool   `xml:"isPermaLink,attr"`
+    Value       string `xml:",chardata"`
+}
+
+// ServeHTTP 输出 RSS 2.0 文档，条目按发现时间从新到旧排列
+func (f *feedNotifier) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+    doc := rssFeed{
+        Version: "2.0",
+        Channel: rssChannel{
+            Title:       f.title,
+            Link:        f.link,
+            Description: "论坛新帖子",
+        },
+    }
+    for _, item := range f.recent() {
+        doc.Channel.Items = append(doc.Channel.Items, rssItem{
+            Title:       item.post.Title,
+            Link:        item.post.URL,
+            Description: plainMessage(item.post),
+            GUID:        rssGUID{IsPermaLink: true, Value: item.post.URL},
+            PubDate:     item.discoveredAt.Format(time.RFC1123Z),
+        })
+    }
+    if len(doc.Channel.Items) > 0 {
+        doc.Channel.LastBuildDate = doc.Channel.Items[0].PubDate
+    }
+
+    w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+    w.Write([]byte(xml.Header))
+    enc := xml.NewEncoder(w)
+    enc.Indent("", "  ")
+    if err := enc.Encode(doc); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+    "context"
+    "encoding/xml"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+// fetchFeed 请求订阅源并解析返回的 RSS 文档
+func fetchFeed(t *testing.T, f *feedNotifier) rssFeed {
+    t.Helper()
+    srv := httptest.NewServer(f)
+    defer srv.Close()
+    resp, err := http.Get(srv.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer resp.Body.Close()
+    if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/rss+xml") {
+        t.Errorf("Content-Type = %q", ct)
+    }
+    var doc rssFeed
+    if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+        t.Fatal(err)
+    }
+    return doc
+}
+
+func TestFeedNotifierServesRSS(t *testing.T) {
+    f := newFeedNotifier("鱼C论坛", "https://fishc.com.cn/", 10)
+    now := time.Date(2024, 5, 12, 10, 0, 0, 0, time.UTC)
+    f.now = func() time.Time { return now }
+    f.Notify(context.Background(), Post{URL: "https://fishc.com.cn/t1", Title: "第一", Message: "a < b"})
+    now = now.Add(time.Minute)
+    f.Notify(context.Background(), Post{URL: "https://fishc.com.cn/t2", Title: "第二", Message: "<b>粗体</b>", Format: formatHTML})
+
+    doc := fetchFeed(t, f)
+    if doc.Version != "2.0" || doc.Channel.Title != "鱼C论坛" || doc.Channel.Link != "https://fishc.com.cn/" {
+        t.Errorf("channel = %+v", doc.Channel)
+    }
+    items := doc.Channel.Items
+    if len(items) != 2 {
+        t.Fatalf("items = %d, want 2", len(items))
+    }
+    if items[0].Title != "第二" || items[1].Title != "第一" {
+        t.Errorf("items = %q, %q, want newest first", items[0].Title, items[1].Title)
+    }
+    if items[0].Description != "粗体" || items[1].Description != "a < b" {
+        t.Errorf("descriptions = %q, %q, want plain text", items[0].Description, items[1].Description)
+    }
+    if !items[0].GUID.IsPermaLink || items[0].GUID.Value != "https://fishc.com.cn/t2" {
+        t.Errorf("guid = %+v", items[0].GUID)
+    }
+    if want := now.Format(time.RFC1123Z); items[0].PubDate != want || doc.Channel.LastBuildDate != want {
+        t.Errorf("pubDate = %q, lastBuildDate = %q, want %q", items[0].PubDate, doc.Channel.LastBuildDate, want)
+    }
+}
+
+func TestFeedNotifierRingBuffer(t *testing.T) {
+    f := newFeedNotifier("t", "l", 3)
+    for _, title := range []string{"1", "2", "3", "4", "5"} {
+        f.Notify(context.Background(), Post{URL: "u" + title, Title: title})
+    }
+    var titles []string
+    for _, item := range f.recent() {
+        titles = append(titles, item.post.Title)
+    }
+    if got := strings.Join(titles, ","); got != "5,4,3" {
+        t.Errorf("recent = %q, want the newest 3", got)
+    }
+}
+
+func TestFeedNotifierEmpty(t *testing.T) {
+    doc := fetchFeed(t, newFeedNotifier("t", "l", 3))
+    if len(doc.Channel.Items) != 0 || doc.Channel.LastBuildDate != "" {
+        t.Errorf("empty feed = %+v", doc.Channel)
+    }
+}
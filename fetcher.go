@@ -0,0 +1,174 @@
+package main
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// Fetcher 获取页面 HTML，解析代码只依赖该接口，便于测试时替换为返回固定内容的实现，或换用其它传输方式
+type Fetcher interface {
+    Fetch(ctx context.Context, url string) (string, error)
+}
+
+// pageFetcher 是 Fetcher 的可选扩展，支持条件 GET 和 spec 指定的请求方法，并返回重定向后的最终地址
+type pageFetcher interface {
+    FetchPage(ctx context.Context, url string, cached page, spec requestSpec) (page, error)
+}
+
+// fetchPage 优先使用 pageFetcher 获取页面，不支持时退化为普通 Fetch，最终地址视为请求地址。
+// 普通 Fetch 只能发送 GET 请求，spec 不是零值时返回错误。返回的错误包装了 ErrFetch
+func fetchPage(ctx context.Context, f Fetcher, url string, cached page, spec requestSpec) (page, error) {
+    if pf, ok := f.(pageFetcher); ok {
+        p, err := pf.FetchPage(ctx, url, cached, spec)
+        return p, wrapStage(ErrFetch, url, err)
+    }
+    if spec != (requestSpec{}) {
+        return page{}, wrapStage(ErrFetch, url, fmt.Errorf("fetcher %T does not support %s requests", f, spec.Method))
+    }
+    content, err := f.Fetch(ctx, url)
+    if err != nil {
+        return page{}, wrapStage(ErrFetch, url, err)
+    }
+    return page{URL: url, Content: content}, nil
+}
+
+// requestSpec 请求页面时使用的方法和请求体，零值表示普通的 GET 请求。
+// 只用于列表页，部分论坛的搜索、筛选接口需要以 POST 提交表单
+type requestSpec struct {
+    Method      string
+    Body        string
+    ContentType string
+}
+
+// FastHTTPFetcher 使用 fasthttp 获取页面，遇到临时错误时最多尝试 Attempts 次
+type FastHTTPFetcher struct {
+    UserAgent string
+    Attempts  int
+}
+
+// Fetch 获取页面内容
+func (f FastHTTPFetcher) Fetch(ctx context.Context, url string) (string, error) {
+    p, err := f.FetchPage(ctx, url, page{}, requestSpec{})
+    return p.Content, err
+}
+
+// FetchPage 按 spec 获取页面，cached 中有 ETag/Last-Modified 时发送条件请求
+func (f FastHTTPFetcher) FetchPage(ctx context.Context, url string, cached page, spec requestSpec) (page, error) {
+    return fetchWithRetry(ctx, url, f.UserAgent, f.Attempts, cached, spec)
+}
+
+// CachingFetcher 把 Next 获取的页面按请求方法、URL 和请求体缓存到 Dir 目录，TTL 内再次请求时直接读取磁盘，
+// 用于调试选择器时避免反复请求论坛。缓存中保存最终地址和缓存验证信息，过期后仍可以条件请求
+type CachingFetcher struct {
+    Next Fetcher
+    Dir  string
+    TTL  time.Duration
+}
+
+// newCachingFetcher 创建缓存目录并返回包装 next 的 CachingFetcher
+func newCachingFetcher(next Fetcher, dir string, ttl time.Duration) (*CachingFetcher, error) {
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return nil, fmt.Errorf("create cache dir: %w", err)
+    }
+    return &CachingFetcher{Next: next, Dir: dir, TTL: ttl}, nil
+}
+
+// Fetch 返回页面内容
+func (f *CachingFetcher) Fetch(ctx context.Context, url string) (string, error) {
+    p, err := f.FetchPage(ctx, url, page{}, requestSpec{})
+    return p.Content, err
+}
+
+// FetchPage 返回未过期的缓存页面，调用方持有的正是这份内容时返回 NotModified；
+// 缓存过期或不存在时通过 Next 获取并写入缓存，服务器返回 304 时延长缓存的有效期。写入失败只记录日志
+func (f *CachingFetcher) FetchPage(ctx context.Context, url string, cached page, spec requestSpec) (page, error) {
+    path := f.path(url, spec)
+    if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < f.TTL {
+        if p, err := readCachedPage(path); err == nil {
+            slog.Debug("使用缓存的页面", "url", url, "path", path)
+            if sameValidators(p, cached) {
+                return page{URL: p.URL, ETag: p.ETag, LastModified: p.LastModified, NotModified: true}, nil
+            }
+            return p, nil
+        }
+    }
+
+    p, err := fetchPage(ctx, f.Next, url, cached, spec)
+    if err != nil {
+        return page{}, err
+    }
+    if p.NotModified {
+        // 缓存文件可能已被删除，此时调用方自己持有内容，不需要缓存
+        now := time.Now()
+        _ = os.Chtimes(path, now, now)
+        return p, nil
+    }
+    if err := writeCachedPage(path, p); err != nil {
+        slog.Warn("写入页面缓存失败", "url", url, "path", path, "err", err)
+    }
+    return p, nil
+}
+
+// sameValidators 判断缓存页面与调用方持有的页面是否带有相同的非空验证信息
+func sameValidators(p, cached page) bool {
+    if p.ETag == "" && p.LastModified == "" {
+        return false
+    }
+    return p.ETag == cached.ETag && p.LastModified == cached.LastModified
+}
+
+// path 返回请求对应的缓存文件路径，POST 请求的方法和请求体也参与计算，不同的搜索条件不会共用缓存
+func (f *CachingFetcher) path(url string, spec requestSpec) string {
+    key := url
+    if spec != (requestSpec{}) {
+        key = spec.Method + " " + url + "\n" + spec.ContentType + "\n" + spec.Body
+    }
+    sum := sha256.Sum256([]byte(key))
+    return filepath.Join(f.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// cachedPage 缓存文件的 JSON 结构
+type cachedPage struct {
+    URL          string `json:"url"`
+    Content      string `json:"content"`
+    ETag         string `json:"etag,omitempty"`
+    LastModified string `json:"last_modified,omitempty"`
+}
+
+// readCachedPage 读取缓存文件
+func readCachedPage(path string) (page, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return page{}, err
+    }
+    var c cachedPage
+    if err := json.Unmarshal(data, &c); err != nil {
+        return page{}, err
+    }
+    return page{URL: c.URL, Content: c.Content, ETag: c.ETag, LastModified: c.LastModified}, nil
+}
+
+// writeCachedPage 写入缓存文件
+func writeCachedPage(path string, p page) error {
+    data, err := json.Marshal(cachedPage{URL: p.URL, Content: p.Content, ETag: p.ETag, LastModified: p.LastModified})
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, data, 0o644)
+}
+
+// newFetcher 按配置创建获取页面使用的 Fetcher
+func newFetcher(cfg *Config) (Fetcher, error) {
+    var fetcher Fetcher = FastHTTPFetcher{UserAgent: cfg.UserAgent, Attempts: cfg.Retries}
+    if cfg.CacheDir != "" {
+        return newCachingFetcher(fetcher, cfg.CacheDir, cfg.CacheTTL)
+    }
+    return fetcher, nil
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+    "compress/gzip"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/valyala/fasthttp"
+    "golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// newTestServer 启动测试用的 HTTP 服务，测试结束时关闭
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+    t.Helper()
+    srv := httptest.NewServer(handler)
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+// withFastRetry 缩短测试中重试的等待时间
+func withFastRetry(t *testing.T) {
+    t.Helper()
+    old := retryBaseDelay
+    retryBaseDelay = time.Millisecond
+    t.Cleanup(func() { retryBaseDelay = old })
+}
+
+func TestFetchSendsUserAgentHeadersAndAuth(t *testing.T) {
+    var got *http.Request
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        got = r
+        fmt.Fprint(w, "ok")
+    })
+
+    oldHeaders, oldAuth := requestHeaders, fetchAuth
+    t.Cleanup(func() { requestHeaders, fetchAuth = oldHeaders, oldAuth })
+    requestHeaders = http.Header{"Referer": {"https://fishc.com.cn/"}}
+    fetchAuth = forumAuth{Cookie: "sid=abc; auth=xyz", Username: "user", Password: "pass"}
+
+    content, err := FastHTTPFetcher{UserAgent: "yuc-test", Attempts: 1}.Fetch(context.Background(), srv.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if content != "ok" {
+        t.Errorf("content = %q, want %q", content, "ok")
+    }
+    if ua := got.Header.Get("User-Agent"); ua != "yuc-test" {
+        t.Errorf("User-Agent = %q, want %q", ua, "yuc-test")
+    }
+    if ref := got.Header.Get("Referer"); ref != "https://fishc.com.cn/" {
+        t.Errorf("Referer = %q", ref)
+    }
+    if c, err := got.Cookie("auth"); err != nil || c.Value != "xyz" {
+        t.Errorf("cookie auth = %v, %v", c, err)
+    }
+    if user, pass, ok := got.BasicAuth(); !ok || user != "user" || pass != "pass" {
+        t.Errorf("basic auth = %q %q %v", user, pass, ok)
+    }
+}
+
+func TestFetchRetriesServerErrors(t *testing.T) {
+    withFastRetry(t)
+    var calls atomic.Int32
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        if calls.Add(1) < 3 {
+            w.WriteHeader(http.StatusBadGateway)
+            return
+        }
+        fmt.Fprint(w, "ok")
+    })
+
+    content, err := FastHTTPFetcher{Attempts: 3}.Fetch(context.Background(), srv.URL)
+    if err != nil || content != "ok" {
+        t.Fatalf("Fetch = %q, %v", content, err)
+    }
+    if n := calls.Load(); n != 3 {
+        t.Errorf("calls = %d, want 3", n)
+    }
+}
+
+func TestFetchDoesNotRetryClientErrors(t *testing.T) {
+    withFastRetry(t)
+    var calls atomic.Int32
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        calls.Add(1)
+        w.WriteHeader(http.StatusNotFound)
+    })
+
+    _, err := FastHTTPFetcher{Attempts: 3}.Fetch(context.Background(), srv.URL)
+    var se *statusError
+    if !errors.As(err, &se) || se.StatusCode != http.StatusNotFound {
+        t.Fatalf("err = %v, want 404 statusError", err)
+    }
+    if n := calls.Load(); n != 1 {
+        t.Errorf("calls = %d, want 1", n)
+    }
+}
+
+func TestFetchThrottledReportsRetryAfter(t *testing.T) {
+    withFastRetry(t)
+    var calls atomic.Int32
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        calls.Add(1)
+        w.Header().Set("Retry-After", "7")
+        w.WriteHeader(http.StatusTooManyRequests)
+    })
+
+    _, err := FastHTTPFetcher{Attempts: 3}.Fetch(context.Background(), srv.URL)
+    var se *statusError
+    if !errors.As(err, &se) || !se.throttled() {
+        t.Fatalf("err = %v, want throttled statusError", err)
+    }
+    if se.RetryAfter != 7*time.Second {
+        t.Errorf("RetryAfter = %v, want 7s", se.RetryAfter)
+    }
+    if n := calls.Load(); n != 1 {
+        t.Errorf("calls = %d, throttled responses must not be retried", n)
+    }
+}
+
+func TestFetchDecompressesGzip(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+            t.Errorf("Accept-Encoding = %q", r.Header.Get("Accept-Encoding"))
+        }
+        w.Header().Set("Content-Encoding", "gzip")
+        gz := gzip.NewWriter(w)
+        fmt.Fprint(gz, "<p>压缩的页面</p>")
+        gz.Close()
+    })
+
+    content, err := FastHTTPFetcher{Attempts: 1}.Fetch(context.Background(), srv.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if content != "<p>压缩的页面</p>" {
+        t.Errorf("content = %q", content)
+    }
+}
+
+func TestFetchDecodesGBK(t *testing.T) {
+    body, err := simplifiedchinese.GBK.NewEncoder().String("<p>鱼C论坛</p>")
+    if err != nil {
+        t.Fatal(err)
+    }
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "text/html; charset=gbk")
+        io.WriteString(w, body)
+    })
+
+    content, err := FastHTTPFetcher{Attempts: 1}.Fetch(context.Background(), srv.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if content != "<p>鱼C论坛</p>" {
+        t.Errorf("content = %q", content)
+    }
+}
+
+func TestFetchFollowsRedirects(t *testing.T) {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+        http.Redirect(w, r, "/new", http.StatusFound)
+    })
+    mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+        fmt.Fprint(w, "new")
+    })
+    mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+        http.Redirect(w, r, "/loop", http.StatusFound)
+    })
+    srv := newTestServer(t, mux.ServeHTTP)
+
+    p, err := FastHTTPFetcher{Attempts: 1}.FetchPage(context.Background(), srv.URL+"/old", page{}, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p.URL != srv.URL+"/new" || p.Content != "new" {
+        t.Errorf("page = %+v, want final URL /new", p)
+    }
+
+    _, err = FastHTTPFetcher{Attempts: 3}.Fetch(context.Background(), srv.URL+"/loop")
+    if !errors.Is(err, fasthttp.ErrTooManyRedirects) {
+        t.Errorf("err = %v, want ErrTooManyRedirects", err)
+    }
+}
+
+func TestFetchConditionalGet(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("If-None-Match") == `"v1"` {
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+        w.Header().Set("ETag", `"v1"`)
+        fmt.Fprint(w, "list")
+    })
+
+    f := FastHTTPFetcher{Attempts: 1}
+    first, err := f.FetchPage(context.Background(), srv.URL, page{}, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if first.ETag != `"v1"` || first.NotModified {
+        t.Fatalf("first = %+v", first)
+    }
+    second, err := f.FetchPage(context.Background(), srv.URL, first, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !second.NotModified || second.ETag != `"v1"` {
+        t.Errorf("second = %+v, want NotModified", second)
+    }
+}
+
+func TestFetchRejectsLargeBody(t *testing.T) {
+    old := httpClient.MaxResponseBodySize
+    httpClient.MaxResponseBodySize = 16
+    t.Cleanup(func() { httpClient.MaxResponseBodySize = old })
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        io.WriteString(w, strings.Repeat("x", 1024))
+    })
+
+    _, err := FastHTTPFetcher{Attempts: 3}.Fetch(context.Background(), srv.URL)
+    if !errors.Is(err, fasthttp.ErrBodyTooLarge) {
+        t.Errorf("err = %v, want ErrBodyTooLarge", err)
+    }
+}
+
+func TestFetchUsesRequestSpec(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Content-Type"), body)
+    })
+
+    spec := requestSpec{Method: http.MethodPost, Body: "q=go", ContentType: "application/x-www-form-urlencoded"}
+    p, err := FastHTTPFetcher{Attempts: 1}.FetchPage(context.Background(), srv.URL, page{}, spec)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := "POST application/x-www-form-urlencoded q=go"; p.Content != want {
+        t.Errorf("content = %q, want %q", p.Content, want)
+    }
+}
+
+func TestFetchCanceledContext(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        fmt.Fprint(w, "ok")
+    })
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := (FastHTTPFetcher{Attempts: 3}).Fetch(ctx, srv.URL); !errors.Is(err, context.Canceled) {
+        t.Errorf("err = %v, want context.Canceled", err)
+    }
+}
+
+func TestParseRetryAfter(t *testing.T) {
+    now := time.Date(2024, 5, 12, 10, 0, 0, 0, time.UTC)
+    tests := []struct {
+        value string
+        want  time.Duration
+    }{
+        {"120", 2 * time.Minute},
+        {" 5 ", 5 * time.Second},
+        {"-3", 0},
+        {"Sun, 12 May 2024 10:00:30 GMT", 30 * time.Second},
+        {"Sun, 12 May 2024 09:00:00 GMT", 0},
+        {"soon", 0},
+        {"", 0},
+    }
+    for _, tt := range tests {
+        if got := parseRetryAfter(tt.value, now); got != tt.want {
+            t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+        }
+    }
+}
+
+func TestIsRetryableFetchError(t *testing.T) {
+    tests := []struct {
+        name string
+        err  error
+        want bool
+    }{
+        {"network", errors.New("connection reset"), true},
+        {"server error", &statusError{StatusCode: 502}, true},
+        {"not found", &statusError{StatusCode: 404}, false},
+        {"too many requests", &statusError{StatusCode: 429}, false},
+        {"unavailable", &statusError{StatusCode: 503}, false},
+        {"robots", errRobotsDisallowed, false},
+        {"redirects", fasthttp.ErrTooManyRedirects, false},
+        {"body too large", fasthttp.ErrBodyTooLarge, false},
+    }
+    for _, tt := range tests {
+        if got := isRetryableFetchError(tt.err); got != tt.want {
+            t.Errorf("%s: isRetryableFetchError = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestDetectCharset(t *testing.T) {
+    tests := []struct {
+        name        string
+        body        string
+        contentType string
+        want        string
+    }{
+        {"header", "", "text/html; charset=GBK", "GBK"},
+        {"meta charset", `<meta charset="gb2312">`, "text/html", "gb2312"},
+        {"http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=big5">`, "", "big5"},
+        {"header wins", `<meta charset="gbk">`, "text/html; charset=utf-8", "utf-8"},
+        {"none", "<p>hi</p>", "text/html", ""},
+    }
+    for _, tt := range tests {
+        if got := detectCharset([]byte(tt.body), tt.contentType); got != tt.want {
+            t.Errorf("%s: detectCharset = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+// countingFetcher 记录请求次数并返回固定页面，用于检查缓存是否生效
+type countingFetcher struct {
+    calls   int
+    content string
+    etag    string
+    // notModified 为 true 时对带有相同 ETag 的请求返回 304
+    notModified bool
+}
+
+func (f *countingFetcher) Fetch(ctx context.Context, url string) (string, error) {
+    p, err := f.FetchPage(ctx, url, page{}, requestSpec{})
+    return p.Content, err
+}
+
+func (f *countingFetcher) FetchPage(ctx context.Context, url string, cached page, spec requestSpec) (page, error) {
+    f.calls++
+    if f.notModified && cached.ETag != "" && cached.ETag == f.etag {
+        return page{URL: url, ETag: f.etag, NotModified: true}, nil
+    }
+    return page{URL: url, Content: f.content + spec.Body, ETag: f.etag}, nil
+}
+
+func TestCachingFetcherServesFreshEntries(t *testing.T) {
+    next := &countingFetcher{content: "page", etag: `"v1"`}
+    f, err := newCachingFetcher(next, t.TempDir(), time.Hour)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ctx := context.Background()
+
+    first, err := f.FetchPage(ctx, "https://example.com/list", page{}, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    second, err := f.FetchPage(ctx, "https://example.com/list", page{}, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if next.calls != 1 {
+        t.Errorf("calls = %d, want 1", next.calls)
+    }
+    if second.Content != "page" || second.ETag != `"v1"` {
+        t.Errorf("cached page = %+v", second)
+    }
+
+    // 调用方已持有同一份内容时返回 NotModified
+    third, err := f.FetchPage(ctx, "https://example.com/list", first, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !third.NotModified {
+        t.Errorf("third = %+v, want NotModified", third)
+    }
+}
+
+func TestCachingFetcherKeysByRequestSpec(t *testing.T) {
+    next := &countingFetcher{content: "result:"}
+    f, err := newCachingFetcher(next, t.TempDir(), time.Hour)
+    if err != nil {
+        t.Fatal(err)
+    }
+    search := func(q string) string {
+        spec := requestSpec{Method: http.MethodPost, Body: "q=" + q}
+        p, err := f.FetchPage(context.Background(), "https://example.com/search", page{}, spec)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return p.Content
+    }
+
+    if got := search("go"); got != "result:q=go" {
+        t.Errorf("go = %q", got)
+    }
+    if got := search("rust"); got != "result:q=rust" {
+        t.Errorf("rust = %q, must not reuse the cache of another body", got)
+    }
+    search("go")
+    if next.calls != 2 {
+        t.Errorf("calls = %d, want 2", next.calls)
+    }
+}
+
+func TestCachingFetcherRevalidatesExpiredEntries(t *testing.T) {
+    next := &countingFetcher{content: "page", etag: `"v1"`, notModified: true}
+    f, err := newCachingFetcher(next, t.TempDir(), time.Nanosecond)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ctx := context.Background()
+    first, err := f.FetchPage(ctx, "https://example.com/list", page{}, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    time.Sleep(time.Millisecond)
+    second, err := f.FetchPage(ctx, "https://example.com/list", first, requestSpec{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if next.calls != 2 || !second.NotModified {
+        t.Errorf("calls = %d, second = %+v, want a conditional request answered with 304", next.calls, second)
+    }
+}
+
+func TestFetchPageWrapsErrFetch(t *testing.T) {
+    _, err := fetchPage(context.Background(), failingFetcher{}, "https://example.com/", page{}, requestSpec{})
+    if !errors.Is(err, ErrFetch) {
+        t.Errorf("err = %v, want ErrFetch", err)
+    }
+    if errorStage(err) != "fetch" {
+        t.Errorf("errorStage = %q, want fetch", errorStage(err))
+    }
+}
+
+func TestFetchPageRejectsRequestSpecForPlainFetcher(t *testing.T) {
+    f := newSiteFetcher()
+    f.set("https://example.com/search", "<html></html>")
+    _, err := fetchPage(context.Background(), f, "https://example.com/search", page{}, requestSpec{Method: http.MethodPost})
+    if !errors.Is(err, ErrFetch) {
+        t.Errorf("err = %v, want ErrFetch for a fetcher that can only send GET requests", err)
+    }
+}
+
+// failingFetcher 总是返回错误
+type failingFetcher struct{}
+
+func (failingFetcher) Fetch(ctx context.Context, url string) (string, error) {
+    return "", errors.New("boom")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// Filter 按关键词或正则表达式过滤帖子，创建时编译好全部规则，可被多个论坛监控共享
+type Filter struct {
+    Include []*regexp.Regexp
+    Exclude []*regexp.Regexp
+}
+
+// newFilter 编译 include 和 exclude 规则，caseSensitive 为 false 时忽略大小写，规则不合法时返回错误
+func newFilter(include, exclude []string, caseSensitive bool) (*Filter, error) {
+    in, err := compilePatterns(include, caseSensitive)
+    if err != nil {
+        return nil, err
+    }
+    ex, err := compilePatterns(exclude, caseSensitive)
+    if err != nil {
+        return nil, err
+    }
+    return &Filter{Include: in, Exclude: ex}, nil
+}
+
+// compilePatterns 将关键词或正则表达式编译为正则列表。普通关键词按字面匹配，
+// 只有以 re: 开头或写成 /…/ 的规则按正则表达式处理
+func compilePatterns(patterns []string, caseSensitive bool) ([]*regexp.Regexp, error) {
+    var compiled []*regexp.Regexp
+    for _, pattern := range patterns {
+        expr := patternExpr(pattern)
+        if !caseSensitive {
+            expr = "(?i)" + expr
+        }
+        re, err := regexp.Compile(expr)
+        if err != nil {
+            return nil, fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+        }
+        compiled = append(compiled, re)
+    }
+    return compiled, nil
+}
+
+// patternExpr 返回规则对应的正则表达式，普通关键词会转义其中的特殊字符
+func patternExpr(pattern string) string {
+    switch {
+    case strings.HasPrefix(pattern, "re:"):
+        return strings.TrimPrefix(pattern, "re:")
+    case len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/"):
+        return pattern[1 : len(pattern)-1]
+    }
+    return regexp.QuoteMeta(pattern)
+}
+
+// Matches 判断帖子是否需要通知：设置了 Include 时标题或内容须匹配其中之一，且不能匹配任何 Exclude。
+// f 为 nil 时不过滤
+func (f *Filter) Matches(p Post) bool {
+    if f == nil {
+        return true
+    }
+    if len(f.Include) > 0 && !matchAny(f.Include, p) {
+        return false
+    }
+    return !matchAny(f.Exclude, p)
+}
+
+// matchAny 判断帖子的标题或内容是否匹配任意一个正则
+func matchAny(patterns []*regexp.Regexp, p Post) bool {
+    for _, re := range patterns {
+        if re.MatchString(p.Title) || re.MatchString(p.Message) {
+            return true
+        }
+    }
+    return false
+}
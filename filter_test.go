@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestFilterMatches(t *testing.T) {
+    tests := []struct {
+        name          string
+        include       []string
+        exclude       []string
+        caseSensitive bool
+        post          Post
+        want          bool
+    }{
+        {"no rules", nil, nil, false, Post{Title: "任何帖子"}, true},
+        {"include title", []string{"Python"}, nil, false, Post{Title: "学习 python"}, true},
+        {"include message", []string{"爬虫"}, nil, false, Post{Title: "分享", Message: "一个爬虫项目"}, true},
+        {"include miss", []string{"Go", "Rust"}, nil, false, Post{Title: "Python"}, false},
+        {"exclude wins", []string{"Python"}, []string{"求助"}, false, Post{Title: "求助 Python"}, false},
+        {"case sensitive", []string{"Go"}, nil, true, Post{Title: "go"}, false},
+        {"keyword is literal", []string{"C++"}, nil, false, Post{Title: "C++ 入门"}, true},
+        {"literal dot", []string{"v1.0"}, nil, false, Post{Title: "v1x0"}, false},
+        {"literal brackets", []string{"[求助]"}, nil, false, Post{Title: "求"}, false},
+        {"re prefix", []string{`re:^\[求助\]`}, nil, false, Post{Title: "[求助] pip 报错"}, true},
+        {"re prefix anchored", []string{`re:^\[求助\]`}, nil, false, Post{Title: "关于 [求助]"}, false},
+        {"slashes", []string{`/py(thon)?3/`}, nil, false, Post{Title: "PY3 教程"}, true},
+        {"single slash is literal", []string{"/"}, nil, false, Post{Title: "a/b"}, true},
+    }
+    for _, tt := range tests {
+        f, err := newFilter(tt.include, tt.exclude, tt.caseSensitive)
+        if err != nil {
+            t.Fatalf("%s: %v", tt.name, err)
+        }
+        if got := f.Matches(tt.post); got != tt.want {
+            t.Errorf("%s: Matches(%+v) = %v, want %v", tt.name, tt.post, got, tt.want)
+        }
+    }
+}
+
+func TestNewFilterRejectsInvalidRegex(t *testing.T) {
+    for _, pattern := range []string{"re:(", "/[/"} {
+        if _, err := newFilter([]string{pattern}, nil, false); err == nil {
+            t.Errorf("newFilter(%q) succeeded", pattern)
+        }
+    }
+    if _, err := newFilter([]string{"("}, nil, false); err != nil {
+        t.Errorf("plain keyword ( must be accepted: %v", err)
+    }
+}
+
+func TestNilFilterMatchesEverything(t *testing.T) {
+    var f *Filter
+    if !f.Matches(Post{}) {
+        t.Error("nil filter rejected a post")
+    }
+}
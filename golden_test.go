@@ -0,0 +1,131 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// update 为 true 时用当前的解析结果覆盖期望文件，用于确认解析变化是有意为之：go test -run TestSelectorFixtures -update
+var update = flag.Bool("update", false, "用当前的解析结果重新生成 testdata 中的期望文件")
+
+// 样本页面的地址，相对链接按这些地址解析
+const (
+    fixtureForumURL = "https://fishc.com.cn/forum.php?mod=forumdisplay&fid=173&mobile=2"
+    fixturePostURL  = "https://fishc.com.cn/forum.php?mod=viewthread&tid=240001&mobile=2"
+)
+
+// 样本文件的后缀：列表页样本用 parseListPosts 解析，帖子页样本按每种正文格式用 parsePostContent 解析，
+// 期望结果保存在同名的 .json 文件中
+const (
+    fixtureListSuffix = ".list.html"
+    fixturePostSuffix = ".post.html"
+)
+
+// fixtureFetcher 不论请求哪个地址都返回同一个样本页面
+type fixtureFetcher struct {
+    content string
+}
+
+func (f fixtureFetcher) Fetch(ctx context.Context, url string) (string, error) {
+    return f.content, nil
+}
+
+// TestSelectorFixtures 用默认选择器解析 testdata 中的每个样本页面，把结果与期望的 .json 文件比较
+func TestSelectorFixtures(t *testing.T) {
+    fixtures, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(fixtures) == 0 {
+        t.Fatal("no fixtures found in testdata")
+    }
+
+    for _, fixture := range fixtures {
+        t.Run(filepath.Base(fixture), func(t *testing.T) {
+            got, err := parseFixture(fixture)
+            if err != nil {
+                t.Fatal(err)
+            }
+            golden := strings.TrimSuffix(fixture, ".html") + ".json"
+            if *update {
+                if err := os.WriteFile(golden, got, 0o644); err != nil {
+                    t.Fatal(err)
+                }
+                return
+            }
+            want, err := os.ReadFile(golden)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if line, wantLine, gotLine := firstDiff(want, got); line > 0 {
+                t.Errorf("line %d differs, rerun with -update if the change is intended\nwant: %s\ngot:  %s", line, wantLine, gotLine)
+            }
+        })
+    }
+}
+
+// parseFixture 按文件后缀解析样本页面，返回格式化后的 JSON
+func parseFixture(path string) ([]byte, error) {
+    content, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var result any
+    switch {
+    case strings.HasSuffix(path, fixtureListSuffix):
+        posts, _, err := parseListPosts(string(content), fixtureForumURL, defaultSelectors)
+        if err != nil {
+            return nil, err
+        }
+        result = posts
+    case strings.HasSuffix(path, fixturePostSuffix):
+        fetcher := fixtureFetcher{content: string(content)}
+        posts := make(map[string]Post)
+        for _, format := range []string{formatPlain, formatMarkdown, formatHTML} {
+            post, err := parsePostContent(context.Background(), fetcher, fixturePostURL, defaultSelectors, format)
+            if err != nil {
+                return nil, err
+            }
+            posts[format] = post
+        }
+        result = posts
+    default:
+        return nil, fmt.Errorf("unknown fixture type, name must end with %s or %s", fixtureListSuffix, fixturePostSuffix)
+    }
+
+    var b bytes.Buffer
+    enc := json.NewEncoder(&b)
+    enc.SetEscapeHTML(false)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(result); err != nil {
+        return nil, err
+    }
+    return b.Bytes(), nil
+}
+
+// firstDiff 返回两段文本第一处不同的行号（从 1 开始）和该行内容，完全相同时行号为 0
+func firstDiff(want, got []byte) (int, string, string) {
+    wantLines := strings.Split(strings.ReplaceAll(string(want), "\r\n", "\n"), "\n")
+    gotLines := strings.Split(string(got), "\n")
+    for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+        var w, g string
+        if i < len(wantLines) {
+            w = wantLines[i]
+        }
+        if i < len(gotLines) {
+            g = gotLines[i]
+        }
+        if w != g || i >= len(wantLines) || i >= len(gotLines) {
+            return i + 1, w, g
+        }
+    }
+    return 0, "", ""
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// healthTracker 记录每个论坛最近一次成功检查的时间，超过阈值未成功时判定为不健康。
+// 论坛因断路器冷却或限流推迟检查时，在推迟结束后再给出一个阈值的余量
+type healthTracker struct {
+    mu        sync.Mutex
+    threshold time.Duration
+    lastPoll  map[string]time.Time
+    // backoff 论坛推迟检查的结束时间
+    backoff map[string]time.Time
+    now     func() time.Time
+}
+
+// newHealthTracker 创建健康检查，启动时间视为各论坛的初始成功时间，给首次检查留出余量
+func newHealthTracker(forums []string, threshold time.Duration) *healthTracker {
+    h := &healthTracker{threshold: threshold, lastPoll: make(map[string]time.Time), backoff: make(map[string]time.Time), now: time.Now}
+    started := h.now()
+    for _, forum := range forums {
+        h.lastPoll[forum] = started
+    }
+    return h
+}
+
+// healthThreshold 返回判定不健康的阈值：所有配置方案中最长监控间隔的 3 倍
+func healthThreshold(cfg *Config, profiles []Profile) time.Duration {
+    interval := cfg.Interval
+    for _, p := range profiles {
+        interval = max(interval, p.Config.Interval)
+    }
+    return 3 * interval
+}
+
+// setThreshold 修改判定不健康的阈值，h 为 nil 时不做任何事
+func (h *healthTracker) setThreshold(threshold time.Duration) {
+    if h == nil {
+        return
+    }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.threshold = threshold
+}
+
+// MarkSuccess 记录论坛的一次成功检查，h 为 nil 时不做任何事
+func (h *healthTracker) MarkSuccess(forum string) {
+    if h == nil {
+        return
+    }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.lastPoll[forum] = h.now()
+    delete(h.backoff, forum)
+}
+
+// BackOff 记录论坛在 d 之后才会再次检查，期间不判定为不健康，h 为 nil 时不做任何事
+func (h *healthTracker) BackOff(forum string, d time.Duration) {
+    if h == nil || d <= 0 {
+        return
+    }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if until := h.now().Add(d); until.After(h.backoff[forum]) {
+        h.backoff[forum] = until
+    }
+}
+
+// track 开始跟踪运行中新增的论坛，当前时间视为初始成功时间，h 为 nil 时不做任何事
+func (h *healthTracker) track(forum string) {
+    if h == nil {
+        return
+    }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if _, ok := h.lastPoll[forum]; !ok {
+        h.lastPoll[forum] = h.now()
+    }
+}
+
+// forget 不再跟踪已停止监控的论坛，h 为 nil 时不做任何事
+func (h *healthTracker) forget(forum string) {
+    if h == nil {
+        return
+    }
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    delete(h.lastPoll, forum)
+    delete(h.backoff, forum)
+}
+
+// Check 检查所有论坛，返回第一个超过阈值未成功检查的论坛信息
+func (h *healthTracker) Check() error {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    now := h.now()
+    for forum, last := range h.lastPoll {
+        if now.Sub(h.backoff[forum]) <= h.threshold {
+            continue
+        }
+        if age := now.Sub(last); age > h.threshold {
+            return fmt.Errorf("%s: last successful poll %v ago", forum, age.Round(time.Second))
+        }
+    }
+    return nil
+}
+
+// ServeHTTP 健康时返回 200，否则返回 503
+func (h *healthTracker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    if err := h.Check(); err != nil {
+        w.WriteHeader(http.StatusServiceUnavailable)
+        fmt.Fprintln(w, err)
+        return
+    }
+    fmt.Fprintln(w, "ok")
+}
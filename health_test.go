@@ -0,0 +1,94 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+// newTestHealth 返回使用可控时钟的健康检查和推进时钟的函数
+func newTestHealth(forums []string, threshold time.Duration) (*healthTracker, func(time.Duration)) {
+    now := time.Date(2024, 5, 12, 12, 0, 0, 0, time.UTC)
+    h := newHealthTracker(nil, threshold)
+    h.now = func() time.Time { return now }
+    for _, forum := range forums {
+        h.track(forum)
+    }
+    return h, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestHealthTrackerThreshold(t *testing.T) {
+    h, advance := newTestHealth([]string{"a", "b"}, time.Minute)
+    if err := h.Check(); err != nil {
+        t.Fatalf("Check() at start = %v", err)
+    }
+    advance(50 * time.Second)
+    h.MarkSuccess("a")
+    advance(20 * time.Second)
+    err := h.Check()
+    if err == nil || !strings.HasPrefix(err.Error(), "b:") {
+        t.Fatalf("Check() = %v, want forum b reported", err)
+    }
+    h.forget("b")
+    if err := h.Check(); err != nil {
+        t.Errorf("Check() after forget = %v", err)
+    }
+}
+
+func TestHealthTrackerBackOff(t *testing.T) {
+    h, advance := newTestHealth([]string{"a"}, time.Minute)
+    h.BackOff("a", 10*time.Minute)
+    h.BackOff("a", time.Minute)
+    advance(10*time.Minute + 30*time.Second)
+    if err := h.Check(); err != nil {
+        t.Fatalf("Check() during backoff = %v, want healthy", err)
+    }
+    advance(time.Minute)
+    if err := h.Check(); err == nil {
+        t.Fatal("Check() = nil, want unhealthy once backoff and threshold have passed")
+    }
+
+    h.MarkSuccess("a")
+    h.BackOff("a", 0)
+    advance(2 * time.Minute)
+    if err := h.Check(); err == nil {
+        t.Error("Check() = nil, want success to clear the earlier backoff")
+    }
+}
+
+func TestHealthTrackerNil(t *testing.T) {
+    var h *healthTracker
+    h.MarkSuccess("a")
+    h.BackOff("a", time.Minute)
+    h.track("a")
+    h.forget("a")
+    h.setThreshold(time.Minute)
+}
+
+func TestHealthTrackerServeHTTP(t *testing.T) {
+    h, advance := newTestHealth([]string{"a"}, time.Minute)
+    rec := httptest.NewRecorder()
+    h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+    if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "ok" {
+        t.Errorf("healthy response = %d %q", rec.Code, rec.Body.String())
+    }
+
+    advance(2 * time.Minute)
+    rec = httptest.NewRecorder()
+    h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+    if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "last successful poll") {
+        t.Errorf("unhealthy response = %d %q", rec.Code, rec.Body.String())
+    }
+}
+
+func TestHealthThreshold(t *testing.T) {
+    cfg := defaultConfig()
+    cfg.Interval = time.Minute
+    long := defaultConfig()
+    long.Interval = 5 * time.Minute
+    if got := healthThreshold(cfg, []Profile{{Config: cfg}, {Config: long}}); got != 15*time.Minute {
+        t.Errorf("healthThreshold = %v, want 3 times the longest interval", got)
+    }
+}
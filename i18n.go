@@ -0,0 +1,68 @@
+package main
+
+// 支持的语言
+const (
+    langZH = "zh"
+    langEN = "en"
+)
+
+// messages 通知标签和主要日志的多语言文本，缺少的条目回退到中文
+var messages = map[string]map[string]string{
+    langZH: {
+        "label.title":           "标题",
+        "label.link":            "链接",
+        "label.author":          "作者",
+        "label.time":            "时间",
+        "label.content":         "帖子内容",
+        "content.missing":       "未找到内容",
+        "button.open":           "打开帖子",
+        "breaker.open":          "论坛连续 %d 次请求失败，暂停检查 %v",
+        "breaker.closed":        "论坛已恢复访问",
+        "breaker.closed.detail": "断路器已关闭，恢复正常检查",
+        "markup.broken":         "论坛页面连续 %d 轮没有找到帖子，选择器可能已失效",
+        "markup.broken.detail":  "请检查列表选择器 %q 是否仍匹配论坛页面",
+        "edit.title":            "帖子内容已更新: %s",
+        "log.started":           "启动",
+        "log.stopped":           "监控已停止",
+        "log.poll_failed":       "本轮检查失败",
+        "log.notify_sent":       "通知已发送",
+        "log.notify_failed":     "发送通知失败",
+    },
+    langEN: {
+        "label.title":           "Title",
+        "label.link":            "Link",
+        "label.author":          "Author",
+        "label.time":            "Time",
+        "label.content":         "Content",
+        "content.missing":       "No content found",
+        "button.open":           "Open",
+        "breaker.open":          "Forum failed %d times in a row, pausing checks for %v",
+        "breaker.closed":        "Forum is reachable again",
+        "breaker.closed.detail": "Circuit breaker closed, resuming normal checks",
+        "markup.broken":         "No posts found for %d cycles in a row, selectors may be broken",
+        "markup.broken.detail":  "Check whether the list selector %q still matches the forum page",
+        "edit.title":            "Post edited: %s",
+        "log.started":           "starting",
+        "log.stopped":           "monitoring stopped",
+        "log.poll_failed":       "poll failed",
+        "log.notify_sent":       "notification sent",
+        "log.notify_failed":     "failed to send notification",
+    },
+}
+
+// lang 当前使用的语言，由 -lang 设置
+var lang = langZH
+
+// msg 返回当前语言下 key 对应的文本
+func msg(key string) string {
+    if s, ok := messages[lang][key]; ok {
+        return s
+    }
+    return messages[langZH][key]
+}
+
+// validLang 判断语言是否受支持
+func validLang(l string) bool {
+    _, ok := messages[l]
+    return ok
+}
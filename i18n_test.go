@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestMessagesComplete(t *testing.T) {
+    for l, table := range messages {
+        for key := range messages[langZH] {
+            if table[key] == "" {
+                t.Errorf("language %s is missing %q", l, key)
+            }
+        }
+        for key := range table {
+            if _, ok := messages[langZH][key]; !ok {
+                t.Errorf("language %s has %q, which is not in the fallback language", l, key)
+            }
+        }
+    }
+}
+
+func TestMsg(t *testing.T) {
+    saved := lang
+    t.Cleanup(func() { lang = saved })
+
+    lang = langEN
+    if got := msg("label.title"); got != "Title" {
+        t.Errorf("msg(label.title) = %q, want English", got)
+    }
+    lang = "xx"
+    if got := msg("label.title"); got != "标题" {
+        t.Errorf("msg(label.title) = %q, want the Chinese fallback", got)
+    }
+    if got := msg("no.such.key"); got != "" {
+        t.Errorf("unknown key = %q", got)
+    }
+}
+
+func TestValidLang(t *testing.T) {
+    for l, want := range map[string]bool{langZH: true, langEN: true, "fr": false, "": false} {
+        if got := validLang(l); got != want {
+            t.Errorf("validLang(%q) = %v, want %v", l, got, want)
+        }
+    }
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+    "net/url"
+    "regexp"
+    "strconv"
+    "strings"
+    "unicode"
+
+    "github.com/PuerkitoBio/goquery"
+    "golang.org/x/net/html"
+)
+
+// 帖子内容的输出格式
+const (
+    formatPlain    = "plain"
+    formatMarkdown = "markdown"
+)
+
+// droppedTags 转换时连同内容一起丢弃的标签
+var droppedTags = map[string]bool{
+    "script": true, "style": true, "iframe": true, "object": true, "embed": true,
+    "form": true, "input": true, "button": true, "select": true, "textarea": true,
+    "noscript": true, "svg": true, "img": true,
+}
+
+// blockTags 转换后需要独占段落的标签
+var blockTags = map[string]bool{
+    "p": true, "div": true, "table": true, "tr": true, "section": true, "article": true,
+}
+
+// blankLinesRe 匹配三个及以上的连续换行
+var blankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+// htmlToMarkdown 将帖子正文转换为 Telegram MarkdownV2，保留段落、换行、加粗、斜体、链接、列表、引用和代码块，
+// 相对链接基于 base 解析。文本节点按 MarkdownV2 转义，生成的标记不转义，结果只能以 MarkdownV2 发送
+func htmlToMarkdown(sel *goquery.Selection, base *url.URL) string {
+    var b strings.Builder
+    for _, node := range sel.Nodes {
+        for child := node.FirstChild; child != nil; child = child.NextSibling {
+            writeMarkdown(&b, child, base)
+        }
+    }
+
+    // 去掉代码块以外每行首尾的空白并合并多余的空行
+    lines := strings.Split(b.String(), "\n")
+    inFence := false
+    for i, line := range lines {
+        if strings.HasPrefix(line, "```") {
+            inFence = !inFence
+            continue
+        }
+        if !inFence {
+            lines[i] = strings.TrimSpace(line)
+        }
+    }
+    text := blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+    return strings.TrimSpace(text)
+}
+
+// writeMarkdown 递归输出单个节点
+func writeMarkdown(b *strings.Builder, n *html.Node, base *url.URL) {
+    switch n.Type {
+    case html.TextNode:
+        b.WriteString(markdownV2Replacer.Replace(collapseSpaces(n.Data)))
+        return
+    case html.ElementNode:
+    default:
+        return
+    }
+
+    tag := n.Data
+    if droppedTags[tag] {
+        return
+    }
+    children := func() {
+        for child := n.FirstChild; child != nil; child = child.NextSibling {
+            writeMarkdown(b, child, base)
+        }
+    }
+
+    switch tag {
+    case "br":
+        b.WriteString("\n")
+    case "h1", "h2", "h3", "h4", "h5", "h6":
+        // MarkdownV2 没有标题，用独占一段的加粗代替
+        b.WriteString("\n\n*")
+        children()
+        b.WriteString("*\n\n")
+    case "strong", "b":
+        b.WriteString("*")
+        children()
+        b.WriteString("*")
+    case "em", "i":
+        b.WriteString("_")
+        children()
+        b.WriteString("_")
+    case "code":
+        b.WriteString("`" + markdownV2CodeReplacer.Replace(textContent(n)) + "`")
+    case "pre":
+        b.WriteString("\n\n```\n" + markdownV2CodeReplacer.Replace(strings.Trim(textContent(n), "\n")) + "\n```\n\n")
+    case "a":
+        text := strings.TrimSpace(collapseSpaces(textContent(n)))
+        href := attr(n, "href")
+        link := resolveMarkdownLink(base, href)
+        switch {
+        case link == "":
+            b.WriteString(markdownV2Replacer.Replace(text))
+        case text == "" || text == link:
+            b.WriteString(markdownV2Replacer.Replace(link))
+        default:
+            b.WriteString("[" + markdownV2Replacer.Replace(text) + "](" + markdownV2URLReplacer.Replace(link) + ")")
+        }
+    case "ul", "ol":
+        b.WriteString("\n")
+        index := 0
+        for child := n.FirstChild; child != nil; child = child.NextSibling {
+            if child.Type != html.ElementNode || child.Data != "li" {
+                continue
+            }
+            index++
+            if tag == "ol" {
+                b.WriteString("\n" + strconv.Itoa(index) + "\\. ")
+            } else {
+                b.WriteString("\n\\- ")
+            }
+            for c := child.FirstChild; c != nil; c = c.NextSibling {
+                writeMarkdown(b, c, base)
+            }
+        }
+        b.WriteString("\n\n")
+    case "blockquote":
+        var inner strings.Builder
+        for child := n.FirstChild; child != nil; child = child.NextSibling {
+            writeMarkdown(&inner, child, base)
+        }
+        b.WriteString("\n\n")
+        for _, line := range strings.Split(strings.TrimSpace(inner.String()), "\n") {
+            b.WriteString(">" + strings.TrimSpace(line) + "\n")
+        }
+        b.WriteString("\n")
+    default:
+        if blockTags[tag] {
+            b.WriteString("\n\n")
+            children()
+            b.WriteString("\n\n")
+            return
+        }
+        children()
+    }
+}
+
+// markdownV2CodeReplacer 转义 MarkdownV2 代码和代码块中的 ` 和 \
+var markdownV2CodeReplacer = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+// markdownV2EscapeRe 匹配 MarkdownV2 的转义序列
+var markdownV2EscapeRe = regexp.MustCompile(`\\([\\_*\[\]()~` + "`" + `>#+\-=|{}.!])`)
+
+// markdownV2Text 去掉 MarkdownV2 文本中的转义符，保留加粗、链接等标记，供不支持 MarkdownV2 的渠道使用
+func markdownV2Text(s string) string {
+    return markdownV2EscapeRe.ReplaceAllString(s, "$1")
+}
+
+// resolveMarkdownLink 解析链接地址，只保留 http 和 https 链接，其他协议（如 javascript:）丢弃
+func resolveMarkdownLink(base *url.URL, href string) string {
+    ref, err := url.Parse(strings.TrimSpace(href))
+    if err != nil || href == "" {
+        return ""
+    }
+    if base != nil {
+        ref = base.ResolveReference(ref)
+    }
+    if ref.Scheme != "http" && ref.Scheme != "https" {
+        return ""
+    }
+    return ref.String()
+}
+
+// textContent 返回节点内的全部文本，保留原有空白
+func textContent(n *html.Node) string {
+    var b strings.Builder
+    var walk func(*html.Node)
+    walk = func(n *html.Node) {
+        if n.Type == html.TextNode {
+            b.WriteString(n.Data)
+        }
+        if n.Type == html.ElementNode && n.Data == "br" {
+            b.WriteString("\n")
+        }
+        for child := n.FirstChild; child != nil; child = child.NextSibling {
+            walk(child)
+        }
+    }
+    walk(n)
+    return b.String()
+}
+
+// attr 返回节点的属性值
+func attr(n *html.Node, key string) string {
+    for _, a := range n.Attr {
+        if a.Key == key {
+            return a.Val
+        }
+    }
+    return ""
+}
+
+// collapseSpaces 将连续的空白字符（包括 HTML 源码中的换行和 &nbsp;）合并为一个空格
+func collapseSpaces(s string) string {
+    var b strings.Builder
+    space := false
+    for _, r := range s {
+        if unicode.IsSpace(r) {
+            if !space {
+                b.WriteByte(' ')
+            }
+            space = true
+            continue
+        }
+        space = false
+        b.WriteRune(r)
+    }
+    return b.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+    "net/url"
+    "strings"
+    "testing"
+
+    "github.com/PuerkitoBio/goquery"
+)
+
+// convertFragment 用 convert 转换 HTML 片段，相对链接基于鱼C论坛解析
+func convertFragment(t *testing.T, fragment string, convert func(*goquery.Selection, *url.URL) string) string {
+    t.Helper()
+    doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<div class="message">` + fragment + `</div>`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    base, _ := url.Parse("https://fishc.com.cn/forum.php")
+    return convert(doc.Find(".message"), base)
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+    tests := []struct {
+        name string
+        html string
+        want string
+    }{
+        {"escapes text", "1+1=2. (yes)", `1\+1\=2\. \(yes\)`},
+        {"bold and italic", "<b>粗</b> <em>斜</em>", "*粗* _斜_"},
+        {"heading", "<h2>标题</h2>正文", "*标题*\n\n正文"},
+        {"relative link", `<a href="thread-1.html">帖子_1</a>`, `[帖子\_1](https://fishc.com.cn/thread-1.html)`},
+        {"link text is url", `<a href="https://a.com/x">https://a.com/x</a>`, `https://a\.com/x`},
+        {"unsafe link", `<a href="javascript:alert(1)">点我</a>`, "点我"},
+        {"link url escapes", `<a href="https://a.com/(x)">x</a>`, `[x](https://a.com/(x\))`},
+        {"inline code", "<code>a_b`c\\</code>", "`a_b\\`c\\\\`"},
+        {"pre", "<pre>\nfor i in x:\n    print(i)\n</pre>", "```\nfor i in x:\n    print(i)\n```"},
+        {"unordered list", "<ul><li>一</li><li>二</li></ul>", "\\- 一\n\\- 二"},
+        {"ordered list", "<ol><li>一</li><li>二</li></ol>", "1\\. 一\n2\\. 二"},
+        {"blockquote", "<blockquote>引用<br>第二行</blockquote>", ">引用\n>第二行"},
+        {"paragraphs", "<p>一</p><p>二</p>", "一\n\n二"},
+        {"drops scripts and images", `<script>x()</script><img src="a.png">文本`, "文本"},
+    }
+    for _, tt := range tests {
+        if got := convertFragment(t, tt.html, htmlToMarkdown); got != tt.want {
+            t.Errorf("%s: htmlToMarkdown = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestMarkdownV2Text(t *testing.T) {
+    tests := []struct {
+        in   string
+        want string
+    }{
+        {`1\+1\=2\.`, "1+1=2."},
+        {`*粗体* \\ 反斜杠`, `*粗体* \ 反斜杠`},
+        {`\- 列表`, "- 列表"},
+        {"普通文本", "普通文本"},
+    }
+    for _, tt := range tests {
+        if got := markdownV2Text(tt.in); got != tt.want {
+            t.Errorf("markdownV2Text(%q) = %q, want %q", tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestResolveMarkdownLink(t *testing.T) {
+    base, _ := url.Parse("https://fishc.com.cn/forum/list.php")
+    tests := []struct {
+        href string
+        want string
+    }{
+        {"thread.html", "https://fishc.com.cn/forum/thread.html"},
+        {"/a?b=1", "https://fishc.com.cn/a?b=1"},
+        {"https://example.com/", "https://example.com/"},
+        {"javascript:alert(1)", ""},
+        {"mailto:a@b.c", ""},
+        {"", ""},
+    }
+    for _, tt := range tests {
+        if got := resolveMarkdownLink(base, tt.href); got != tt.want {
+            t.Errorf("resolveMarkdownLink(%q) = %q, want %q", tt.href, got, tt.want)
+        }
+    }
+}
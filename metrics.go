@@ -0,0 +1,68 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus 监控指标
+var (
+    fetchesTotal = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "yuc_fetches_total",
+        Help: "Total number of page fetches.",
+    })
+    fetchErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "yuc_fetch_errors_total",
+        Help: "Total number of failed page fetches.",
+    })
+    retriesDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "yuc_retries_dropped_total",
+        Help: "Total number of retries skipped because the retry budget was exhausted.",
+    })
+    notificationsSentTotal = promauto.NewCounter(prometheus.CounterOpts{
+        Name: "yuc_notifications_sent_total",
+        Help: "Total number of notifications sent successfully.",
+    })
+    lastSuccessfulPoll = promauto.NewGaugeVec(prometheus.GaugeOpts{
+        Name: "yuc_last_successful_poll_timestamp_seconds",
+        Help: "Unix timestamp of the last successful poll.",
+    }, []string{"forum"})
+    pollErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+        Name: "yuc_poll_errors_total",
+        Help: "Total number of failed poll cycles by failing stage (fetch, parse, notify).",
+    }, []string{"forum", "stage"})
+    pollDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+        Name:    "yuc_poll_duration_seconds",
+        Help:    "Duration of poll cycles.",
+        Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+    }, []string{"forum"})
+)
+
+// serveHTTP 在 addr 上启动 HTTP 服务，ctx 取消时关闭
+func serveHTTP(ctx context.Context, addr string, handler http.Handler) {
+    server := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+    go func() {
+        <-ctx.Done()
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+        defer cancel()
+        server.Shutdown(shutdownCtx)
+    }()
+    go func() {
+        slog.Info("HTTP 服务已启动", "addr", addr)
+        if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+            slog.Error("HTTP 服务异常退出", "addr", addr, "err", err)
+        }
+    }()
+}
+
+// metricsHandler 返回 Prometheus 指标的 HTTP 处理器
+func metricsHandler() http.Handler {
+    return promhttp.Handler()
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+    pollErrorsTotal.WithLabelValues("https://metrics.example/", "fetch").Inc()
+    lastSuccessfulPoll.WithLabelValues("https://metrics.example/").SetToCurrentTime()
+
+    rec := httptest.NewRecorder()
+    metricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("status = %d", rec.Code)
+    }
+    body := rec.Body.String()
+    for _, want := range []string{
+        `yuc_poll_errors_total{forum="https://metrics.example/",stage="fetch"} 1`,
+        `yuc_last_successful_poll_timestamp_seconds{forum="https://metrics.example/"}`,
+    } {
+        if !strings.Contains(body, want) {
+            t.Errorf("metrics output is missing %s", want)
+        }
+    }
+}
@@ -0,0 +1,699 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "math/rand"
+    "slices"
+    "sort"
+    "sync"
+    "time"
+)
+
+// monitorOptions 监控单个论坛所需的参数
+type monitorOptions struct {
+    URL       string
+    Fetcher   Fetcher
+    Interval  time.Duration
+    Jitter    time.Duration
+    Selectors Selectors
+    Format    string
+    MaxLen    int
+    Filter    *Filter
+    Dedup     string
+    // CanonicalStrip 按地址去重前从帖子地址中去掉的查询参数
+    CanonicalStrip []string
+    Batch          bool
+    // BatchSort 合并通知的排序方式，"replies" 或 "views" 按对应计数从多到少排列
+    BatchSort string
+    // MinReplies 列表页回复数低于该值的帖子暂不处理，0 表示不限制
+    MinReplies int
+    // SkipSticky 跳过列表页上的置顶帖
+    SkipSticky bool
+    // MinAge 和 MaxAge 限制通知的帖子发布时长，0 表示不限制；DropUnknownAge 为 true 时不通知无法解析发帖时间的帖子
+    MinAge         time.Duration
+    MaxAge         time.Duration
+    DropUnknownAge bool
+    SkipInitial    bool
+    // Since 不为零时首次运行向后翻页，通知该时间之后发布的全部帖子
+    Since time.Time
+    // CatchUpPages 启动时补发遗漏帖子最多向后翻的列表页数，1 表示只看第一页
+    CatchUpPages int
+    // BreakerThreshold 连续失败多少次后打开断路器，0 表示不启用
+    BreakerThreshold int
+    BreakerCooldown  time.Duration
+    // EmptyAlertCycles 列表页连续多少轮没有帖子时提醒选择器可能失效，0 表示不提醒
+    EmptyAlertCycles int
+    // Request 请求列表页使用的方法和请求体，零值为 GET
+    Request requestSpec
+    // Concurrency 每轮并发获取帖子内容的最大数量
+    Concurrency int
+    // CycleTimeout 每轮抓取列表页和帖子内容的总时长上限，0 表示不限制
+    CycleTimeout time.Duration
+    // MinSleep 两轮检查之间的最短等待时间
+    MinSleep time.Duration
+    // Store 记录已处理的帖子，为 nil 时只保存在内存中
+    Store    SeenStore
+    Notifier Notifier
+    // Operator 发送给运维人员的通知渠道，不经过静默时段、订阅源和死信文件，为 nil 时只记录日志
+    Operator Notifier
+    Health   *healthTracker
+    // Live 不为 nil 时每轮检查开始前用其中的设置覆盖间隔、过滤规则、帖子筛选条件和通知渠道
+    Live *liveSettings
+}
+
+// runMonitors 为每个论坛启动一个监控 goroutine，等待全部退出后返回
+func runMonitors(ctx context.Context, monitors []monitorOptions) {
+    var wg sync.WaitGroup
+    for _, opts := range monitors {
+        wg.Add(1)
+        go func(opts monitorOptions) {
+            defer wg.Done()
+            runMonitor(ctx, opts)
+        }(opts)
+    }
+    wg.Wait()
+}
+
+// runMonitor 监控单个论坛直到 ctx 被取消，单个论坛出现意外错误时不影响其他论坛
+func runMonitor(ctx context.Context, opts monitorOptions) {
+    defer func() {
+        if r := recover(); r != nil {
+            slog.Error("监控异常退出", "url", opts.URL, "panic", r)
+        }
+    }()
+    monitorForum(ctx, opts)
+}
+
+// forForum 返回以 o 为模板监控 forum 的参数
+func (o monitorOptions) forForum(forum ForumConfig, storeFor func(string) SeenStore) monitorOptions {
+    o.URL = forum.URL
+    o.Selectors = forum.Selectors
+    o.Store = storeFor(forum.URL)
+    return o
+}
+
+// runOnce 对每个论坛执行一次检查后返回，供 cron 等外部调度使用
+func runOnce(ctx context.Context, monitors []monitorOptions) error {
+    var errs []error
+    for _, opts := range monitors {
+        if err := newForumMonitor(opts).poll(ctx); err != nil {
+            pollErrorsTotal.WithLabelValues(opts.URL, errorStage(err)).Inc()
+            errs = append(errs, fmt.Errorf("%s: %w", opts.URL, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// monitorForum 持续监控论坛页面，直到 ctx 被取消
+func monitorForum(ctx context.Context, opts monitorOptions) {
+    m := newForumMonitor(opts)
+    for ctx.Err() == nil {
+        start := time.Now()
+        if err := m.poll(ctx); err != nil && ctx.Err() == nil {
+            pollErrorsTotal.WithLabelValues(opts.URL, errorStage(err)).Inc()
+            slog.Error(msg("log.poll_failed"), "url", opts.URL, "err", err)
+        }
+        elapsed := time.Since(start)
+        interval := jitteredInterval(m.opts.Interval, m.opts.Jitter, m.rng)
+        if elapsed > interval {
+            slog.Warn("本轮检查耗时超过监控间隔", "url", opts.URL, "elapsed", elapsed, "interval", interval)
+        }
+        delay := nextDelay(interval, elapsed, m.opts.MinSleep)
+        if m.backoff > delay {
+            delay = m.backoff
+        }
+        sleepContext(ctx, delay)
+    }
+}
+
+// nextDelay 计算本轮检查结束后到下一轮开始前的等待时间：从间隔中扣除本轮耗时，
+// 使检查按固定节奏进行而不因耗时累积漂移，但不少于 floor
+func nextDelay(interval, elapsed, floor time.Duration) time.Duration {
+    return max(interval-elapsed, floor)
+}
+
+// throttleMaxDelay 论坛限流时推迟下一轮检查的最长时间
+const throttleMaxDelay = time.Hour
+
+// throttleDelay 计算论坛第 n 次连续限流后下一轮检查前的等待时间：
+// 有 Retry-After 时按其等待，否则从 2 倍 interval 开始每次翻倍，都不超过 throttleMaxDelay
+func throttleDelay(interval, retryAfter time.Duration, n int) time.Duration {
+    if retryAfter > 0 {
+        return min(retryAfter, throttleMaxDelay)
+    }
+    delay := interval
+    for i := 0; i < n && delay < throttleMaxDelay; i++ {
+        delay *= 2
+    }
+    return min(delay, throttleMaxDelay)
+}
+
+// jitteredInterval 在 interval 上叠加 [-jitter, +jitter] 范围内的随机偏移，
+// Validate 保证 jitter 小于 interval，因此结果总是正数
+func jitteredInterval(interval, jitter time.Duration, rng *rand.Rand) time.Duration {
+    if jitter <= 0 {
+        return interval
+    }
+    return interval - jitter + time.Duration(rng.Int63n(int64(2*jitter)+1))
+}
+
+// forumMonitor 保存单个论坛在多轮检查之间的状态
+type forumMonitor struct {
+    opts       monitorOptions
+    seen       SeenStore
+    firstCycle bool
+    rng        *rand.Rand
+    // validators 保存列表页上次响应的 ETag/Last-Modified，用于条件 GET
+    validators page
+    // catchUp 为 true 时下一轮检查会向后翻页，补发停机期间被挤出第一页的帖子
+    catchUp bool
+    breaker *circuitBreaker
+    // emptyCycles 列表页连续没有解析出帖子的轮数
+    emptyCycles int
+    // listSelector 最近一次匹配到帖子的列表选择器，切换时记录日志
+    listSelector string
+    // throttled 论坛连续返回 429/503 的次数，backoff 为据此推迟下一轮检查的时间
+    throttled int
+    backoff   time.Duration
+}
+
+// newForumMonitor 创建论坛监控并加载已通知帖子的历史状态
+func newForumMonitor(opts monitorOptions) *forumMonitor {
+    seen := opts.Store
+    if seen == nil {
+        seen = newMemoryStore()
+    }
+    // 没有历史状态时才按首次运行处理，否则补发停机期间的所有新帖。
+    // 读取失败时按首次运行处理，宁可漏掉停机期间的帖子也不一次发出大量通知
+    n, err := seen.Len()
+    if err != nil {
+        slog.Error("读取去重记录失败", "url", opts.URL, "err", err)
+    }
+    return &forumMonitor{
+        opts:       opts,
+        seen:       seen,
+        firstCycle: n == 0,
+        catchUp:    n > 0 && opts.CatchUpPages > 1,
+        breaker:    newCircuitBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+        rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+    }
+}
+
+// poll 执行一轮检查：获取列表页、找出新帖并逐个通知
+func (m *forumMonitor) poll(ctx context.Context) error {
+    m.opts.Live.apply(&m.opts)
+    opts := m.opts
+    start := time.Now()
+    defer func() {
+        pollDuration.WithLabelValues(opts.URL).Observe(time.Since(start).Seconds())
+    }()
+    // 只有本轮被限流时才推迟下一轮检查，其他结果（包括其他错误和断路器跳过）都恢复正常间隔
+    throttled := false
+    defer func() {
+        if !throttled {
+            m.throttled, m.backoff = 0, 0
+        }
+    }()
+
+    // 断路器打开期间跳过请求，避免论坛长时间故障时持续请求和刷屏报错
+    if !m.breaker.Allow() {
+        slog.Debug("断路器打开，跳过本轮检查", "url", opts.URL)
+        return nil
+    }
+
+    // 抓取受 CycleTimeout 限制，超时前已获取内容的帖子仍用 ctx 正常通知
+    fetchCtx := ctx
+    if opts.CycleTimeout > 0 {
+        var cancel context.CancelFunc
+        fetchCtx, cancel = context.WithTimeout(ctx, opts.CycleTimeout)
+        defer cancel()
+    }
+
+    // 获取页面内容
+    fetched, err := fetchPage(fetchCtx, opts.Fetcher, opts.URL, m.validators, opts.Request)
+    if err != nil {
+        var se *statusError
+        if errors.As(err, &se) && se.throttled() {
+            throttled = true
+            m.throttled++
+            m.backoff = throttleDelay(opts.Interval, se.RetryAfter, m.throttled)
+            slog.Warn("论坛限流，推迟下一轮检查", "url", opts.URL, "status", se.StatusCode, "delay", m.backoff)
+            opts.Health.BackOff(opts.URL, m.backoff)
+        }
+        if ctx.Err() == nil && m.breaker.Failure() {
+            m.notifyOperator(ctx, Post{
+                URL:     opts.URL,
+                Title:   fmt.Sprintf(msg("breaker.open"), opts.BreakerThreshold, m.breaker.Cooldown()),
+                Message: err.Error(),
+            })
+        }
+        // 断路器打开期间不会检查，健康检查按冷却结束的时间计算
+        opts.Health.BackOff(opts.URL, m.breaker.Remaining())
+        return err
+    }
+    if m.breaker.Success() {
+        m.notifyOperator(ctx, Post{URL: opts.URL, Title: msg("breaker.closed"), Message: msg("breaker.closed.detail")})
+    }
+    if fetched.NotModified {
+        slog.Debug("论坛页面没有变化，跳过解析", "url", opts.URL)
+        m.markSuccess()
+        return nil
+    }
+    m.validators = page{ETag: fetched.ETag, LastModified: fetched.LastModified}
+
+    // 解析页面内容并获取所有列表项中的链接，相对链接按重定向后的最终地址解析
+    posts, selector, err := parseListPosts(fetched.Content, fetched.URL, opts.Selectors)
+    if err != nil {
+        return err
+    }
+    slog.Debug("解析论坛页面完成", "url", opts.URL, "posts", len(posts), "selector", selector)
+    if len(posts) > 0 && selector != m.listSelector {
+        slog.Info("使用列表选择器", "url", opts.URL, "selector", selector)
+        m.listSelector = selector
+    }
+    m.checkEmpty(ctx, len(posts))
+    posts = orderListPosts(posts, opts.SkipSticky, time.Now())
+    if m.catchUp {
+        posts = m.catchUpPosts(fetchCtx, fetched, posts)
+        m.catchUp = false
+    }
+    if m.firstCycle && !opts.Since.IsZero() {
+        posts = m.sincePosts(fetchCtx, fetched, posts)
+    }
+
+    // 帖子已按从新到旧排列，倒序整理以便从最早的新帖开始通知
+    type candidate struct {
+        item Post
+        // skip 为 true 时只记录为已读，不发送通知
+        skip bool
+    }
+    var candidates []candidate
+    var toFetch []Post
+    queued := make(map[string]bool)
+    now := time.Now()
+    for i := len(posts) - 1; i >= 0; i-- {
+        item := posts[i]
+        if opts.Dedup != dedupHash && m.seenURL(item.URL) {
+            continue
+        }
+
+        // 首次运行时只通知最新的一个帖子，其余仅记录为已读；设置 SkipInitial 时全部只记录为已读
+        skip := m.firstCycle && (i > 0 || opts.SkipInitial)
+        if m.firstCycle && !opts.Since.IsZero() {
+            // 设置 Since 时通知该时间之后的全部帖子，列表页上没有时间的帖子获取内容后再判断
+            posted, ok := parsePostTime(item.Time, now)
+            skip = ok && posted.Before(opts.Since)
+        }
+
+        // 回复数不足的帖子不记为已读，回复数涨上来后仍会通知；列表页没有回复数时不过滤
+        if !skip && item.Replies >= 0 && item.Replies < opts.MinReplies {
+            slog.Debug("帖子回复数不足，暂不通知", "post_url", item.URL, "replies", item.Replies)
+            continue
+        }
+        candidates = append(candidates, candidate{item: item, skip: skip})
+
+        // 按内容去重时必须先获取帖子内容才能判断是否通知过，否则只获取需要通知的帖子
+        if (opts.Dedup == dedupHash || !skip) && !queued[item.URL] {
+            queued[item.URL] = true
+            toFetch = append(toFetch, item)
+        }
+    }
+
+    contents := make(map[string]Post, len(toFetch))
+    for _, post := range m.fetchPosts(fetchCtx, toFetch) {
+        contents[post.URL] = post
+    }
+
+    failed, deferred := 0, 0
+    var notifyErr error
+    // pending 合并模式下等待发送的帖子，pendingKeys 为对应的去重键
+    var pending []Post
+    var pendingKeys []string
+    for _, c := range candidates {
+        post := contents[c.item.URL]
+        if post.Message == "" && !c.skip && fetchCtx.Err() != nil {
+            // 超时前没能获取内容，不记录为已读，下一轮重新获取
+            deferred++
+            continue
+        }
+        key := canonicalURL(c.item.URL, opts.CanonicalStrip)
+        if opts.Dedup == dedupHash {
+            if post.Message == "" {
+                // 获取失败时不记录，下一轮重新获取
+                continue
+            }
+            key = contentHash(post)
+        }
+        if m.isSeen(key) {
+            continue
+        }
+        wait, ageOK := false, true
+        if !c.skip {
+            wait, ageOK = m.checkAge(post, time.Now())
+        }
+        if wait {
+            // 不记录为已读，下一轮再检查
+            slog.Debug("帖子发布时间太短，暂不通知", "post_url", post.URL, "time", post.Time)
+            continue
+        }
+        if post.URL == "" {
+            // 只记录为已读的帖子没有获取内容
+            post = c.item
+        }
+        if !c.skip && m.firstCycle && !opts.Since.IsZero() {
+            if posted, ok := parsePostTime(post.Time, now); !ok || posted.Before(opts.Since) {
+                c.skip = true
+            }
+        }
+        if c.skip {
+            m.mark(key, post)
+            continue
+        }
+        if !ageOK {
+            slog.Info("帖子发布时间不在范围内，跳过通知", "post_url", post.URL, "time", post.Time)
+            m.mark(key, post)
+            continue
+        }
+
+        // 过滤规则匹配完整的正文，截断只影响通知中显示的内容
+        if !opts.Filter.Matches(post) {
+            slog.Info("帖子不符合过滤条件，跳过通知", "post_url", post.URL, "title", post.Title)
+            m.mark(key, post)
+            continue
+        }
+        // 需要通知的帖子在发送成功（包括免打扰时段内暂存）后才记录，发送失败时下一轮重试
+        post.Message = truncateMessage(post.Message, post.URL, opts.MaxLen)
+        if opts.Batch {
+            pending = append(pending, post)
+            pendingKeys = append(pendingKeys, key)
+        } else if err := opts.Notifier.Notify(ctx, post); errors.Is(err, errHeld) {
+            // 免打扰时段内已暂存，时段结束后发送时才计数
+            m.mark(key, post)
+        } else if err != nil {
+            slog.Error(msg("log.notify_failed"), "post_url", post.URL, "err", err)
+            failed++
+            notifyErr = err
+        } else {
+            m.mark(key, post)
+            notificationsSentTotal.Inc()
+            slog.Info(msg("log.notify_sent"), "post_url", post.URL, "title", post.Title)
+        }
+    }
+    m.firstCycle = false
+    if deferred > 0 {
+        slog.Warn("本轮检查超时，部分帖子留到下一轮处理", "url", opts.URL, "timeout", opts.CycleTimeout, "posts", deferred)
+    }
+
+    // 合并模式下本轮的新帖子在最后一起发送
+    if len(pending) > 0 {
+        batch := slices.Clone(pending)
+        sortByPopularity(batch, opts.BatchSort)
+        err := notifyBatch(ctx, opts.Notifier, batch)
+        if err == nil || errors.Is(err, errHeld) {
+            for i, p := range pending {
+                m.mark(pendingKeys[i], p)
+            }
+        }
+        if errors.Is(err, errHeld) {
+            // 免打扰时段内已暂存，时段结束后发送时才计数
+        } else if err != nil {
+            slog.Error("发送合并通知失败", "url", opts.URL, "posts", len(pending), "err", err)
+            failed += len(pending)
+            notifyErr = err
+        } else {
+            notificationsSentTotal.Add(float64(len(pending)))
+            slog.Info("合并通知已发送", "url", opts.URL, "posts", len(pending))
+        }
+    }
+    m.markSuccess()
+
+    if failed > 0 {
+        return wrapStage(ErrNotify, opts.URL, fmt.Errorf("%d notifications failed, last error: %w", failed, notifyErr))
+    }
+    return nil
+}
+
+// sortByPopularity 按 by 指定的计数从多到少稳定排序，by 为空时保持发帖顺序
+func sortByPopularity(posts []Post, by string) {
+    if by == "" {
+        return
+    }
+    count := func(p Post) int {
+        if by == "views" {
+            return p.Views
+        }
+        return p.Replies
+    }
+    sort.SliceStable(posts, func(i, j int) bool { return count(posts[i]) > count(posts[j]) })
+}
+
+// catchUpPosts 从第一页开始向后翻页，直到遇到已通知过的帖子或达到 CatchUpPages 页，
+// 返回按从新到旧排列的全部帖子。按内容去重时无法从列表判断是否通知过，会一直翻到页数上限
+func (m *forumMonitor) catchUpPosts(ctx context.Context, first page, posts []Post) []Post {
+    opts := m.opts
+    current, pagePosts := first, posts
+    for n := 2; n <= opts.CatchUpPages && m.firstSeen(pagePosts) < 0; n++ {
+        next := nextPageURL(current.Content, current.URL, n)
+        if next == "" {
+            break
+        }
+        fetched, err := fetchPage(ctx, opts.Fetcher, next, page{}, requestSpec{})
+        if err != nil {
+            slog.Warn("获取下一页失败，停止补发", "url", next, "err", err)
+            break
+        }
+        pagePosts, _, err = parseListPosts(fetched.Content, fetched.URL, opts.Selectors)
+        if err != nil || len(pagePosts) == 0 {
+            break
+        }
+        pagePosts = orderListPosts(pagePosts, opts.SkipSticky, time.Now())
+        // 比上次通知过的帖子更早的帖子不需要补发
+        if i := m.firstSeen(pagePosts); i >= 0 {
+            posts = append(posts, pagePosts[:i]...)
+            break
+        }
+        posts = append(posts, pagePosts...)
+        current = fetched
+    }
+    slog.Info("启动补发检查完成", "url", opts.URL, "posts", len(posts))
+    return posts
+}
+
+// orderListPosts 按需去掉置顶帖，再按列表页上的发帖时间从新到旧排序。
+// 有帖子的时间无法解析时无法可靠比较，保持页面顺序
+func orderListPosts(posts []Post, skipSticky bool, now time.Time) []Post {
+    if skipSticky {
+        kept := posts[:0:0]
+        for _, p := range posts {
+            if p.Sticky {
+                slog.Debug("跳过置顶帖", "post_url", p.URL)
+                continue
+            }
+            kept = append(kept, p)
+        }
+        posts = kept
+    }
+    times := make([]time.Time, len(posts))
+    for i, p := range posts {
+        t, ok := parsePostTime(p.Time, now)
+        if !ok {
+            return posts
+        }
+        times[i] = t
+    }
+    order := make([]int, len(posts))
+    for i := range order {
+        order[i] = i
+    }
+    sort.SliceStable(order, func(i, j int) bool { return times[order[i]].After(times[order[j]]) })
+    sorted := make([]Post, len(posts))
+    for i, k := range order {
+        sorted[i] = posts[k]
+    }
+    return sorted
+}
+
+// sinceMaxPages 设置 Since 时首次运行最多向后翻的列表页数
+const sinceMaxPages = 50
+
+// sincePosts 从第一页开始向后翻页，直到某页出现早于 Since 的帖子、没有下一页或达到 sinceMaxPages 页，
+// 返回按从新到旧排列的全部帖子。列表页上没有可解析的发帖时间时无法判断该翻到哪里，只使用第一页
+func (m *forumMonitor) sincePosts(ctx context.Context, first page, posts []Post) []Post {
+    opts := m.opts
+    current, pagePosts := first, posts
+    for n := 2; n <= sinceMaxPages && !reachedSince(pagePosts, opts.Since, time.Now()); n++ {
+        next := nextPageURL(current.Content, current.URL, n)
+        if next == "" {
+            break
+        }
+        fetched, err := fetchPage(ctx, opts.Fetcher, next, page{}, requestSpec{})
+        if err != nil {
+            slog.Warn("获取下一页失败，停止回溯", "url", next, "err", err)
+            break
+        }
+        pagePosts, _, err = parseListPosts(fetched.Content, fetched.URL, opts.Selectors)
+        if err != nil || len(pagePosts) == 0 {
+            break
+        }
+        pagePosts = orderListPosts(pagePosts, opts.SkipSticky, time.Now())
+        posts = append(posts, pagePosts...)
+        current = fetched
+    }
+    slog.Info("回溯检查完成", "url", opts.URL, "since", opts.Since, "posts", len(posts))
+    return posts
+}
+
+// reachedSince 判断列表中是否已有早于 since 的帖子，列表中的帖子都没有可解析的时间时也返回 true
+func reachedSince(posts []Post, since, now time.Time) bool {
+    dated := false
+    for _, p := range posts {
+        posted, ok := parsePostTime(p.Time, now)
+        if !ok {
+            continue
+        }
+        if posted.Before(since) {
+            return true
+        }
+        dated = true
+    }
+    return !dated
+}
+
+// -age-unknown 的取值
+const (
+    agePass = "pass"
+    ageDrop = "drop"
+)
+
+// checkAge 按 MinAge/MaxAge 检查帖子的发布时长：wait 为 true 表示发布不足 MinAge，应留到之后再处理；
+// ok 为 false 表示不应通知。未设置时长限制时总是通过
+func (m *forumMonitor) checkAge(p Post, now time.Time) (wait, ok bool) {
+    if m.opts.MinAge <= 0 && m.opts.MaxAge <= 0 {
+        return false, true
+    }
+    posted, parsed := parsePostTime(p.Time, now)
+    if !parsed {
+        return false, !m.opts.DropUnknownAge
+    }
+    age := now.Sub(posted)
+    if m.opts.MinAge > 0 && age < m.opts.MinAge {
+        return true, false
+    }
+    return false, m.opts.MaxAge <= 0 || age <= m.opts.MaxAge
+}
+
+// mark 记录已处理的帖子，保存失败只记录日志
+func (m *forumMonitor) mark(key string, p Post) {
+    if err := m.seen.Mark(key, p); err != nil {
+        slog.Error("保存去重记录失败", "post_url", p.URL, "err", err)
+    }
+}
+
+// isSeen 查询 key 是否已记录，查询失败时记录日志并按已记录处理，留到下一轮重试以免重复通知
+func (m *forumMonitor) isSeen(key string) bool {
+    seen, err := m.seen.Seen(key)
+    if err != nil {
+        slog.Error("查询去重记录失败", "url", m.opts.URL, "key", key, "err", err)
+        return true
+    }
+    return seen
+}
+
+// seenURL 按规范地址查询帖子是否已记录，同时兼容规范化之前按原始地址保存的记录
+func (m *forumMonitor) seenURL(u string) bool {
+    key := canonicalURL(u, m.opts.CanonicalStrip)
+    return m.isSeen(key) || (key != u && m.isSeen(u))
+}
+
+// firstSeen 返回列表中第一个已通知过的帖子的下标，没有时返回 -1
+func (m *forumMonitor) firstSeen(posts []Post) int {
+    for i, p := range posts {
+        if m.seenURL(p.URL) {
+            return i
+        }
+    }
+    return -1
+}
+
+// fetchPosts 用最多 Concurrency 个 goroutine 并发获取帖子内容，结果与 items 顺序一致。
+// 请求仍经过按站点的限速器，并发不会突破 -rate 的限制
+func (m *forumMonitor) fetchPosts(ctx context.Context, items []Post) []Post {
+    results := make([]Post, len(items))
+    workers := max(1, min(m.opts.Concurrency, len(items)))
+    jobs := make(chan int)
+
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range jobs {
+                // 本轮已超时的不再请求，结果留空
+                if ctx.Err() != nil {
+                    continue
+                }
+                results[i] = m.fetchPost(ctx, items[i])
+            }
+        }()
+    }
+    for i := range items {
+        jobs <- i
+    }
+    close(jobs)
+    wg.Wait()
+    return results
+}
+
+// fetchPost 获取帖子内容，帖子页没有标题时使用列表页上的标题
+func (m *forumMonitor) fetchPost(ctx context.Context, item Post) Post {
+    opts := m.opts
+    post, err := parsePostContent(ctx, opts.Fetcher, item.URL, opts.Selectors, opts.Format)
+    if err != nil {
+        slog.Error("获取帖子内容失败", "post_url", item.URL, "err", err)
+    }
+    if post.Title == "" {
+        post.Title = item.Title
+    }
+    post.Replies, post.Views = item.Replies, item.Views
+    if post.Time == "" {
+        post.Time = item.Time
+    }
+    return post
+}
+
+// notifyOperator 发送断路器状态变化、选择器可能失效等给运维人员的通知，失败时只记录日志
+func (m *forumMonitor) notifyOperator(ctx context.Context, p Post) {
+    slog.Warn(p.Title, "url", m.opts.URL)
+    if m.opts.Operator == nil {
+        return
+    }
+    if err := m.opts.Operator.Notify(ctx, p); err != nil {
+        slog.Error("发送运维通知失败", "url", m.opts.URL, "err", err)
+    }
+}
+
+// checkEmpty 统计列表页连续没有解析出帖子的轮数，达到 EmptyAlertCycles 时提醒一次选择器可能已失效，
+// 解析出帖子后重新计数
+func (m *forumMonitor) checkEmpty(ctx context.Context, found int) {
+    if found > 0 {
+        m.emptyCycles = 0
+        return
+    }
+    m.emptyCycles++
+    if m.emptyCycles == m.opts.EmptyAlertCycles {
+        m.notifyOperator(ctx, Post{
+            URL:     m.opts.URL,
+            Title:   fmt.Sprintf(msg("markup.broken"), m.emptyCycles),
+            Message: fmt.Sprintf(msg("markup.broken.detail"), m.opts.Selectors.List),
+        })
+    }
+}
+
+// markSuccess 记录本轮检查成功完成，更新指标和健康状态
+func (m *forumMonitor) markSuccess() {
+    lastSuccessfulPoll.WithLabelValues(m.opts.URL).SetToCurrentTime()
+    m.opts.Health.MarkSuccess(m.opts.URL)
+}
@@ -0,0 +1,857 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "net/http"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+const testForumURL = "https://forum.example/forum.php"
+
+// testSelectors 匹配 listItem 和 postPage 生成的页面
+var testSelectors = Selectors{List: "a.xst", Title: "h1", Message: ".message"}
+
+// siteFetcher 按地址返回预先设置的页面，没有设置的地址返回 404
+type siteFetcher struct {
+    mu    sync.Mutex
+    pages map[string]string
+    errs  map[string]error
+}
+
+func newSiteFetcher() *siteFetcher {
+    return &siteFetcher{pages: make(map[string]string), errs: make(map[string]error)}
+}
+
+func (f *siteFetcher) Fetch(_ context.Context, url string) (string, error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if err := f.errs[url]; err != nil {
+        return "", err
+    }
+    content, ok := f.pages[url]
+    if !ok {
+        return "", &statusError{URL: url, StatusCode: 404}
+    }
+    return content, nil
+}
+
+// set 设置地址对应的页面
+func (f *siteFetcher) set(url, content string) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.pages[url] = content
+}
+
+// fail 让地址返回 err，err 为 nil 时恢复正常
+func (f *siteFetcher) fail(url string, err error) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    f.errs[url] = err
+}
+
+// listItem 列表页上的一个帖子，replies 小于 0 时不输出回复数，sticky 为 true 时置顶
+type listItem struct {
+    id      string
+    time    string
+    replies int
+    sticky  bool
+}
+
+// listPage 按 Discuz 电脑版的表格布局生成列表页
+func listPage(items ...listItem) string {
+    var b strings.Builder
+    b.WriteString("<table>")
+    for _, it := range items {
+        if it.sticky {
+            b.WriteString(`<tbody id="stickthread_` + it.id + `">`)
+        } else {
+            b.WriteString("<tbody>")
+        }
+        fmt.Fprintf(&b, `<tr><th><a class="xst" href="thread-%s.html">%s</a></th>`, it.id, it.id)
+        if it.time != "" {
+            fmt.Fprintf(&b, `<td class="by"><em>%s</em></td>`, it.time)
+        }
+        if it.replies >= 0 {
+            fmt.Fprintf(&b, `<td class="num"><a>%d</a><em>0</em></td>`, it.replies)
+        }
+        b.WriteString("</tr></tbody>")
+    }
+    b.WriteString("</table>")
+    return b.String()
+}
+
+// postURL 返回 listPage 中帖子的地址
+func postURL(id string) string {
+    return "https://forum.example/thread-" + id + ".html"
+}
+
+// addPosts 为每个 id 设置帖子页，标题为 id，正文为 "body of id"
+func (f *siteFetcher) addPosts(ids ...string) {
+    for _, id := range ids {
+        f.set(postURL(id), postPage(id, "body of "+id))
+    }
+}
+
+func postPage(title, body string) string {
+    return `<h1>` + title + `</h1><div class="message">` + body + `</div>`
+}
+
+// items 返回没有时间和回复数的列表项
+func items(ids ...string) []listItem {
+    var list []listItem
+    for _, id := range ids {
+        list = append(list, listItem{id: id, replies: -1})
+    }
+    return list
+}
+
+// ago 返回 d 之前的论坛当地时间，格式与列表页相同
+func ago(d time.Duration) string {
+    return time.Now().In(forumLocation).Add(-d).Format("2006-1-2 15:04")
+}
+
+// newTestMonitor 返回使用 fetcher 和 notifier 监控测试论坛的参数
+func newTestMonitor(fetcher Fetcher, notifier Notifier) monitorOptions {
+    return monitorOptions{
+        URL:          testForumURL,
+        Fetcher:      fetcher,
+        Interval:     time.Minute,
+        Selectors:    testSelectors,
+        Format:       formatPlain,
+        Dedup:        dedupURL,
+        CatchUpPages: 1,
+        Concurrency:  2,
+        Store:        newMemoryStore(),
+        Notifier:     notifier,
+    }
+}
+
+// seeded 返回已有一条无关记录的存储，使监控不按首次运行处理
+func seeded(t *testing.T) SeenStore {
+    t.Helper()
+    store := newMemoryStore()
+    if err := store.Mark("seed", Post{}); err != nil {
+        t.Fatal(err)
+    }
+    return store
+}
+
+func pollOnce(t *testing.T, m *forumMonitor) {
+    t.Helper()
+    if err := m.poll(context.Background()); err != nil {
+        t.Fatalf("poll() = %v", err)
+    }
+}
+
+func TestMonitorFirstRunNotifiesNewestOnly(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("3", "2", "1")...))
+    fetcher.addPosts("1", "2", "3", "4", "5")
+    notifier := &recordingNotifier{}
+    m := newForumMonitor(newTestMonitor(fetcher, notifier))
+
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "3" {
+        t.Fatalf("first run notified %q, want only the newest post", got)
+    }
+    if notifier.posts[0].Message != "body of 3" {
+        t.Errorf("message = %q", notifier.posts[0].Message)
+    }
+
+    fetcher.set(testForumURL, listPage(items("5", "4", "3", "2", "1")...))
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "3,4,5" {
+        t.Errorf("notified %q, want new posts oldest first", got)
+    }
+
+    pollOnce(t, m)
+    if len(notifier.posts) != 3 {
+        t.Errorf("unchanged page notified again: %q", notifier.titles())
+    }
+}
+
+func TestRunOnceJoinsErrors(t *testing.T) {
+    good := newSiteFetcher()
+    good.set(testForumURL, listPage())
+    bad := newSiteFetcher()
+    okOpts := newTestMonitor(good, &recordingNotifier{})
+    badOpts := newTestMonitor(bad, &recordingNotifier{})
+    badOpts.URL = "https://other.example/"
+
+    err := runOnce(context.Background(), []monitorOptions{okOpts, badOpts})
+    if err == nil || !strings.Contains(err.Error(), "https://other.example/") || strings.Contains(err.Error(), testForumURL) {
+        t.Errorf("runOnce() = %v, want only the failing forum", err)
+    }
+}
+
+func TestJitteredInterval(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+    if got := jitteredInterval(time.Minute, 0, rng); got != time.Minute {
+        t.Errorf("no jitter = %v, want the interval", got)
+    }
+    for i := 0; i < 100; i++ {
+        got := jitteredInterval(time.Minute, 10*time.Second, rng)
+        if got < 50*time.Second || got > 70*time.Second {
+            t.Fatalf("jitteredInterval = %v, want within 60s ± 10s", got)
+        }
+    }
+}
+
+func TestMonitorSkipInitial(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("2", "1")...))
+    fetcher.addPosts("1", "2", "3")
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.SkipInitial = true
+    m := newForumMonitor(opts)
+
+    pollOnce(t, m)
+    if len(notifier.posts) != 0 {
+        t.Fatalf("skip initial notified %q", notifier.titles())
+    }
+    fetcher.set(testForumURL, listPage(items("3", "2", "1")...))
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "3" {
+        t.Errorf("notified %q, want 3", got)
+    }
+}
+
+func TestMonitorExistingStoreNotifiesAllUnseen(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("3", "2", "1")...))
+    fetcher.addPosts("1", "2", "3")
+    store := seeded(t)
+    if err := store.Mark(postURL("1"), Post{}); err != nil {
+        t.Fatal(err)
+    }
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = store
+    pollOnce(t, newForumMonitor(opts))
+
+    if got := strings.Join(notifier.titles(), ","); got != "2,3" {
+        t.Errorf("notified %q, want 2,3", got)
+    }
+}
+
+func TestMonitorCanonicalDedup(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, `<a class="xst" href="thread-1.html?from=home">1</a>`)
+    fetcher.set(postURL("1")+"?from=home", postPage("1", "body"))
+    store := seeded(t)
+    if err := store.Mark(postURL("1"), Post{}); err != nil {
+        t.Fatal(err)
+    }
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = store
+    opts.CanonicalStrip = []string{"from"}
+    pollOnce(t, newForumMonitor(opts))
+
+    if len(notifier.posts) != 0 {
+        t.Errorf("post with a tracking parameter was notified again: %q", notifier.titles())
+    }
+}
+
+func TestMonitorMinReplies(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(listItem{id: "1", replies: 1}, listItem{id: "2", replies: -1}))
+    fetcher.addPosts("1", "2")
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = seeded(t)
+    opts.MinReplies = 3
+    m := newForumMonitor(opts)
+
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "2" {
+        t.Fatalf("notified %q, want only the post without a reply count", got)
+    }
+
+    fetcher.set(testForumURL, listPage(listItem{id: "1", replies: 3}, listItem{id: "2", replies: -1}))
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "2,1" {
+        t.Errorf("notified %q, want post 1 once it reached enough replies", got)
+    }
+}
+
+func TestMonitorHashDedup(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("2", "1")...))
+    fetcher.set(postURL("1"), postPage("same", "same body"))
+    fetcher.set(postURL("2"), postPage("same", "same body"))
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = seeded(t)
+    opts.Dedup = dedupHash
+    m := newForumMonitor(opts)
+
+    pollOnce(t, m)
+    if len(notifier.posts) != 1 {
+        t.Fatalf("notified %d posts, want identical content only once", len(notifier.posts))
+    }
+
+    // 按内容去重时同一地址的内容变化后会再次通知
+    fetcher.set(postURL("2"), postPage("same", "edited body"))
+    pollOnce(t, m)
+    if len(notifier.posts) != 2 || notifier.posts[1].Message != "edited body" {
+        t.Errorf("posts = %+v, want the edited post notified", notifier.posts)
+    }
+}
+
+func TestMonitorFilterMarksSkippedPosts(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("2", "1")...))
+    fetcher.set(postURL("1"), postPage("Python 问题", "body"))
+    fetcher.set(postURL("2"), postPage("Java 问题", "body"))
+    filter, err := newFilter([]string{"python"}, nil, false)
+    if err != nil {
+        t.Fatal(err)
+    }
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = seeded(t)
+    opts.Filter = filter
+    m := newForumMonitor(opts)
+
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "Python 问题" {
+        t.Errorf("notified %q, want only the matching post", got)
+    }
+    if seen, _ := m.seen.Seen(postURL("2")); !seen {
+        t.Error("filtered post was not marked as seen")
+    }
+}
+
+func TestMonitorFilterMatchesBeforeTruncating(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("1")...))
+    fetcher.set(postURL("1"), postPage("问题", strings.Repeat("前言", 50)+"python"))
+    filter, err := newFilter([]string{"python"}, nil, false)
+    if err != nil {
+        t.Fatal(err)
+    }
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = seeded(t)
+    opts.Filter = filter
+    opts.MaxLen = 20
+    pollOnce(t, newForumMonitor(opts))
+
+    if len(notifier.posts) != 1 {
+        t.Fatalf("posts = %d, want the keyword past MaxLen to match", len(notifier.posts))
+    }
+    if strings.Contains(notifier.posts[0].Message, "python") {
+        t.Errorf("message = %q, want it truncated", notifier.posts[0].Message)
+    }
+}
+
+func TestMonitorSkipsSticky(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(listItem{id: "9", replies: -1, sticky: true}, listItem{id: "1", replies: -1}))
+    fetcher.addPosts("1", "9")
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = seeded(t)
+    opts.SkipSticky = true
+    pollOnce(t, newForumMonitor(opts))
+
+    if got := strings.Join(notifier.titles(), ","); got != "1" {
+        t.Errorf("notified %q, want sticky post skipped", got)
+    }
+}
+
+func TestMonitorAgeLimits(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(
+        listItem{id: "new", time: ago(time.Minute), replies: -1},
+        listItem{id: "ok", time: ago(2 * time.Hour), replies: -1},
+        listItem{id: "old", time: ago(48 * time.Hour), replies: -1},
+    ))
+    fetcher.addPosts("new", "ok", "old")
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = seeded(t)
+    opts.MinAge = time.Hour
+    opts.MaxAge = 24 * time.Hour
+    m := newForumMonitor(opts)
+
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "ok" {
+        t.Errorf("notified %q, want only the post within the age range", got)
+    }
+    if seen, _ := m.seen.Seen(postURL("new")); seen {
+        t.Error("post younger than min age was marked as seen")
+    }
+    if seen, _ := m.seen.Seen(postURL("old")); !seen {
+        t.Error("post older than max age was not marked as seen")
+    }
+}
+
+// blockingFetcher 请求 block 时一直等到 ctx 结束，其他地址交给 siteFetcher
+type blockingFetcher struct {
+    *siteFetcher
+    block string
+}
+
+func (f blockingFetcher) Fetch(ctx context.Context, url string) (string, error) {
+    if url == f.block {
+        <-ctx.Done()
+        return "", ctx.Err()
+    }
+    return f.siteFetcher.Fetch(ctx, url)
+}
+
+func TestMonitorCycleTimeout(t *testing.T) {
+    site := newSiteFetcher()
+    site.set(testForumURL, listPage(items("slow", "fast")...))
+    site.addPosts("slow", "fast")
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(blockingFetcher{siteFetcher: site, block: postURL("slow")}, notifier)
+    opts.Store = seeded(t)
+    opts.CycleTimeout = 50 * time.Millisecond
+    m := newForumMonitor(opts)
+
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "fast" {
+        t.Errorf("notified %q, want the post fetched before the timeout", got)
+    }
+    if seen, _ := m.seen.Seen(postURL("slow")); seen {
+        t.Fatal("post not fetched before the timeout was marked as seen")
+    }
+
+    m.opts.Fetcher = site
+    pollOnce(t, m)
+    if got := strings.Join(notifier.titles(), ","); got != "fast,slow" {
+        t.Errorf("notified %q, want the timed out post retried next cycle", got)
+    }
+}
+
+func TestCheckAge(t *testing.T) {
+    now := time.Date(2024, 5, 12, 12, 0, 0, 0, forumLocation)
+    tests := []struct {
+        name     string
+        min, max time.Duration
+        drop     bool
+        time     string
+        wait, ok bool
+    }{
+        {"no limits", 0, 0, false, "", false, true},
+        {"unknown passes", time.Hour, 0, false, "", false, true},
+        {"unknown dropped", time.Hour, 0, true, "", false, false},
+        {"too young", time.Hour, 0, false, "2024-5-12 11:30", true, false},
+        {"old enough", time.Hour, 0, false, "2024-5-12 10:00", false, true},
+        {"too old", 0, time.Hour, false, "2024-5-12 10:00", false, false},
+        {"within max", 0, time.Hour, false, "2024-5-12 11:30", false, true},
+    }
+    for _, tt := range tests {
+        m := &forumMonitor{opts: monitorOptions{MinAge: tt.min, MaxAge: tt.max, DropUnknownAge: tt.drop}}
+        wait, ok := m.checkAge(Post{Time: tt.time}, now)
+        if wait != tt.wait || ok != tt.ok {
+            t.Errorf("%s: checkAge = %v, %v, want %v, %v", tt.name, wait, ok, tt.wait, tt.ok)
+        }
+    }
+}
+
+func TestMonitorBatch(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/list" {
+            fmt.Fprint(w, `<a class="th_item" href="/t2">二</a><a class="th_item" href="/t1">一</a>`)
+            return
+        }
+        fmt.Fprintf(w, `<div class="message">%s</div>`, r.URL.Path)
+    })
+    notifier := &recordingBatchNotifier{}
+    m := newForumMonitor(monitorOptions{URL: srv.URL + "/list", Fetcher: FastHTTPFetcher{UserAgent: defaultUserAgent, Attempts: 1}, Selectors: defaultSelectors, Notifier: notifier, Batch: true})
+    m.firstCycle = false
+    if err := m.poll(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+
+    if len(notifier.batches) != 1 || len(notifier.posts) != 0 {
+        t.Fatalf("batches = %d, single posts = %d, want one batch", len(notifier.batches), len(notifier.posts))
+    }
+    var titles []string
+    for _, p := range notifier.batches[0] {
+        titles = append(titles, p.Title)
+    }
+    if got := strings.Join(titles, ","); got != "一,二" {
+        t.Errorf("batch = %q, want oldest first", got)
+    }
+}
+
+func TestMonitorCatchUp(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("6", "5")...))
+    fetcher.set(testForumURL+"?page=2", listPage(items("4", "3")...))
+    fetcher.set(testForumURL+"?page=3", listPage(items("2", "1")...))
+    fetcher.addPosts("1", "2", "3", "4", "5", "6")
+    store := newMemoryStore()
+    if err := store.Mark(postURL("3"), Post{}); err != nil {
+        t.Fatal(err)
+    }
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = store
+    opts.CatchUpPages = 5
+    pollOnce(t, newForumMonitor(opts))
+
+    if got := strings.Join(notifier.titles(), ","); got != "4,5,6" {
+        t.Errorf("notified %q, want posts newer than the last seen one across pages", got)
+    }
+}
+
+func TestMonitorBreakerAlertsOperator(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("1")...))
+    fetcher.addPosts("1")
+    fetcher.fail(testForumURL, errors.New("connection refused"))
+    notifier, operator := &recordingNotifier{}, &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = seeded(t)
+    opts.Operator = operator
+    opts.BreakerThreshold = 2
+    opts.BreakerCooldown = time.Millisecond
+    m := newForumMonitor(opts)
+
+    for i := 0; i < 2; i++ {
+        if err := m.poll(context.Background()); !errors.Is(err, ErrFetch) {
+            t.Fatalf("poll() = %v, want ErrFetch", err)
+        }
+    }
+    if len(operator.posts) != 1 || !strings.Contains(operator.posts[0].Message, "connection refused") {
+        t.Fatalf("operator posts = %+v, want one breaker alert", operator.posts)
+    }
+
+    time.Sleep(2 * time.Millisecond)
+    fetcher.fail(testForumURL, nil)
+    pollOnce(t, m)
+    if len(operator.posts) != 2 {
+        t.Errorf("operator posts = %d, want a recovery alert", len(operator.posts))
+    }
+    if got := strings.Join(notifier.titles(), ","); got != "1" {
+        t.Errorf("notifier got %q, want only the forum post and no alerts", got)
+    }
+}
+
+func TestSortByPopularity(t *testing.T) {
+    posts := []Post{{Title: "a", Replies: 1, Views: 30}, {Title: "b", Replies: 3, Views: 10}, {Title: "c", Replies: 1, Views: 20}}
+    tests := []struct {
+        by   string
+        want string
+    }{
+        {"", "a,b,c"},
+        {"replies", "b,a,c"},
+        {"views", "a,c,b"},
+    }
+    for _, tt := range tests {
+        sorted := append([]Post(nil), posts...)
+        sortByPopularity(sorted, tt.by)
+        var titles []string
+        for _, p := range sorted {
+            titles = append(titles, p.Title)
+        }
+        if got := strings.Join(titles, ","); got != tt.want {
+            t.Errorf("sortByPopularity(%q) = %q, want %q", tt.by, got, tt.want)
+        }
+    }
+}
+
+func TestMonitorBatchSorted(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(listItem{id: "3", replies: 1}, listItem{id: "2", replies: 9}, listItem{id: "1", replies: 5}))
+    fetcher.addPosts("1", "2", "3")
+    notifier := &recordingBatchNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Store = seeded(t)
+    opts.Batch = true
+    opts.BatchSort = "replies"
+    pollOnce(t, newForumMonitor(opts))
+
+    if len(notifier.batches) != 1 || len(notifier.posts) != 0 {
+        t.Fatalf("batches = %d, single posts = %d, want one batch", len(notifier.batches), len(notifier.posts))
+    }
+    var titles []string
+    for _, p := range notifier.batches[0] {
+        titles = append(titles, p.Title)
+    }
+    if got := strings.Join(titles, ","); got != "2,1,3" {
+        t.Errorf("batch = %q, want sorted by replies", got)
+    }
+}
+
+func TestMonitorNotifyFailure(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("1")...))
+    fetcher.addPosts("1")
+    opts := newTestMonitor(fetcher, &recordingNotifier{err: errors.New("down")})
+    opts.Store = seeded(t)
+    err := newForumMonitor(opts).poll(context.Background())
+    if !errors.Is(err, ErrNotify) || !strings.Contains(err.Error(), "1 notifications failed") {
+        t.Errorf("poll() = %v, want a wrapped ErrNotify", err)
+    }
+}
+
+func TestMonitorRetriesFailedNotification(t *testing.T) {
+    for _, batch := range []bool{false, true} {
+        fetcher := newSiteFetcher()
+        fetcher.set(testForumURL, listPage(items("1")...))
+        fetcher.addPosts("1")
+        notifier := &recordingBatchNotifier{}
+        notifier.err = errors.New("down")
+        opts := newTestMonitor(fetcher, notifier)
+        opts.Store = seeded(t)
+        opts.Batch = batch
+        m := newForumMonitor(opts)
+
+        if err := m.poll(context.Background()); !errors.Is(err, ErrNotify) {
+            t.Fatalf("batch %v: poll() = %v, want ErrNotify", batch, err)
+        }
+        if seen, _ := m.seen.Seen(postURL("1")); seen {
+            t.Errorf("batch %v: post was marked as seen although the notification failed", batch)
+        }
+        notifier.err = nil
+        pollOnce(t, m)
+        pollOnce(t, m)
+        if attempts := len(notifier.posts) + len(notifier.batches); attempts != 2 {
+            t.Errorf("batch %v: attempts = %d, want one failure and one successful retry", batch, attempts)
+        }
+    }
+}
+
+func TestMonitorHeldIsNotFailure(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("1")...))
+    fetcher.addPosts("1")
+    opts := newTestMonitor(fetcher, &recordingNotifier{err: errHeld})
+    opts.Store = seeded(t)
+    m := newForumMonitor(opts)
+    pollOnce(t, m)
+    if seen, _ := m.seen.Seen(postURL("1")); !seen {
+        t.Error("held post was not marked as seen")
+    }
+}
+
+func TestMonitorSince(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(
+        listItem{id: "4", time: ago(time.Hour), replies: -1},
+        listItem{id: "3", time: ago(2 * time.Hour), replies: -1},
+    ))
+    fetcher.set(testForumURL+"?page=2", listPage(
+        listItem{id: "2", time: ago(3 * time.Hour), replies: -1},
+        listItem{id: "1", time: ago(48 * time.Hour), replies: -1},
+    ))
+    fetcher.addPosts("1", "2", "3", "4")
+    notifier := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, notifier)
+    opts.Since = time.Now().Add(-24 * time.Hour)
+    m := newForumMonitor(opts)
+    pollOnce(t, m)
+
+    if got := strings.Join(notifier.titles(), ","); got != "2,3,4" {
+        t.Errorf("notified %q, want every post since the given time", got)
+    }
+    if seen, _ := m.seen.Seen(postURL("1")); !seen {
+        t.Error("post before since was not marked as seen")
+    }
+}
+
+func TestMonitorThrottleBacksOff(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.fail(testForumURL, &statusError{URL: testForumURL, StatusCode: 429, RetryAfter: 90 * time.Second})
+    health := newHealthTracker([]string{testForumURL}, time.Minute)
+    opts := newTestMonitor(fetcher, &recordingNotifier{})
+    opts.Health = health
+    m := newForumMonitor(opts)
+
+    if err := m.poll(context.Background()); err == nil {
+        t.Fatal("poll() = nil, want the throttling error")
+    }
+    if m.backoff != 90*time.Second {
+        t.Errorf("backoff = %v, want Retry-After", m.backoff)
+    }
+    health.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+    if err := health.Check(); err != nil {
+        t.Errorf("Check() = %v, want healthy while backing off", err)
+    }
+
+    fetcher.fail(testForumURL, nil)
+    fetcher.set(testForumURL, listPage())
+    pollOnce(t, m)
+    if m.backoff != 0 || m.throttled != 0 {
+        t.Errorf("backoff = %v, throttled = %d after success, want reset", m.backoff, m.throttled)
+    }
+}
+
+func TestMonitorBackoffResetsOnOtherErrors(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.fail(testForumURL, &statusError{URL: testForumURL, StatusCode: 503, RetryAfter: time.Hour})
+    m := newForumMonitor(newTestMonitor(fetcher, &recordingNotifier{}))
+    m.poll(context.Background())
+    if m.backoff != time.Hour {
+        t.Fatalf("backoff = %v, want Retry-After", m.backoff)
+    }
+
+    // 限流结束后论坛返回其他错误时按正常间隔重试
+    fetcher.fail(testForumURL, errors.New("connection refused"))
+    if err := m.poll(context.Background()); err == nil {
+        t.Fatal("poll() = nil, want the fetch error")
+    }
+    if m.backoff != 0 || m.throttled != 0 {
+        t.Errorf("backoff = %v, throttled = %d after a non-throttle error, want reset", m.backoff, m.throttled)
+    }
+}
+
+func TestMonitorEmptyAlert(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, "<p>layout changed</p>")
+    operator := &recordingNotifier{}
+    opts := newTestMonitor(fetcher, &recordingNotifier{})
+    opts.Operator = operator
+    opts.EmptyAlertCycles = 2
+    m := newForumMonitor(opts)
+
+    for i := 0; i < 3; i++ {
+        pollOnce(t, m)
+    }
+    if len(operator.posts) != 1 || !strings.Contains(operator.posts[0].Message, testSelectors.List) {
+        t.Fatalf("operator posts = %+v, want one alert naming the list selector", operator.posts)
+    }
+
+    fetcher.set(testForumURL, listPage(items("1")...))
+    fetcher.addPosts("1")
+    pollOnce(t, m)
+    fetcher.set(testForumURL, "<p>layout changed</p>")
+    pollOnce(t, m)
+    pollOnce(t, m)
+    if len(operator.posts) != 2 {
+        t.Errorf("operator posts = %d, want the count to restart after posts reappear", len(operator.posts))
+    }
+}
+
+func TestOrderListPosts(t *testing.T) {
+    now := time.Date(2024, 5, 12, 12, 0, 0, 0, forumLocation)
+    posts := []Post{
+        {URL: "a", Time: "2024-5-10 10:00"},
+        {URL: "b", Time: "2024-5-12 10:00", Sticky: true},
+        {URL: "c", Time: "2024-5-11 10:00"},
+    }
+    tests := []struct {
+        name       string
+        posts      []Post
+        skipSticky bool
+        want       string
+    }{
+        {"sorted by time", posts, false, "b,c,a"},
+        {"sticky skipped", posts, true, "c,a"},
+        {"unknown time keeps order", append([]Post{{URL: "x"}}, posts...), false, "x,a,b,c"},
+    }
+    for _, tt := range tests {
+        var urls []string
+        for _, p := range orderListPosts(tt.posts, tt.skipSticky, now) {
+            urls = append(urls, p.URL)
+        }
+        if got := strings.Join(urls, ","); got != tt.want {
+            t.Errorf("%s: order = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestReachedSince(t *testing.T) {
+    now := time.Date(2024, 5, 12, 12, 0, 0, 0, forumLocation)
+    since := time.Date(2024, 5, 11, 0, 0, 0, 0, forumLocation)
+    tests := []struct {
+        name  string
+        times []string
+        want  bool
+    }{
+        {"all newer", []string{"2024-5-12 10:00", "2024-5-11 10:00"}, false},
+        {"one older", []string{"2024-5-12 10:00", "2024-5-10 10:00"}, true},
+        {"no dates", []string{"", ""}, true},
+        {"empty", nil, true},
+    }
+    for _, tt := range tests {
+        var posts []Post
+        for _, tm := range tt.times {
+            posts = append(posts, Post{Time: tm})
+        }
+        if got := reachedSince(posts, since, now); got != tt.want {
+            t.Errorf("%s: reachedSince = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestNextDelay(t *testing.T) {
+    tests := []struct {
+        interval, elapsed, floor, want time.Duration
+    }{
+        {time.Minute, 10 * time.Second, time.Second, 50 * time.Second},
+        {time.Minute, 2 * time.Minute, time.Second, time.Second},
+        {time.Minute, 59900 * time.Millisecond, time.Second, time.Second},
+        {time.Minute, 0, 0, time.Minute},
+    }
+    for _, tt := range tests {
+        if got := nextDelay(tt.interval, tt.elapsed, tt.floor); got != tt.want {
+            t.Errorf("nextDelay(%v, %v, %v) = %v, want %v", tt.interval, tt.elapsed, tt.floor, got, tt.want)
+        }
+    }
+}
+
+func TestThrottleDelay(t *testing.T) {
+    tests := []struct {
+        interval, retryAfter time.Duration
+        n                    int
+        want                 time.Duration
+    }{
+        {time.Minute, 0, 1, 2 * time.Minute},
+        {time.Minute, 0, 3, 8 * time.Minute},
+        {time.Minute, 0, 20, throttleMaxDelay},
+        {time.Minute, 5 * time.Second, 3, 5 * time.Second},
+        {time.Minute, 2 * time.Hour, 1, throttleMaxDelay},
+    }
+    for _, tt := range tests {
+        if got := throttleDelay(tt.interval, tt.retryAfter, tt.n); got != tt.want {
+            t.Errorf("throttleDelay(%v, %v, %d) = %v, want %v", tt.interval, tt.retryAfter, tt.n, got, tt.want)
+        }
+    }
+}
+
+func TestFetchPostsBoundedConcurrency(t *testing.T) {
+    var inFlight, peak atomic.Int32
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        n := inFlight.Add(1)
+        defer inFlight.Add(-1)
+        for {
+            p := peak.Load()
+            if n <= p || peak.CompareAndSwap(p, n) {
+                break
+            }
+        }
+        time.Sleep(20 * time.Millisecond)
+        fmt.Fprintf(w, `<div class="message">%s</div>`, r.URL.Path)
+    })
+    var items []Post
+    for i := 0; i < 6; i++ {
+        items = append(items, Post{URL: fmt.Sprintf("%s/t%d", srv.URL, i)})
+    }
+    m := newForumMonitor(monitorOptions{URL: srv.URL, Fetcher: FastHTTPFetcher{UserAgent: defaultUserAgent, Attempts: 1}, Selectors: defaultSelectors, Concurrency: 2})
+
+    posts := m.fetchPosts(context.Background(), items)
+    for i, p := range posts {
+        if want := fmt.Sprintf("/t%d", i); p.Message != want {
+            t.Errorf("post %d = %q, want %q in input order", i, p.Message, want)
+        }
+    }
+    if n := peak.Load(); n > 2 {
+        t.Errorf("peak concurrent fetches = %d, want at most 2", n)
+    }
+}
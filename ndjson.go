@@ -0,0 +1,62 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "os"
+    "sync"
+    "time"
+)
+
+// outputNDJSON 将新帖子以换行分隔的 JSON 输出
+const outputNDJSON = "ndjson"
+
+// outputFiles 已打开的 -output-file，按路径复用，重新加载配置时不会重复打开
+var (
+    outputFilesMu sync.Mutex
+    outputFiles   = make(map[string]*os.File)
+)
+
+// openOutputFile 以追加方式打开 path，同一路径只打开一次，文件在进程退出前保持打开
+func openOutputFile(path string) (*os.File, error) {
+    outputFilesMu.Lock()
+    defer outputFilesMu.Unlock()
+    if f, ok := outputFiles[path]; ok {
+        return f, nil
+    }
+    f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+    if err != nil {
+        return nil, err
+    }
+    outputFiles[path] = f
+    return f, nil
+}
+
+// NDJSONNotifier 将每个新帖子写成一行 JSON，便于用 tail -f 或管道接入其他工具
+type NDJSONNotifier struct {
+    mu     sync.Mutex
+    Writer io.Writer
+}
+
+// ndjsonRecord 输出的每一行 JSON，字段与 Webhook 相同并增加发现时间
+type ndjsonRecord struct {
+    webhookPayload
+    DiscoveredAt time.Time `json:"discovered_at"`
+}
+
+// Notify 写出一行 JSON。整行通过一次 Write 写入且不经过缓冲，写入后即可被读取
+func (n *NDJSONNotifier) Notify(_ context.Context, p Post) error {
+    line, err := json.Marshal(ndjsonRecord{
+        webhookPayload: newWebhookPayload(p),
+        DiscoveredAt:   time.Now().UTC(),
+    })
+    if err != nil {
+        return err
+    }
+
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    _, err = n.Writer.Write(append(line, '\n'))
+    return err
+}
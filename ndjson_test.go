@@ -0,0 +1,68 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestNDJSONNotifierWritesOneLinePerPost(t *testing.T) {
+    var b bytes.Buffer
+    n := &NDJSONNotifier{Writer: &b}
+    for _, title := range []string{"一", "二"} {
+        if err := n.Notify(context.Background(), Post{Title: title, Message: `a\.b`, Format: formatMarkdown}); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    scanner := bufio.NewScanner(&b)
+    var titles []string
+    for scanner.Scan() {
+        var rec ndjsonRecord
+        if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+            t.Fatalf("line %q: %v", scanner.Text(), err)
+        }
+        if rec.Message != "a.b" || rec.DiscoveredAt.IsZero() {
+            t.Errorf("record = %+v", rec)
+        }
+        titles = append(titles, rec.Title)
+    }
+    if len(titles) != 2 || titles[0] != "一" || titles[1] != "二" {
+        t.Errorf("titles = %v", titles)
+    }
+}
+
+func TestOpenOutputFileReusesHandle(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "posts.ndjson")
+    first, err := openOutputFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    second, err := openOutputFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() {
+        outputFilesMu.Lock()
+        delete(outputFiles, path)
+        outputFilesMu.Unlock()
+        first.Close()
+    })
+    if first != second {
+        t.Error("the same path must reuse the open file")
+    }
+
+    first.WriteString("a\n")
+    second.WriteString("b\n")
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(data) != "a\nb\n" {
+        t.Errorf("file = %q", data)
+    }
+}
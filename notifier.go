@@ -0,0 +1,222 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "os"
+    "text/template"
+    "time"
+)
+
+// Notifier 将新帖子推送到某个通知渠道，monitorForum 只依赖该接口
+type Notifier interface {
+    Notify(ctx context.Context, p Post) error
+}
+
+// BatchNotifier 是能把多个帖子合并为一条消息发送的通知渠道
+type BatchNotifier interface {
+    NotifyBatch(ctx context.Context, posts []Post) error
+}
+
+// notifyBatch 合并推送多个帖子，渠道不支持合并时逐个推送
+func notifyBatch(ctx context.Context, n Notifier, posts []Post) error {
+    if b, ok := n.(BatchNotifier); ok {
+        return b.NotifyBatch(ctx, posts)
+    }
+    var errs []error
+    for _, p := range posts {
+        if err := n.Notify(ctx, p); err != nil {
+            errs = append(errs, fmt.Errorf("%s: %w", p.URL, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// multiNotifier 将帖子依次推送到多个渠道，某个渠道失败不影响其他渠道
+type multiNotifier []Notifier
+
+// Notify 推送到所有渠道并汇总错误
+func (m multiNotifier) Notify(ctx context.Context, p Post) error {
+    var errs []error
+    for _, n := range m {
+        if err := n.Notify(ctx, p); err != nil {
+            errs = append(errs, err)
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// NotifyBatch 合并推送到所有渠道并汇总错误
+func (m multiNotifier) NotifyBatch(ctx context.Context, posts []Post) error {
+    var errs []error
+    for _, n := range m {
+        if err := notifyBatch(ctx, n, posts); err != nil {
+            errs = append(errs, err)
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// StdoutNotifier 将帖子按 Telegram 消息格式写入 Writer 而不真正发送，用于 -dry-run 调试选择器
+type StdoutNotifier struct {
+    Writer    io.Writer
+    ParseMode string
+    Template  *template.Template
+}
+
+// Notify 写出格式化后的消息，每条消息之间用分隔线隔开
+func (n *StdoutNotifier) Notify(_ context.Context, p Post) error {
+    _, err := fmt.Fprintf(n.Writer, "%s\n----------------\n", renderPost(n.Template, p, n.ParseMode))
+    return err
+}
+
+// NotifyBatch 写出合并后的消息
+func (n *StdoutNotifier) NotifyBatch(_ context.Context, posts []Post) error {
+    _, err := fmt.Fprintf(n.Writer, "%s\n----------------\n", formatBatch(n.Template, posts, n.ParseMode))
+    return err
+}
+
+// notifyMaxAttempts 发送通知的最大尝试次数
+var notifyMaxAttempts = 3
+
+// retryNotify 执行发送操作，失败且可重试时等待后重试。send 返回服务端要求的等待时间，为 0 时按指数退避
+func retryNotify(ctx context.Context, name string, send func() (time.Duration, bool, error)) error {
+    var lastErr error
+    for attempt := 1; attempt <= notifyMaxAttempts; attempt++ {
+        wait, retryable, err := send()
+        if err == nil {
+            return nil
+        }
+        lastErr = err
+        if !retryable || attempt == notifyMaxAttempts || ctx.Err() != nil {
+            break
+        }
+        if !allowRetry() {
+            slog.Warn("重试预算已用完，放弃重试", "notifier", name, "attempt", attempt, "err", err)
+            break
+        }
+
+        if wait == 0 {
+            wait = retryBaseDelay << (attempt - 1)
+        }
+        slog.Warn("发送通知失败，稍后重试", "notifier", name, "attempt", attempt, "delay", wait, "err", err)
+        if !sleepContext(ctx, wait) {
+            return ctx.Err()
+        }
+    }
+    return lastErr
+}
+
+// notifyClient 通知渠道共用的 HTTP 客户端，超时限制包括读取响应体，渠道无响应时不会阻塞监控
+var notifyClient = &http.Client{Timeout: 30 * time.Second}
+
+// postJSON 以 JSON 格式提交 payload，返回响应供调用方检查状态码，调用方负责关闭响应体
+func postJSON(ctx context.Context, endpoint string, headers http.Header, payload any) (*http.Response, error) {
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return nil, fmt.Errorf("encode payload: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    for key, values := range headers {
+        req.Header[key] = values
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    return notifyClient.Do(req)
+}
+
+// buildNotifier 根据配置创建所有启用的通知渠道
+func buildNotifier(cfg *Config) (Notifier, error) {
+    tmpl, err := parseTemplate(cfg.Template)
+    if err != nil {
+        return nil, err
+    }
+    if cfg.DryRun {
+        return &StdoutNotifier{Writer: os.Stdout, ParseMode: cfg.ParseMode, Template: tmpl}, nil
+    }
+
+    // 配置了死信文件时，每个远程渠道单独记录发送失败的帖子，重新发送时不会重复发给已成功的渠道
+    var notifier multiNotifier
+    add := func(target string, n Notifier) {
+        if cfg.DeadLetter != "" {
+            n = &deadLetterNotifier{Notifier: n, Target: target, Path: cfg.DeadLetter}
+        }
+        notifier = append(notifier, n)
+    }
+    if cfg.telegramEnabled() {
+        add("telegram", &TelegramNotifier{
+            BotToken:  cfg.Token,
+            ChatIDs:   cfg.ChatIDs,
+            ParseMode: cfg.ParseMode,
+            Template:  tmpl,
+            Silent:    cfg.Silent,
+            NoPreview: cfg.NoPreview,
+            ThreadID:  cfg.ThreadID,
+            Buttons:   cfg.Buttons,
+        })
+    }
+    if cfg.DiscordWebhook != "" {
+        add("discord", &DiscordNotifier{WebhookURL: cfg.DiscordWebhook})
+    }
+    if cfg.SlackWebhook != "" {
+        add("slack", &SlackNotifier{WebhookURL: cfg.SlackWebhook})
+    }
+    if cfg.Webhook != "" {
+        // 请求头已在 Validate 中检查过
+        headers, _ := parseHeaders(cfg.WebhookHeaders)
+        add("webhook", &WebhookNotifier{URL: cfg.Webhook, Headers: headers})
+    }
+    if cfg.SMTPHost != "" {
+        add("smtp", &SMTPNotifier{
+            Host:     cfg.SMTPHost,
+            Port:     cfg.SMTPPort,
+            Username: cfg.SMTPUsername,
+            Password: cfg.SMTPPassword,
+            From:     cfg.SMTPFrom,
+            To:       cfg.SMTPTo,
+            TLS:      cfg.SMTPTLS,
+            HTML:     cfg.SMTPHTML,
+        })
+    }
+    if cfg.Exec != "" {
+        add("exec", &ExecNotifier{Command: cfg.Exec, Timeout: cfg.ExecTimeout})
+    }
+    if cfg.Output == outputNDJSON {
+        var w io.Writer = os.Stdout
+        if cfg.OutputFile != "" {
+            f, err := openOutputFile(cfg.OutputFile)
+            if err != nil {
+                return nil, fmt.Errorf("open output file: %w", err)
+            }
+            w = f
+        }
+        notifier = append(notifier, &NDJSONNotifier{Writer: w})
+    }
+    return notifier, nil
+}
+
+// buildOperatorNotifier 创建发送运维通知的渠道：只使用 Telegram、Discord、Slack 和邮件这些面向人的渠道，
+// webhook、exec 和 NDJSON 输出的消费方只处理帖子，不会收到告警。发送失败时不写入死信文件，
+// 调用方也不会再套上静默时段和订阅源，断路器等告警总是立即送达且不会混入帖子。没有可用渠道时返回 nil，告警只记录日志
+func buildOperatorNotifier(cfg *Config) (Notifier, error) {
+    c := *cfg
+    c.DeadLetter, c.Webhook, c.Exec, c.Output = "", "", "", ""
+    n, err := buildNotifier(&c)
+    if err != nil {
+        return nil, err
+    }
+    if channels, ok := n.(multiNotifier); ok && len(channels) == 0 {
+        return nil, nil
+    }
+    return n, nil
+}
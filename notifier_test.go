@@ -0,0 +1,239 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// recordingNotifier 记录收到的帖子，err 不为 nil 时每次都返回该错误
+type recordingNotifier struct {
+    mu      sync.Mutex
+    posts   []Post
+    batches [][]Post
+    err     error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, p Post) error {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    n.posts = append(n.posts, p)
+    return n.err
+}
+
+// titles 返回收到的帖子标题
+func (n *recordingNotifier) titles() []string {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    var titles []string
+    for _, p := range n.posts {
+        titles = append(titles, p.Title)
+    }
+    return titles
+}
+
+// recordingBatchNotifier 在 recordingNotifier 的基础上支持合并推送
+type recordingBatchNotifier struct {
+    recordingNotifier
+}
+
+func (n *recordingBatchNotifier) NotifyBatch(_ context.Context, posts []Post) error {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    n.batches = append(n.batches, posts)
+    return n.err
+}
+
+// withNotifyTimeout 缩短测试中通知请求的超时时间
+func withNotifyTimeout(t *testing.T, d time.Duration) {
+    t.Helper()
+    old := notifyClient.Timeout
+    notifyClient.Timeout = d
+    t.Cleanup(func() { notifyClient.Timeout = old })
+}
+
+// newStalledServer 启动一个直到测试结束才响应的服务器
+func newStalledServer(t *testing.T) *httptest.Server {
+    t.Helper()
+    release := make(chan struct{})
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        select {
+        case <-release:
+        case <-r.Context().Done():
+        }
+    }))
+    t.Cleanup(func() {
+        close(release)
+        srv.Close()
+    })
+    return srv
+}
+
+func TestPostJSONTimesOut(t *testing.T) {
+    withNotifyTimeout(t, 50*time.Millisecond)
+    srv := newStalledServer(t)
+
+    start := time.Now()
+    resp, err := postJSON(context.Background(), srv.URL, nil, map[string]string{"a": "b"})
+    if err == nil {
+        resp.Body.Close()
+        t.Fatal("postJSON succeeded against a stalled server")
+    }
+    if elapsed := time.Since(start); elapsed > 5*time.Second {
+        t.Errorf("postJSON returned after %v, want the client timeout", elapsed)
+    }
+}
+
+func TestMultiNotifierContinuesAfterFailure(t *testing.T) {
+    failing := &recordingNotifier{err: errors.New("down")}
+    ok := &recordingNotifier{}
+    err := multiNotifier{failing, ok}.Notify(context.Background(), Post{Title: "t"})
+    if err == nil || !strings.Contains(err.Error(), "down") {
+        t.Errorf("err = %v", err)
+    }
+    if len(ok.posts) != 1 {
+        t.Error("second notifier was skipped after the first failed")
+    }
+}
+
+func TestNotifyBatchFallsBackToSingleNotify(t *testing.T) {
+    single := &recordingNotifier{}
+    batch := &recordingBatchNotifier{}
+    posts := []Post{{Title: "a"}, {Title: "b"}}
+    if err := notifyBatch(context.Background(), multiNotifier{single, batch}, posts); err != nil {
+        t.Fatal(err)
+    }
+    if strings.Join(single.titles(), ",") != "a,b" {
+        t.Errorf("single = %v", single.titles())
+    }
+    if len(batch.batches) != 1 || len(batch.batches[0]) != 2 || len(batch.posts) != 0 {
+        t.Errorf("batch = %+v", batch.batches)
+    }
+}
+
+func TestRetryNotify(t *testing.T) {
+    withFastRetry(t)
+    tests := []struct {
+        name      string
+        failures  int
+        retryable bool
+        wantCalls int
+        wantErr   bool
+    }{
+        {"success", 0, true, 1, false},
+        {"recovers", 2, true, 3, false},
+        {"gives up", 5, true, notifyMaxAttempts, true},
+        {"permanent", 5, false, 1, true},
+    }
+    for _, tt := range tests {
+        calls := 0
+        err := retryNotify(context.Background(), "test", func() (time.Duration, bool, error) {
+            calls++
+            if calls <= tt.failures {
+                return 0, tt.retryable, errors.New("fail")
+            }
+            return 0, false, nil
+        })
+        if calls != tt.wantCalls || (err != nil) != tt.wantErr {
+            t.Errorf("%s: calls = %d, err = %v", tt.name, calls, err)
+        }
+    }
+}
+
+func TestRetryNotifyRespectsBudget(t *testing.T) {
+    withFastRetry(t)
+    old := retryBudget
+    retryBudget = newRetryBudget(1)
+    retryBudget.AllowN(time.Now(), 1)
+    t.Cleanup(func() { retryBudget = old })
+
+    calls := 0
+    retryNotify(context.Background(), "test", func() (time.Duration, bool, error) {
+        calls++
+        return 0, true, errors.New("fail")
+    })
+    if calls != 1 {
+        t.Errorf("calls = %d, an exhausted budget must stop retries", calls)
+    }
+}
+
+func TestStdoutNotifier(t *testing.T) {
+    var b bytes.Buffer
+    n := &StdoutNotifier{Writer: &b, ParseMode: parseModeHTML}
+    if err := n.Notify(context.Background(), Post{Title: "a&b", URL: "https://fishc.com.cn/t"}); err != nil {
+        t.Fatal(err)
+    }
+    if err := n.NotifyBatch(context.Background(), []Post{{Title: "x"}, {Title: "y"}}); err != nil {
+        t.Fatal(err)
+    }
+    out := b.String()
+    if !strings.Contains(out, "<b>a&amp;b</b>") || strings.Count(out, "----------------") != 2 || !strings.Contains(out, batchSeparator) {
+        t.Errorf("output = %q", out)
+    }
+}
+
+func TestBuildNotifierChannels(t *testing.T) {
+    cfg := defaultConfig()
+    cfg.Token = "token"
+    cfg.ChatIDs = []string{"1"}
+    cfg.DiscordWebhook = "https://discord.example/hook"
+    cfg.Webhook = "https://example.com/hook"
+    cfg.DeadLetter = t.TempDir() + "/dead.ndjson"
+
+    n, err := buildNotifier(cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    channels := n.(multiNotifier)
+    if len(channels) != 3 {
+        t.Fatalf("channels = %d, want 3", len(channels))
+    }
+    for _, c := range channels {
+        if _, ok := c.(*deadLetterNotifier); !ok {
+            t.Errorf("%T is not wrapped with the dead letter log", c)
+        }
+    }
+
+    // 运维通知只发到 Telegram 和 Discord，不写入死信文件
+    cfg.Exec = "true"
+    cfg.Output = outputNDJSON
+    operator, err := buildOperatorNotifier(cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ops := operator.(multiNotifier)
+    if len(ops) != 2 {
+        t.Fatalf("operator channels = %d, want telegram and discord only", len(ops))
+    }
+    if _, ok := ops[0].(*TelegramNotifier); !ok {
+        t.Errorf("operator channel %T, want *TelegramNotifier", ops[0])
+    }
+    if _, ok := ops[1].(*DiscordNotifier); !ok {
+        t.Errorf("operator channel %T, want *DiscordNotifier", ops[1])
+    }
+    if cfg.DeadLetter == "" || cfg.Webhook == "" || cfg.Exec == "" {
+        t.Error("buildOperatorNotifier modified the config")
+    }
+    cfg.Exec, cfg.Output = "", ""
+
+    machine := validConfig()
+    machine.Exec = "true"
+    if operator, err := buildOperatorNotifier(machine); err != nil || operator != nil {
+        t.Errorf("operator = %v, %v, want nil without a chat or e-mail channel", operator, err)
+    }
+
+    cfg.DryRun = true
+    n, err = buildNotifier(cfg)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, ok := n.(*StdoutNotifier); !ok {
+        t.Errorf("dry run notifier = %T", n)
+    }
+}
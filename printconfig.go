@@ -0,0 +1,92 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "net/url"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// redactedValue 输出配置时替代敏感值的占位符
+const redactedValue = "[REDACTED]"
+
+// redacted 返回隐藏了令牌、密码、Cookie、Webhook 地址等敏感值的副本
+func (c *Config) redacted() *Config {
+    r := *c
+    for _, s := range []*string{&r.Token, &r.DiscordWebhook, &r.SlackWebhook, &r.SMTPPassword, &r.Cookie, &r.BasicAuth, &r.ForumPassword} {
+        if *s != "" {
+            *s = redactedValue
+        }
+    }
+    r.Webhook = redactURL(r.Webhook)
+    r.Proxy = redactURL(r.Proxy)
+    r.WebhookHeaders = redactHeaders(r.WebhookHeaders, true)
+    r.Headers = redactHeaders(r.Headers, false)
+    return &r
+}
+
+// redactURL 隐藏 URL 中的密码和查询参数，Webhook 地址的令牌常放在查询参数中
+func redactURL(raw string) string {
+    u, err := url.Parse(raw)
+    if err != nil || raw == "" {
+        return raw
+    }
+    if u.RawQuery != "" {
+        u.RawQuery = redactedValue
+    }
+    return u.Redacted()
+}
+
+// redactHeaders 隐藏 "Key: Value" 形式请求头的值，all 为 false 时只隐藏 Cookie、Authorization 等敏感头
+func redactHeaders(lines []string, all bool) []string {
+    if len(lines) == 0 {
+        return lines
+    }
+    out := make([]string, len(lines))
+    for i, line := range lines {
+        key, _, ok := strings.Cut(line, ":")
+        if ok && (all || sensitiveHeaders[strings.ToLower(strings.TrimSpace(key))]) {
+            line = key + ": " + redactedValue
+        }
+        out[i] = line
+    }
+    return out
+}
+
+// printConfig 以 YAML 输出合并了默认值、配置文件、环境变量和命令行参数之后实际生效的配置，敏感值已隐藏。
+// 使用配置方案时每个方案输出叠加顶层配置后的完整设置，停用的方案不输出
+func printConfig(w io.Writer, cfg *Config, profiles []Profile) error {
+    top := cfg.redacted()
+    top.Profiles = nil
+    if len(cfg.Profiles) > 0 {
+        for _, p := range profiles {
+            pc := p.Config.redacted()
+            pc.Profiles = nil
+            var node yaml.Node
+            if err := node.Encode(pc); err != nil {
+                return err
+            }
+            // 方案名放在最前面，去掉方案内不适用的 profiles 字段
+            content := []*yaml.Node{
+                {Kind: yaml.ScalarNode, Value: "name"},
+                {Kind: yaml.ScalarNode, Value: p.Name},
+            }
+            for i := 0; i+1 < len(node.Content); i += 2 {
+                if node.Content[i].Value != "profiles" {
+                    content = append(content, node.Content[i], node.Content[i+1])
+                }
+            }
+            node.Content = content
+            top.Profiles = append(top.Profiles, node)
+        }
+    }
+
+    out, err := yaml.Marshal(top)
+    if err != nil {
+        return err
+    }
+    _, err = fmt.Fprint(w, string(out))
+    return err
+}
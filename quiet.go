@@ -0,0 +1,171 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "strings"
+    "sync"
+    "time"
+    _ "time/tzdata"
+)
+
+// quietWindow 每天的免打扰时段，以当天零点起的分钟数表示，start 大于 end 时跨越零点
+type quietWindow struct {
+    start, end int
+    loc        *time.Location
+}
+
+// parseQuietWindow 解析 "22:00-07:00" 形式的时段，tz 为 IANA 时区名或 Local
+func parseQuietWindow(s, tz string) (quietWindow, error) {
+    from, to, ok := strings.Cut(s, "-")
+    if !ok {
+        return quietWindow{}, fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", s)
+    }
+    start, err := time.Parse("15:04", strings.TrimSpace(from))
+    if err != nil {
+        return quietWindow{}, fmt.Errorf("invalid quiet hours %q: %w", s, err)
+    }
+    end, err := time.Parse("15:04", strings.TrimSpace(to))
+    if err != nil {
+        return quietWindow{}, fmt.Errorf("invalid quiet hours %q: %w", s, err)
+    }
+    loc, err := time.LoadLocation(tz)
+    if err != nil {
+        return quietWindow{}, fmt.Errorf("invalid quiet hours timezone %q: %w", tz, err)
+    }
+    w := quietWindow{start: start.Hour()*60 + start.Minute(), end: end.Hour()*60 + end.Minute(), loc: loc}
+    if w.start == w.end {
+        return quietWindow{}, fmt.Errorf("invalid quiet hours %q: start equals end", s)
+    }
+    return w, nil
+}
+
+// contains 判断 t 是否在免打扰时段内
+func (w quietWindow) contains(t time.Time) bool {
+    t = t.In(w.loc)
+    m := t.Hour()*60 + t.Minute()
+    if w.start < w.end {
+        return m >= w.start && m < w.end
+    }
+    return m >= w.start || m < w.end
+}
+
+// untilEnd 返回从 t 到下一次免打扰时段结束的时间
+func (w quietWindow) untilEnd(t time.Time) time.Duration {
+    local := t.In(w.loc)
+    end := time.Date(local.Year(), local.Month(), local.Day(), w.end/60, w.end%60, 0, 0, w.loc)
+    if !end.After(local) {
+        end = end.AddDate(0, 0, 1)
+    }
+    return end.Sub(local)
+}
+
+// quietNotifier 在免打扰时段内把通知暂存在内存中，时段结束后依次发出，Batch 为 true 时合并为一条发出。
+// 发现新帖和去重照常进行，进程退出时立即发出暂存的通知，不会丢失
+type quietNotifier struct {
+    Notifier
+    window quietWindow
+    batch  bool
+    now    func() time.Time
+
+    mu       sync.Mutex
+    queue    []Post
+    flushing bool
+}
+
+// withQuietHours 按配置为通知渠道加上免打扰时段，未配置时原样返回。时段格式已由 Validate 检查
+func withQuietHours(cfg *Config, n Notifier) Notifier {
+    if cfg.QuietHours == "" {
+        return n
+    }
+    window, _ := parseQuietWindow(cfg.QuietHours, cfg.QuietTimezone)
+    return &quietNotifier{Notifier: n, window: window, batch: cfg.QuietBatch, now: time.Now}
+}
+
+// quietFlushes 等待发送暂存通知的 goroutine，进程退出前等待它们发送完毕
+var quietFlushes sync.WaitGroup
+
+// quietShutdownTimeout 进程退出时发送暂存通知的时长上限
+var quietShutdownTimeout = 30 * time.Second
+
+// errHeld 表示通知在免打扰时段内被暂存，尚未发送，调用方不应把它计为发送成功或失败
+var errHeld = errors.New("notification held during quiet hours")
+
+// Notify 免打扰时段内暂存帖子并返回 errHeld，否则直接发送
+func (n *quietNotifier) Notify(ctx context.Context, p Post) error {
+    if n.hold(ctx, []Post{p}) {
+        return errHeld
+    }
+    return n.Notifier.Notify(ctx, p)
+}
+
+// NotifyBatch 免打扰时段内暂存帖子并返回 errHeld，否则直接合并发送
+func (n *quietNotifier) NotifyBatch(ctx context.Context, posts []Post) error {
+    if n.hold(ctx, posts) {
+        return errHeld
+    }
+    return notifyBatch(ctx, n.Notifier, posts)
+}
+
+// hold 在免打扰时段内暂存帖子并返回 true，需要时启动在时段结束或 ctx 结束后发送的 goroutine
+func (n *quietNotifier) hold(ctx context.Context, posts []Post) bool {
+    now := n.now()
+    if !n.window.contains(now) {
+        return false
+    }
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    n.queue = append(n.queue, posts...)
+    slog.Info("免打扰时段，通知暂存到时段结束后发送", "posts", len(posts), "queued", len(n.queue))
+    if !n.flushing {
+        n.flushing = true
+        quietFlushes.Add(1)
+        go n.flushAfter(ctx, n.window.untilEnd(now))
+    }
+    return true
+}
+
+// flushAfter 等待 delay 后发送暂存的通知。ctx 结束表示进程即将退出，此时不再等待，
+// 在 quietShutdownTimeout 内立即发送
+func (n *quietNotifier) flushAfter(ctx context.Context, delay time.Duration) {
+    defer quietFlushes.Done()
+    if sleepContext(ctx, delay) {
+        n.flush(ctx)
+        return
+    }
+    slog.Info("进程退出，立即发送免打扰时段内暂存的通知")
+    shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), quietShutdownTimeout)
+    defer cancel()
+    n.flush(shutdownCtx)
+}
+
+// flush 发送并清空暂存的通知。配置了死信文件时各渠道发送失败的帖子由 deadLetterNotifier 写入死信文件，
+// 之后可以用 -replay-deadletter 重新发送
+func (n *quietNotifier) flush(ctx context.Context) {
+    n.mu.Lock()
+    queue := n.queue
+    n.queue, n.flushing = nil, false
+    n.mu.Unlock()
+    if len(queue) == 0 {
+        return
+    }
+
+    slog.Info("免打扰时段结束，发送暂存的通知", "posts", len(queue))
+    if n.batch {
+        if err := notifyBatch(ctx, n.Notifier, queue); err != nil {
+            slog.Error("发送暂存的合并通知失败", "posts", len(queue), "err", err)
+            return
+        }
+        notificationsSentTotal.Add(float64(len(queue)))
+        return
+    }
+    for _, p := range queue {
+        if err := n.Notifier.Notify(ctx, p); err != nil {
+            slog.Error(msg("log.notify_failed"), "post_url", p.URL, "err", err)
+            continue
+        }
+        notificationsSentTotal.Inc()
+    }
+}
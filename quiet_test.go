@@ -0,0 +1,186 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseQuietWindow(t *testing.T) {
+    tests := []struct {
+        in, tz     string
+        start, end int
+        err        string
+    }{
+        {"22:00-07:00", "Local", 22 * 60, 7 * 60, ""},
+        {" 12:30 - 13:45 ", "Asia/Shanghai", 12*60 + 30, 13*60 + 45, ""},
+        {"22:00", "Local", 0, 0, "expected HH:MM-HH:MM"},
+        {"25:00-07:00", "Local", 0, 0, "invalid quiet hours"},
+        {"22:00-7", "Local", 0, 0, "invalid quiet hours"},
+        {"08:00-08:00", "Local", 0, 0, "start equals end"},
+        {"22:00-07:00", "Mars/Olympus", 0, 0, "invalid quiet hours timezone"},
+    }
+    for _, tt := range tests {
+        w, err := parseQuietWindow(tt.in, tt.tz)
+        if tt.err != "" {
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("parseQuietWindow(%q, %q) = %v, want error containing %q", tt.in, tt.tz, err, tt.err)
+            }
+            continue
+        }
+        if err != nil || w.start != tt.start || w.end != tt.end {
+            t.Errorf("parseQuietWindow(%q, %q) = %+v, %v", tt.in, tt.tz, w, err)
+        }
+    }
+}
+
+func TestQuietWindowContains(t *testing.T) {
+    overnight, err := parseQuietWindow("22:00-07:00", "UTC")
+    if err != nil {
+        t.Fatal(err)
+    }
+    daytime, err := parseQuietWindow("12:00-13:00", "Asia/Shanghai")
+    if err != nil {
+        t.Fatal(err)
+    }
+    at := func(hour, minute int) time.Time { return time.Date(2024, 5, 12, hour, minute, 0, 0, time.UTC) }
+    tests := []struct {
+        name string
+        w    quietWindow
+        t    time.Time
+        want bool
+    }{
+        {"overnight start", overnight, at(22, 0), true},
+        {"overnight after midnight", overnight, at(3, 0), true},
+        {"overnight end excluded", overnight, at(7, 0), false},
+        {"overnight daytime", overnight, at(12, 0), false},
+        {"other timezone inside", daytime, at(4, 30), true},
+        {"other timezone outside", daytime, at(12, 30), false},
+    }
+    for _, tt := range tests {
+        if got := tt.w.contains(tt.t); got != tt.want {
+            t.Errorf("%s: contains(%v) = %v, want %v", tt.name, tt.t, got, tt.want)
+        }
+    }
+}
+
+func TestQuietWindowUntilEnd(t *testing.T) {
+    w, err := parseQuietWindow("22:00-07:00", "UTC")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := w.untilEnd(time.Date(2024, 5, 12, 23, 0, 0, 0, time.UTC)); got != 8*time.Hour {
+        t.Errorf("untilEnd(23:00) = %v, want 8h", got)
+    }
+    if got := w.untilEnd(time.Date(2024, 5, 12, 6, 30, 0, 0, time.UTC)); got != 30*time.Minute {
+        t.Errorf("untilEnd(06:30) = %v, want 30m", got)
+    }
+}
+
+// newTestQuiet 返回包装 next 的免打扰通知，now 返回的时间决定是否在时段内
+func newTestQuiet(t *testing.T, next Notifier, batch bool, quiet bool) *quietNotifier {
+    t.Helper()
+    w, err := parseQuietWindow("22:00-07:00", "UTC")
+    if err != nil {
+        t.Fatal(err)
+    }
+    // 暂存的通知要到时段结束后才会自动发送，测试中直接调用 flush
+    now := time.Date(2024, 5, 12, 12, 0, 0, 0, time.UTC)
+    if quiet {
+        now = time.Date(2024, 5, 12, 23, 0, 0, 0, time.UTC)
+    }
+    return &quietNotifier{Notifier: next, window: w, batch: batch, now: func() time.Time { return now }}
+}
+
+func TestQuietNotifierPassesThroughOutsideWindow(t *testing.T) {
+    next := &recordingNotifier{}
+    n := newTestQuiet(t, next, false, false)
+    if err := n.Notify(context.Background(), Post{Title: "a"}); err != nil {
+        t.Fatal(err)
+    }
+    if len(next.posts) != 1 {
+        t.Errorf("posts = %d, want sent immediately", len(next.posts))
+    }
+}
+
+func TestQuietNotifierHoldsAndFlushes(t *testing.T) {
+    next := &recordingNotifier{}
+    n := newTestQuiet(t, next, false, true)
+    for _, title := range []string{"a", "b"} {
+        if err := n.Notify(context.Background(), Post{Title: title}); !errors.Is(err, errHeld) {
+            t.Fatalf("Notify() = %v, want errHeld", err)
+        }
+    }
+    if len(next.posts) != 0 {
+        t.Fatal("post was sent during quiet hours")
+    }
+    n.flush(context.Background())
+    if got := strings.Join(next.titles(), ","); got != "a,b" {
+        t.Errorf("flushed %q, want held posts in order", got)
+    }
+    n.flush(context.Background())
+    if len(next.posts) != 2 {
+        t.Error("second flush sent posts again")
+    }
+}
+
+func TestQuietNotifierBatchFlush(t *testing.T) {
+    next := &recordingBatchNotifier{}
+    n := newTestQuiet(t, next, true, true)
+    n.Notify(context.Background(), Post{Title: "a"})
+    if err := n.NotifyBatch(context.Background(), []Post{{Title: "b"}, {Title: "c"}}); !errors.Is(err, errHeld) {
+        t.Fatalf("NotifyBatch() = %v, want errHeld", err)
+    }
+    n.flush(context.Background())
+    if len(next.batches) != 1 || len(next.batches[0]) != 3 || len(next.posts) != 0 {
+        t.Errorf("batches = %v, posts = %d, want one batch of 3", next.batches, len(next.posts))
+    }
+}
+
+func TestQuietNotifierFlushesOnShutdown(t *testing.T) {
+    next := &recordingNotifier{}
+    n := newTestQuiet(t, next, false, true)
+    ctx, cancel := context.WithCancel(context.Background())
+    if err := n.Notify(ctx, Post{Title: "a"}); !errors.Is(err, errHeld) {
+        t.Fatalf("Notify() = %v, want errHeld", err)
+    }
+    cancel()
+
+    deadline := time.Now().Add(5 * time.Second)
+    for len(next.titles()) == 0 && time.Now().Before(deadline) {
+        time.Sleep(time.Millisecond)
+    }
+    if got := strings.Join(next.titles(), ","); got != "a" {
+        t.Errorf("sent %q on shutdown, want the held post", got)
+    }
+}
+
+func TestQuietNotifierFlushFailureGoesToDeadLetter(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "dead.ndjson")
+    next := multiNotifier{&deadLetterNotifier{Notifier: &recordingNotifier{err: errors.New("down")}, Target: "webhook", Path: path}}
+    n := newTestQuiet(t, next, false, true)
+    n.Notify(context.Background(), Post{URL: "https://example.com/1", Title: "a"})
+    n.flush(context.Background())
+
+    records := readDeadLetters(t, path)
+    if len(records) != 1 || records[0].URL != "https://example.com/1" || records[0].Target != "webhook" {
+        t.Errorf("records = %+v, want the held post that failed to flush", records)
+    }
+}
+
+func TestWithQuietHours(t *testing.T) {
+    cfg := defaultConfig()
+    next := &recordingNotifier{}
+    if n := withQuietHours(cfg, next); n != Notifier(next) {
+        t.Error("notifier was wrapped without quiet hours")
+    }
+    cfg.QuietHours = "22:00-07:00"
+    cfg.QuietBatch = true
+    q, ok := withQuietHours(cfg, next).(*quietNotifier)
+    if !ok || !q.batch {
+        t.Errorf("withQuietHours = %#v, want a batching quietNotifier", q)
+    }
+}
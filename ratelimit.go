@@ -0,0 +1,90 @@
+package main
+
+import (
+    "context"
+    "net/url"
+    "sync"
+
+    "golang.org/x/time/rate"
+)
+
+// hostLimiter 按站点分别限制请求频率，每个站点一个令牌桶
+type hostLimiter struct {
+    mu       sync.Mutex
+    limit    rate.Limit
+    limiters map[string]*rate.Limiter
+}
+
+// rateLimit 全局的按站点限速器，为 nil 时不限速
+var rateLimit *hostLimiter
+
+// newHostLimiter 创建每个站点每秒最多 perSecond 个请求的限速器
+func newHostLimiter(perSecond float64) *hostLimiter {
+    return &hostLimiter{
+        limit:    rate.Limit(perSecond),
+        limiters: make(map[string]*rate.Limiter),
+    }
+}
+
+// Wait 等待页面所在站点的令牌，ctx 被取消时返回 ctx 的错误。l 为 nil 时直接放行
+func (l *hostLimiter) Wait(ctx context.Context, pageURL string) error {
+    if l == nil {
+        return nil
+    }
+    u, err := url.Parse(pageURL)
+    if err != nil {
+        return err
+    }
+
+    l.mu.Lock()
+    limiter, ok := l.limiters[u.Host]
+    if !ok {
+        // 桶容量为 1，不允许突发，请求之间至少间隔 1/limit 秒
+        limiter = rate.NewLimiter(l.limit, 1)
+        l.limiters[u.Host] = limiter
+    }
+    l.mu.Unlock()
+
+    return limiter.Wait(ctx)
+}
+
+// retryBudget 抓取和通知的重试共用的令牌桶，限制整个进程每秒的重试次数，为 nil 时不限制。
+// 大面积故障时各论坛、各帖子的重试不会叠加成对论坛和通知服务的集中冲击
+var retryBudget *rate.Limiter
+
+// newRetryBudget 创建每秒最多 perSecond 次重试的预算，允许积攒最多 1 秒的令牌
+func newRetryBudget(perSecond float64) *rate.Limiter {
+    return rate.NewLimiter(rate.Limit(perSecond), max(1, int(perSecond)))
+}
+
+// requestSlots 限制整个进程同时进行的页面请求数的信号量，所有论坛的监控共用，为 nil 时不限制
+var requestSlots chan struct{}
+
+// acquireRequest 等待一个请求名额，ctx 被取消时返回 ctx 的错误。取得名额后必须调用 releaseRequest 归还
+func acquireRequest(ctx context.Context) error {
+    if requestSlots == nil {
+        return nil
+    }
+    select {
+    case requestSlots <- struct{}{}:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// releaseRequest 归还 acquireRequest 取得的请求名额
+func releaseRequest() {
+    if requestSlots != nil {
+        <-requestSlots
+    }
+}
+
+// allowRetry 从重试预算中取一个令牌，预算用完时返回 false，调用方应放弃重试并返回上次的错误
+func allowRetry() bool {
+    if retryBudget == nil || retryBudget.Allow() {
+        return true
+    }
+    retriesDroppedTotal.Inc()
+    return false
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestHostLimiterPerHost(t *testing.T) {
+    l := newHostLimiter(20)
+    ctx := context.Background()
+    start := time.Now()
+    for i := 0; i < 3; i++ {
+        if err := l.Wait(ctx, "https://a.example/page"); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+        t.Errorf("3 requests to one host took %v, want at least 2 intervals of 50ms", elapsed)
+    }
+
+    start = time.Now()
+    if err := l.Wait(ctx, "https://b.example/page"); err != nil {
+        t.Fatal(err)
+    }
+    if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+        t.Errorf("first request to another host waited %v", elapsed)
+    }
+}
+
+func TestHostLimiterCanceled(t *testing.T) {
+    l := newHostLimiter(0.001)
+    if err := l.Wait(context.Background(), "https://a.example/"); err != nil {
+        t.Fatal(err)
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if err := l.Wait(ctx, "https://a.example/"); err == nil {
+        t.Error("Wait() = nil, want an error when the context ends first")
+    }
+
+    var none *hostLimiter
+    if err := none.Wait(ctx, "https://a.example/"); err != nil {
+        t.Errorf("nil limiter Wait() = %v", err)
+    }
+}
+
+func TestAcquireRequest(t *testing.T) {
+    saved := requestSlots
+    t.Cleanup(func() { requestSlots = saved })
+
+    requestSlots = nil
+    if err := acquireRequest(context.Background()); err != nil {
+        t.Fatalf("unlimited acquire = %v", err)
+    }
+    releaseRequest()
+
+    requestSlots = make(chan struct{}, 1)
+    if err := acquireRequest(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    if err := acquireRequest(ctx); !errors.Is(err, context.DeadlineExceeded) {
+        t.Fatalf("acquire with no free slot = %v, want deadline exceeded", err)
+    }
+    releaseRequest()
+    if err := acquireRequest(context.Background()); err != nil {
+        t.Errorf("acquire after release = %v", err)
+    }
+    releaseRequest()
+}
+
+func TestAllowRetry(t *testing.T) {
+    saved := retryBudget
+    t.Cleanup(func() { retryBudget = saved })
+
+    retryBudget = nil
+    if !allowRetry() {
+        t.Fatal("nil budget should always allow retries")
+    }
+    retryBudget = newRetryBudget(2)
+    allowed := 0
+    for i := 0; i < 5; i++ {
+        if allowRetry() {
+            allowed++
+        }
+    }
+    if allowed != 2 {
+        t.Errorf("allowed %d retries in a burst, want 2", allowed)
+    }
+}
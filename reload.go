@@ -0,0 +1,172 @@
+package main
+
+import (
+    "log/slog"
+    "reflect"
+    "slices"
+    "sync"
+    "time"
+)
+
+// liveSettings 运行中可以通过 SIGHUP 重新加载的设置，同一配置方案的监控共用一份，
+// 监控在每轮检查开始时读取
+type liveSettings struct {
+    mu       sync.Mutex
+    interval time.Duration
+    jitter   time.Duration
+    // watchInterval 关注帖子的检查间隔
+    watchInterval time.Duration
+    filter        *Filter
+    notifier      Notifier
+    operator      Notifier
+    // 以下为帖子的筛选条件和合并通知的排序方式
+    minReplies     int
+    minAge         time.Duration
+    maxAge         time.Duration
+    dropUnknownAge bool
+    batchSort      string
+}
+
+// set 替换全部可重新加载的设置
+func (l *liveSettings) set(interval, jitter, watchInterval time.Duration, filter *Filter, notifier, operator Notifier) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.interval, l.jitter, l.watchInterval, l.filter = interval, jitter, watchInterval, filter
+    l.notifier, l.operator = notifier, operator
+}
+
+// setPostRules 按 cfg 替换帖子的回复数和发布时长条件以及合并通知的排序方式
+func (l *liveSettings) setPostRules(cfg *Config) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.minReplies, l.minAge, l.maxAge = cfg.MinReplies, cfg.MinAge, cfg.MaxAge
+    l.dropUnknownAge, l.batchSort = cfg.AgeUnknown == ageDrop, cfg.BatchSort
+}
+
+// watch 返回关注帖子当前使用的检查间隔和通知渠道
+func (l *liveSettings) watch() (time.Duration, Notifier) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return l.watchInterval, l.notifier
+}
+
+// apply 用当前设置覆盖 opts 中对应的字段，l 为 nil 时不做修改
+func (l *liveSettings) apply(opts *monitorOptions) {
+    if l == nil {
+        return
+    }
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    opts.Interval, opts.Jitter, opts.Filter = l.interval, l.jitter, l.filter
+    opts.Notifier, opts.Operator = l.notifier, l.operator
+    opts.MinReplies, opts.MinAge, opts.MaxAge = l.minReplies, l.minAge, l.maxAge
+    opts.DropUnknownAge, opts.BatchSort = l.dropUnknownAge, l.batchSort
+}
+
+// runningProfile 正在运行的配置方案及其可重新加载的设置
+type runningProfile struct {
+    cfg  *Config
+    live *liveSettings
+}
+
+// reloadableFields 可以热更新的配置字段，用于在日志中列出发生变化的设置
+var reloadableFields = []string{
+    "Interval", "Jitter", "WatchInterval", "Include", "Exclude", "CaseSensitive",
+    "MinReplies", "MinAge", "MaxAge", "AgeUnknown", "BatchSort",
+    "Token", "ChatIDs", "ParseMode", "Template", "Silent", "NoPreview", "ThreadID", "Buttons",
+    "DiscordWebhook", "SlackWebhook", "Webhook", "WebhookHeaders", "DeadLetter", "QuietHours", "QuietTimezone", "QuietBatch", "Output", "OutputFile", "DryRun",
+    "SMTPHost", "SMTPPort", "SMTPUsername", "SMTPPassword", "SMTPFrom", "SMTPTo", "SMTPTLS", "SMTPHTML", "Exec", "ExecTimeout",
+}
+
+// restartFields 修改后需要重启才能生效的配置字段。论坛地址由 forumURLs 单独比较，
+// 配置方案和只在命令行使用的运行模式不参与比较，其余字段必须出现在 reloadableFields 或这里
+var restartFields = []string{
+    "Selectors", "Forums", "Format", "MaxLen", "Dedup", "CanonicalStrip", "Since", "SkipSticky", "SkipInitial", "CatchUpPages", "Concurrency",
+    "TargetsFile", "CycleTimeout", "MinSleep", "IPVersion", "Batch", "Store", "State", "StoreMaxAge", "BloomCapacity", "BloomFPRate",
+    "UserAgent", "Method", "Body", "ContentType", "Retries", "BreakerThreshold", "BreakerCooldown", "EmptyAlertCycles",
+    "Proxy", "CAFile", "InsecureSkipVerify", "Headers", "Cookie", "BasicAuth", "ForumUsername", "ForumPassword",
+    "Rate", "RetryBudget", "MaxConcurrency", "MaxBody", "IgnoreRobots", "CacheDir", "CacheTTL",
+    "MetricsAddr", "HealthAddr", "FeedAddr", "FeedSize", "LogLevel", "Trace", "Lang", "Watch",
+}
+
+// reloadConfig 重新读取配置文件和命令行参数，把过滤规则、帖子筛选条件、间隔、模板和通知渠道应用到正在运行的配置方案，
+// 并按新的间隔调整健康检查的阈值。新配置有错误时保留原配置；论坛列表等无法热更新的设置发生变化时提示需要重启
+func reloadConfig(args []string, running map[string]*runningProfile, feed *feedNotifier, health *healthTracker) {
+    cfg, err := loadConfig(args)
+    if err == nil {
+        err = cfg.Validate()
+    }
+    var profiles []Profile
+    if err == nil {
+        profiles, err = cfg.profiles()
+    }
+    if err != nil {
+        slog.Error("重新加载配置失败，继续使用原配置", "err", err)
+        return
+    }
+
+    for _, p := range profiles {
+        rp, ok := running[p.Name]
+        if !ok {
+            slog.Warn("新增的配置方案需要重启才能生效", "profile", p.Name)
+            continue
+        }
+        if !slices.Equal(forumURLs(rp.cfg), forumURLs(p.Config)) {
+            slog.Warn("论坛列表的修改需要重启才能生效", "profile", p.Name)
+        }
+        if changed := changedFields(rp.cfg, p.Config, restartFields); len(changed) > 0 {
+            slog.Warn("部分设置的修改需要重启才能生效", "profile", p.Name, "fields", changed)
+        }
+
+        notifier, err := buildNotifier(p.Config)
+        if err != nil {
+            slog.Error("重新加载通知渠道失败，继续使用原配置", "profile", p.Name, "err", err)
+            continue
+        }
+        operator, err := buildOperatorNotifier(p.Config)
+        if err != nil {
+            slog.Error("重新加载通知渠道失败，继续使用原配置", "profile", p.Name, "err", err)
+            continue
+        }
+        notifier = withQuietHours(p.Config, notifier)
+        if feed != nil {
+            notifier = multiNotifier{notifier, feed}
+        }
+        filter, err := newFilter(p.Config.Include, p.Config.Exclude, p.Config.CaseSensitive)
+        if err != nil {
+            slog.Error("重新加载过滤规则失败，继续使用原配置", "profile", p.Name, "err", err)
+            continue
+        }
+        rp.live.set(p.Config.Interval, p.Config.Jitter, p.Config.WatchInterval, filter, notifier, operator)
+        rp.live.setPostRules(p.Config)
+        slog.Info("配置已重新加载", "profile", p.Name, "changed", changedFields(rp.cfg, p.Config, reloadableFields))
+        rp.cfg = p.Config
+    }
+    health.setThreshold(healthThreshold(cfg, profiles))
+    for name := range running {
+        if !slices.ContainsFunc(profiles, func(p Profile) bool { return p.Name == name }) {
+            slog.Warn("删除或停用配置方案需要重启才能生效", "profile", name)
+        }
+    }
+}
+
+// forumURLs 返回配置文件中直接列出的论坛的 URL，目标文件中的论坛会自动增减，不参与比较
+func forumURLs(cfg *Config) []string {
+    var urls []string
+    for _, forum := range cfg.staticForums() {
+        urls = append(urls, forum.URL)
+    }
+    return urls
+}
+
+// changedFields 返回 fields 中新旧配置取值不同的字段名
+func changedFields(old, new *Config, fields []string) []string {
+    var changed []string
+    ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+    for _, name := range fields {
+        if !reflect.DeepEqual(ov.FieldByName(name).Interface(), nv.FieldByName(name).Interface()) {
+            changed = append(changed, name)
+        }
+    }
+    return changed
+}
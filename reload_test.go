@@ -0,0 +1,127 @@
+package main
+
+import (
+    "os"
+    "reflect"
+    "slices"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestChangedFields(t *testing.T) {
+    old := defaultConfig()
+    updated := defaultConfig()
+    updated.Interval = time.Hour
+    updated.Include = []string{"go"}
+    updated.Selectors.List = "a.other"
+    got := changedFields(old, updated, []string{"Interval", "Include", "Exclude", "Selectors", "Token"})
+    if strings.Join(got, ",") != "Interval,Include,Selectors" {
+        t.Errorf("changedFields = %q", got)
+    }
+    if got := changedFields(old, defaultConfig(), reloadableFields); len(got) != 0 {
+        t.Errorf("identical configs changed %q", got)
+    }
+}
+
+// TestReloadFieldNames 确保字段列表中的名称都存在，FieldByName 找不到字段时会 panic
+func TestReloadFieldNames(t *testing.T) {
+    cfg := defaultConfig()
+    changedFields(cfg, cfg, reloadableFields)
+    changedFields(cfg, cfg, restartFields)
+}
+
+// TestReloadFieldsCoverConfig 确保每个配置字段要么可以热更新，要么修改后提示需要重启，新增字段时不会被静默忽略
+func TestReloadFieldsCoverConfig(t *testing.T) {
+    // 论坛地址由 forumURLs 单独比较，配置方案逐个比较，其余为只在命令行使用的运行模式
+    exempt := []string{"URLs", "Profiles", "ShowVersion", "SelfCheck", "SelfCheckSend", "ListSelectors", "PrintConfig", "ReplayDeadLetter", "Once"}
+    typ := reflect.TypeOf(Config{})
+    for i := 0; i < typ.NumField(); i++ {
+        name := typ.Field(i).Name
+        n := 0
+        for _, list := range [][]string{reloadableFields, restartFields, exempt} {
+            if slices.Contains(list, name) {
+                n++
+            }
+        }
+        if n != 1 {
+            t.Errorf("field %s is listed %d times, want exactly once in reloadableFields, restartFields or exempt", name, n)
+        }
+    }
+}
+
+func TestLiveSettingsApply(t *testing.T) {
+    notifier, operator := &recordingNotifier{}, &recordingNotifier{}
+    filter, err := newFilter([]string{"go"}, nil, false)
+    if err != nil {
+        t.Fatal(err)
+    }
+    live := &liveSettings{}
+    live.set(time.Minute, time.Second, time.Hour, filter, notifier, operator)
+    cfg := defaultConfig()
+    cfg.MinReplies, cfg.MinAge, cfg.MaxAge, cfg.AgeUnknown, cfg.BatchSort = 3, time.Minute, time.Hour, ageDrop, "replies"
+    live.setPostRules(cfg)
+
+    opts := monitorOptions{Interval: time.Second, Format: formatHTML}
+    live.apply(&opts)
+    if opts.Interval != time.Minute || opts.Jitter != time.Second || opts.Filter != filter ||
+        opts.Notifier != Notifier(notifier) || opts.Operator != Notifier(operator) {
+        t.Errorf("opts = %+v", opts)
+    }
+    if opts.MinReplies != 3 || opts.MinAge != time.Minute || opts.MaxAge != time.Hour || !opts.DropUnknownAge || opts.BatchSort != "replies" {
+        t.Errorf("post rules = %+v", opts)
+    }
+    if opts.Format != formatHTML {
+        t.Error("apply changed a setting that cannot be reloaded")
+    }
+    if interval, n := live.watch(); interval != time.Hour || n != Notifier(notifier) {
+        t.Errorf("watch() = %v, %v", interval, n)
+    }
+
+    var none *liveSettings
+    none.apply(&opts)
+    if opts.Interval != time.Minute {
+        t.Error("nil settings changed the options")
+    }
+}
+
+func TestReloadConfig(t *testing.T) {
+    for _, key := range []string{"TELEGRAM_BOT_TOKEN", "TELEGRAM_CHAT_ID", "YUC_FORUM_USERNAME", "YUC_FORUM_PASSWORD", "YUC_SMTP_PASSWORD"} {
+        t.Setenv(key, "")
+    }
+    path := writeConfig(t, "webhook: http://example.com/hook\ninterval: 1m\n")
+    args := []string{"-config", path}
+    cfg, err := loadConfig(args)
+    if err != nil {
+        t.Fatal(err)
+    }
+    live := &liveSettings{}
+    live.set(cfg.Interval, 0, cfg.WatchInterval, nil, &recordingNotifier{}, nil)
+    running := map[string]*runningProfile{"": {cfg: cfg, live: live}}
+    health := newHealthTracker(nil, 3*time.Minute)
+
+    if err := os.WriteFile(path, []byte("webhook: http://example.com/hook\ninterval: 2m\ninclude: [go]\nmin_replies: 5\n"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    reloadConfig(args, running, nil, health)
+    var opts monitorOptions
+    live.apply(&opts)
+    if opts.Interval != 2*time.Minute || opts.Filter == nil || opts.MinReplies != 5 {
+        t.Fatalf("reloaded options = interval %v, filter %v, min replies %d", opts.Interval, opts.Filter, opts.MinReplies)
+    }
+    if running[""].cfg.Interval != 2*time.Minute {
+        t.Error("running profile config was not replaced")
+    }
+    if health.threshold != 6*time.Minute {
+        t.Errorf("health threshold = %v, want 3 times the new interval", health.threshold)
+    }
+
+    if err := os.WriteFile(path, []byte("webhook: http://example.com/hook\ninterval: 5m\ndedup: title\n"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    reloadConfig(args, running, nil, health)
+    live.apply(&opts)
+    if opts.Interval != 2*time.Minute || running[""].cfg.Interval != 2*time.Minute {
+        t.Errorf("invalid config was applied: interval %v", opts.Interval)
+    }
+}
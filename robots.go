@@ -0,0 +1,231 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "errors"
+    "fmt"
+    "net/url"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// errRobotsDisallowed 表示 robots.txt 不允许抓取该页面
+var errRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+// robotsTTL robots.txt 的缓存时间
+var robotsTTL = 24 * time.Hour
+
+// robotsRules 从 robots.txt 中选出的适用于当前 User-Agent 的规则
+type robotsRules struct {
+    allow      []string
+    disallow   []string
+    crawlDelay time.Duration
+}
+
+// robotsGroup robots.txt 中的一组规则及其适用的 User-Agent
+type robotsGroup struct {
+    agents []string
+    rules  robotsRules
+}
+
+// parseRobots 解析 robots.txt，返回与 userAgent 最匹配的一组规则，没有匹配时允许全部
+func parseRobots(content, userAgent string) *robotsRules {
+    var groups []*robotsGroup
+    var current *robotsGroup
+    lastWasAgent := false
+
+    scanner := bufio.NewScanner(strings.NewReader(content))
+    for scanner.Scan() {
+        line, _, _ := strings.Cut(scanner.Text(), "#")
+        key, value, ok := strings.Cut(line, ":")
+        if !ok {
+            continue
+        }
+        key = strings.ToLower(strings.TrimSpace(key))
+        value = strings.TrimSpace(value)
+
+        switch key {
+        case "user-agent":
+            // 连续的 User-Agent 行属于同一组
+            if current == nil || !lastWasAgent {
+                current = &robotsGroup{}
+                groups = append(groups, current)
+            }
+            current.agents = append(current.agents, strings.ToLower(value))
+            lastWasAgent = true
+            continue
+        case "allow":
+            if current != nil && value != "" {
+                current.rules.allow = append(current.rules.allow, value)
+            }
+        case "disallow":
+            if current != nil && value != "" {
+                current.rules.disallow = append(current.rules.disallow, value)
+            }
+        case "crawl-delay":
+            if seconds, err := strconv.ParseFloat(value, 64); err == nil && current != nil && seconds > 0 {
+                current.rules.crawlDelay = time.Duration(seconds * float64(time.Second))
+            }
+        }
+        lastWasAgent = false
+    }
+
+    // 选择名称最长且包含在 User-Agent 中的组，其次使用 * 组
+    ua := strings.ToLower(userAgent)
+    var best *robotsGroup
+    bestLen := -1
+    for _, g := range groups {
+        for _, agent := range g.agents {
+            n := -1
+            if agent == "*" {
+                n = 0
+            } else if strings.Contains(ua, agent) {
+                n = len(agent)
+            }
+            if n > bestLen {
+                best, bestLen = g, n
+            }
+        }
+    }
+    if best == nil {
+        return &robotsRules{}
+    }
+    return &best.rules
+}
+
+// Allowed 按最长匹配原则判断路径是否允许抓取，长度相同时 Allow 优先
+func (r *robotsRules) Allowed(path string) bool {
+    allowLen, disallowLen := -1, -1
+    for _, pattern := range r.allow {
+        if robotsMatch(pattern, path) && len(pattern) > allowLen {
+            allowLen = len(pattern)
+        }
+    }
+    for _, pattern := range r.disallow {
+        if robotsMatch(pattern, path) && len(pattern) > disallowLen {
+            disallowLen = len(pattern)
+        }
+    }
+    return disallowLen < 0 || allowLen >= disallowLen
+}
+
+// robotsMatch 判断路径是否匹配 robots.txt 规则，支持 * 通配符和表示结尾的 $
+func robotsMatch(pattern, path string) bool {
+    anchored := strings.HasSuffix(pattern, "$")
+    pattern = strings.TrimSuffix(pattern, "$")
+
+    parts := strings.Split(pattern, "*")
+    if !strings.HasPrefix(path, parts[0]) {
+        return false
+    }
+    pos := len(parts[0])
+    for _, part := range parts[1:] {
+        i := strings.Index(path[pos:], part)
+        if i < 0 {
+            return false
+        }
+        pos += i + len(part)
+    }
+    if !anchored {
+        return true
+    }
+    // 以 $ 结尾时最后一段必须位于路径末尾
+    last := parts[len(parts)-1]
+    return pos == len(path) || (len(parts) > 1 && strings.HasSuffix(path, last))
+}
+
+// robotsEntry 单个站点缓存的 robots.txt 规则和最近一次请求时间
+type robotsEntry struct {
+    rules       *robotsRules
+    fetchedAt   time.Time
+    lastRequest time.Time
+}
+
+// robotsCache 按站点缓存 robots.txt，并按 Crawl-delay 控制请求间隔
+type robotsCache struct {
+    mu    sync.Mutex
+    hosts map[string]*robotsEntry
+}
+
+// robotsPolicy 全局的 robots.txt 策略，为 nil 时不检查 robots.txt
+var robotsPolicy *robotsCache
+
+// newRobotsCache 创建空的 robots.txt 缓存
+func newRobotsCache() *robotsCache {
+    return &robotsCache{hosts: make(map[string]*robotsEntry)}
+}
+
+// Wait 检查页面是否允许抓取，并在需要时等待 Crawl-delay。c 为 nil 时直接放行
+func (c *robotsCache) Wait(ctx context.Context, pageURL, userAgent string) error {
+    if c == nil {
+        return nil
+    }
+    u, err := url.Parse(pageURL)
+    if err != nil {
+        return err
+    }
+
+    entry, err := c.entry(ctx, u, userAgent)
+    if err != nil {
+        return err
+    }
+
+    path := u.EscapedPath()
+    if path == "" {
+        path = "/"
+    }
+    if u.RawQuery != "" {
+        path += "?" + u.RawQuery
+    }
+    if !entry.rules.Allowed(path) {
+        return fmt.Errorf("%s: %w", pageURL, errRobotsDisallowed)
+    }
+
+    // 预约下一次请求的时间，并发请求会依次排队
+    c.mu.Lock()
+    wait := time.Until(entry.lastRequest.Add(entry.rules.crawlDelay))
+    if wait < 0 {
+        wait = 0
+    }
+    entry.lastRequest = time.Now().Add(wait)
+    c.mu.Unlock()
+
+    if wait > 0 && !sleepContext(ctx, wait) {
+        return ctx.Err()
+    }
+    return nil
+}
+
+// entry 返回站点的缓存规则，过期或不存在时重新获取 robots.txt
+func (c *robotsCache) entry(ctx context.Context, u *url.URL, userAgent string) (*robotsEntry, error) {
+    host := u.Scheme + "://" + u.Host
+
+    c.mu.Lock()
+    entry := c.hosts[host]
+    c.mu.Unlock()
+    if entry != nil && time.Since(entry.fetchedAt) < robotsTTL {
+        return entry, nil
+    }
+
+    // robots.txt 不存在或获取失败时视为允许全部，避免因此停止监控
+    rules := &robotsRules{}
+    robotsPage, err := doFetch(host+"/robots.txt", userAgent, fetchTimeout, page{}, requestSpec{})
+    if err == nil {
+        rules = parseRobots(robotsPage.Content, userAgent)
+    } else if ctx.Err() != nil {
+        return nil, ctx.Err()
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if entry == nil {
+        entry = &robotsEntry{}
+        c.hosts[host] = entry
+    }
+    entry.rules = rules
+    entry.fetchedAt = time.Now()
+    return entry, nil
+}
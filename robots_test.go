@@ -0,0 +1,167 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestParseRobotsSelectsGroup(t *testing.T) {
+    const robots = `# comment
+User-agent: *
+Disallow: /private
+Crawl-delay: 2
+
+User-agent: yuc
+User-agent: other
+Disallow: /forum.php?mod=post
+Allow: /private/open
+Crawl-delay: 0.5
+
+User-agent: yuc-strict
+Disallow: /
+`
+    tests := []struct {
+        ua       string
+        disallow []string
+        delay    time.Duration
+    }{
+        {"Mozilla/5.0", []string{"/private"}, 2 * time.Second},
+        {"yuc/1.0", []string{"/forum.php?mod=post"}, 500 * time.Millisecond},
+        {"OTHER-bot", []string{"/forum.php?mod=post"}, 500 * time.Millisecond},
+        {"yuc-strict/1.0", []string{"/"}, 0},
+    }
+    for _, tt := range tests {
+        rules := parseRobots(robots, tt.ua)
+        if fmt.Sprint(rules.disallow) != fmt.Sprint(tt.disallow) || rules.crawlDelay != tt.delay {
+            t.Errorf("parseRobots(%q) = disallow %q, delay %v, want %q, %v", tt.ua, rules.disallow, rules.crawlDelay, tt.disallow, tt.delay)
+        }
+    }
+
+    if rules := parseRobots("User-agent: googlebot\nDisallow: /\n", "yuc"); !rules.Allowed("/anything") {
+        t.Error("rules for another crawler applied")
+    }
+    if rules := parseRobots("Disallow: /\n", "yuc"); !rules.Allowed("/anything") {
+        t.Error("rule outside any group applied")
+    }
+}
+
+func TestRobotsRulesAllowed(t *testing.T) {
+    rules := &robotsRules{
+        allow:    []string{"/private/open", "/*.css$", "/forum.php?mod=viewthread"},
+        disallow: []string{"/private", "/forum.php", "/*.php$", "/tmp/"},
+    }
+    tests := []struct {
+        path string
+        want bool
+    }{
+        {"/", true},
+        {"/private", false},
+        {"/private/secret", false},
+        {"/private/open/file", true},
+        {"/static/site.css", true},
+        {"/static/site.css?v=1", true},
+        {"/forum.php?mod=forumdisplay", false},
+        {"/forum.php?mod=viewthread&tid=1", true},
+        {"/index.php", false},
+        {"/index.php?x=1", true},
+        {"/tmp", true},
+        {"/tmp/a", false},
+    }
+    for _, tt := range tests {
+        if got := rules.Allowed(tt.path); got != tt.want {
+            t.Errorf("Allowed(%q) = %v, want %v", tt.path, got, tt.want)
+        }
+    }
+}
+
+func TestRobotsMatch(t *testing.T) {
+    tests := []struct {
+        pattern, path string
+        want          bool
+    }{
+        {"/a", "/a", true},
+        {"/a", "/abc", true},
+        {"/a", "/b", false},
+        {"/a$", "/a", true},
+        {"/a$", "/ab", false},
+        {"/*.html", "/x/y.html", true},
+        {"/*.html$", "/x/y.html?z", false},
+        {"/x/*/z", "/x/y/z", true},
+        {"/x/*/z", "/x/y", false},
+        {"*", "/anything", true},
+    }
+    for _, tt := range tests {
+        if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+            t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+        }
+    }
+}
+
+func TestRobotsCacheWait(t *testing.T) {
+    var robotsRequests atomic.Int32
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/robots.txt" {
+            robotsRequests.Add(1)
+            fmt.Fprint(w, "User-agent: *\nDisallow: /admin\nCrawl-delay: 0.05\n")
+            return
+        }
+        fmt.Fprint(w, "ok")
+    })
+    c := newRobotsCache()
+    ctx := context.Background()
+
+    if err := c.Wait(ctx, srv.URL+"/admin/panel", "yuc"); !errors.Is(err, errRobotsDisallowed) {
+        t.Fatalf("Wait(/admin) = %v, want errRobotsDisallowed", err)
+    }
+    start := time.Now()
+    for i := 0; i < 3; i++ {
+        if err := c.Wait(ctx, srv.URL+"/forum.php", "yuc"); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+        t.Errorf("3 requests took %v, want crawl delay respected", elapsed)
+    }
+    if n := robotsRequests.Load(); n != 1 {
+        t.Errorf("robots.txt requested %d times, want cached", n)
+    }
+}
+
+func TestRobotsCacheMissingAllowsAll(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        http.NotFound(w, r)
+    })
+    if err := newRobotsCache().Wait(context.Background(), srv.URL+"/admin", "yuc"); err != nil {
+        t.Errorf("Wait() = %v, want allowed without robots.txt", err)
+    }
+    var none *robotsCache
+    if err := none.Wait(context.Background(), srv.URL+"/admin", "yuc"); err != nil {
+        t.Errorf("nil cache Wait() = %v", err)
+    }
+}
+
+func TestFetchHonorsRobots(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/robots.txt" {
+            fmt.Fprint(w, "User-agent: *\nDisallow: /forum.php?mod=post\n")
+            return
+        }
+        fmt.Fprint(w, "ok")
+    })
+    saved := robotsPolicy
+    t.Cleanup(func() { robotsPolicy = saved })
+    robotsPolicy = newRobotsCache()
+
+    fetcher := FastHTTPFetcher{UserAgent: "yuc", Attempts: 1}
+    if _, err := fetcher.Fetch(context.Background(), srv.URL+"/forum.php?mod=post&fid=1"); !errors.Is(err, errRobotsDisallowed) {
+        t.Errorf("disallowed fetch = %v", err)
+    }
+    if content, err := fetcher.Fetch(context.Background(), srv.URL+"/forum.php?mod=viewthread"); err != nil || content != "ok" {
+        t.Errorf("allowed fetch = %q, %v", content, err)
+    }
+}
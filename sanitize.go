@@ -0,0 +1,247 @@
+package main
+
+import (
+    "net/url"
+    "strings"
+
+    "github.com/PuerkitoBio/goquery"
+    "golang.org/x/net/html"
+)
+
+// formatHTML 帖子内容保留为 Telegram 支持的 HTML 标签
+const formatHTML = "html"
+
+// telegramTags Telegram HTML 消息支持的标签，键为常见的同义标签，值为输出时使用的标签
+var telegramTags = map[string]string{
+    "b": "b", "strong": "b",
+    "i": "i", "em": "i",
+    "u": "u", "ins": "u",
+    "s": "s", "strike": "s", "del": "s",
+    "a":    "a",
+    "code": "code",
+    "pre":  "pre",
+}
+
+// hiddenTags 清理时连同内容一起丢弃的标签
+var hiddenTags = map[string]bool{
+    "script": true, "style": true, "noscript": true, "iframe": true, "object": true,
+    "svg": true, "select": true, "textarea": true, "template": true,
+}
+
+// sanitizeTelegramHTML 将任意 HTML 转换为 Telegram 能解析的 HTML：只保留 telegramTags 中的标签，
+// a 只保留 http/https 链接的 href，其它标签去掉只留文本，script/style 连同内容丢弃，
+// 文本中的 <、>、& 全部转义，未闭合的标签在末尾补齐，多余的结束标签丢弃。空白原样保留
+func sanitizeTelegramHTML(s string) string {
+    var b strings.Builder
+    var open []string
+    skip := ""
+    z := html.NewTokenizer(strings.NewReader(s))
+    for {
+        tt := z.Next()
+        if tt == html.ErrorToken {
+            break
+        }
+        token := z.Token()
+        if skip != "" {
+            if tt == html.EndTagToken && token.Data == skip {
+                skip = ""
+            }
+            continue
+        }
+
+        switch tt {
+        case html.TextToken:
+            b.WriteString(html.EscapeString(token.Data))
+        case html.StartTagToken, html.SelfClosingTagToken:
+            if token.Data == "br" {
+                b.WriteString("\n")
+                continue
+            }
+            if hiddenTags[token.Data] {
+                if tt == html.StartTagToken {
+                    skip = token.Data
+                }
+                continue
+            }
+            tag, ok := telegramTags[token.Data]
+            if !ok || tt == html.SelfClosingTagToken || !canNest(open, tag) {
+                continue
+            }
+            if tag == "a" {
+                href := resolveMarkdownLink(nil, attrValue(token, "href"))
+                if href == "" {
+                    continue
+                }
+                b.WriteString(`<a href="` + html.EscapeString(href) + `">`)
+            } else {
+                b.WriteString("<" + tag + ">")
+            }
+            open = append(open, tag)
+        case html.EndTagToken:
+            tag, ok := telegramTags[token.Data]
+            if !ok {
+                continue
+            }
+            // 结束标签对应的开始标签被丢弃时忽略；否则先关闭其内部尚未闭合的标签
+            i := len(open) - 1
+            for i >= 0 && open[i] != tag {
+                i--
+            }
+            if i < 0 {
+                continue
+            }
+            for j := len(open) - 1; j >= i; j-- {
+                b.WriteString("</" + open[j] + ">")
+            }
+            open = open[:i]
+        }
+    }
+    for j := len(open) - 1; j >= 0; j-- {
+        b.WriteString("</" + open[j] + ">")
+    }
+    return b.String()
+}
+
+// canNest 判断 tag 能否嵌套在 open 中：pre 和 code 内只允许 pre 直接包含 code，链接不能嵌套链接，同一标签不重复嵌套
+func canNest(open []string, tag string) bool {
+    for _, t := range open {
+        if t == tag {
+            return false
+        }
+    }
+    if len(open) == 0 {
+        return true
+    }
+    switch open[len(open)-1] {
+    case "pre":
+        return tag == "code"
+    case "code":
+        return false
+    }
+    for _, t := range open {
+        if t == "pre" || t == "code" {
+            return false
+        }
+    }
+    return true
+}
+
+// attrValue 返回 token 的属性值
+func attrValue(t html.Token, key string) string {
+    for _, a := range t.Attr {
+        if a.Key == key {
+            return a.Val
+        }
+    }
+    return ""
+}
+
+// htmlToTelegram 将帖子正文转换为 Telegram HTML，保留段落、换行、加粗、斜体、链接和代码，相对链接基于 base 解析
+func htmlToTelegram(sel *goquery.Selection, base *url.URL) string {
+    var b strings.Builder
+    for _, node := range sel.Nodes {
+        for child := node.FirstChild; child != nil; child = child.NextSibling {
+            writeTelegramHTML(&b, child, base)
+        }
+    }
+
+    // 去掉 pre 以外每行首尾的空白并合并多余的空行，pre 内的文本已转义，不会出现 <pre> 字样
+    parts := strings.Split(b.String(), "<pre>")
+    for i, part := range parts {
+        code, rest := "", part
+        if i > 0 {
+            if c, r, ok := strings.Cut(part, "</pre>"); ok {
+                code, rest = c+"</pre>", r
+            }
+        }
+        lines := strings.Split(rest, "\n")
+        for j, line := range lines {
+            lines[j] = strings.TrimSpace(line)
+        }
+        parts[i] = code + blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+    }
+    return sanitizeTelegramHTML(strings.TrimSpace(strings.Join(parts, "<pre>")))
+}
+
+// writeTelegramHTML 递归输出单个节点
+func writeTelegramHTML(b *strings.Builder, n *html.Node, base *url.URL) {
+    switch n.Type {
+    case html.TextNode:
+        b.WriteString(html.EscapeString(collapseSpaces(n.Data)))
+        return
+    case html.ElementNode:
+    default:
+        return
+    }
+
+    tag := n.Data
+    if droppedTags[tag] {
+        return
+    }
+    children := func() {
+        for child := n.FirstChild; child != nil; child = child.NextSibling {
+            writeTelegramHTML(b, child, base)
+        }
+    }
+
+    switch tag {
+    case "br":
+        b.WriteString("\n")
+    case "code":
+        b.WriteString("<code>" + html.EscapeString(textContent(n)) + "</code>")
+    case "pre":
+        b.WriteString("\n\n<pre>" + html.EscapeString(strings.Trim(textContent(n), "\n")) + "</pre>\n\n")
+    case "a":
+        link := resolveMarkdownLink(base, attr(n, "href"))
+        if link == "" {
+            children()
+            return
+        }
+        b.WriteString(`<a href="` + html.EscapeString(link) + `">`)
+        children()
+        b.WriteString("</a>")
+    case "li":
+        b.WriteString("\n• ")
+        children()
+    case "h1", "h2", "h3", "h4", "h5", "h6":
+        b.WriteString("\n\n<b>")
+        children()
+        b.WriteString("</b>\n\n")
+    default:
+        if t, ok := telegramTags[tag]; ok {
+            b.WriteString("<" + t + ">")
+            children()
+            b.WriteString("</" + t + ">")
+            return
+        }
+        if blockTags[tag] || tag == "ul" || tag == "ol" || tag == "blockquote" {
+            b.WriteString("\n\n")
+            children()
+            b.WriteString("\n\n")
+            return
+        }
+        children()
+    }
+}
+
+// htmlText 返回 HTML 片段去掉标签后的文本，用于在非 HTML 消息中显示 html 格式的正文
+func htmlText(s string) string {
+    doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+    if err != nil {
+        return s
+    }
+    return doc.Text()
+}
+
+// plainMessage 返回帖子正文不含 Telegram 格式的文本：html 格式去掉标签，markdown 格式去掉 MarkdownV2 转义符，
+// 用于 Discord、Webhook 等不使用 Telegram 格式的渠道
+func plainMessage(p Post) string {
+    switch p.Format {
+    case formatHTML:
+        return htmlText(p.Message)
+    case formatMarkdown:
+        return markdownV2Text(p.Message)
+    default:
+        return p.Message
+    }
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestSanitizeTelegramHTML(t *testing.T) {
+    tests := []struct {
+        name string
+        in   string
+        want string
+    }{
+        {"keeps supported tags", "<b>a</b><i>b</i><u>c</u><s>d</s>", "<b>a</b><i>b</i><u>c</u><s>d</s>"},
+        {"maps synonyms", "<strong>a</strong><em>b</em><del>c</del>", "<b>a</b><i>b</i><s>c</s>"},
+        {"strips unknown tags", `<div class="x"><span>text</span></div>`, "text"},
+        {"drops script content", "a<script>alert(1)</script>b", "ab"},
+        {"escapes text", "1 < 2 & 3 > 2", "1 &lt; 2 &amp; 3 &gt; 2"},
+        {"closes unclosed tags", "<b><i>x", "<b><i>x</i></b>"},
+        {"drops stray end tags", "x</b>", "x"},
+        {"closes inner tags first", "<b><i>x</b>y", "<b><i>x</i></b>y"},
+        {"safe links only", `<a href="javascript:x">a</a><a href="https://a.com/?a=1&b=2">b</a>`, `a<a href="https://a.com/?a=1&amp;b=2">b</a>`},
+        {"no nested links", `<a href="https://a.com">x<a href="https://b.com">y</a></a>`, `<a href="https://a.com">xy</a>`},
+        {"code only inside pre", "<pre><code>x</code><b>y</b></pre>", "<pre><code>x</code>y</pre>"},
+        {"br becomes newline", "a<br>b<br/>c", "a\nb\nc"},
+        {"truncated tag", "text<b", "text"},
+    }
+    for _, tt := range tests {
+        if got := sanitizeTelegramHTML(tt.in); got != tt.want {
+            t.Errorf("%s: sanitizeTelegramHTML(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+        }
+    }
+}
+
+func TestHTMLToTelegram(t *testing.T) {
+    tests := []struct {
+        name string
+        html string
+        want string
+    }{
+        {"inline tags", "<strong>粗</strong> <em>斜</em> <span>普通</span>", "<b>粗</b> <i>斜</i> 普通"},
+        {"relative link", `<a href="t.html">帖子</a>`, `<a href="https://fishc.com.cn/t.html">帖子</a>`},
+        {"list", "<ul><li>一</li><li>二</li></ul>", "• 一\n• 二"},
+        {"heading", "<h3>标题</h3>正文", "<b>标题</b>\n\n正文"},
+        {"pre keeps spacing", "<pre>  a < b\n    c</pre>", "<pre>  a &lt; b\n    c</pre>"},
+        {"escapes text", "a & b", "a &amp; b"},
+    }
+    for _, tt := range tests {
+        if got := convertFragment(t, tt.html, htmlToTelegram); got != tt.want {
+            t.Errorf("%s: htmlToTelegram = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestPlainMessage(t *testing.T) {
+    tests := []struct {
+        name string
+        post Post
+        want string
+    }{
+        {"plain", Post{Message: "<b>literal</b>"}, "<b>literal</b>"},
+        {"html", Post{Message: "<b>粗</b> &amp; <a href=\"https://a.com\">链接</a>", Format: formatHTML}, "粗 & 链接"},
+        {"markdown", Post{Message: `*粗* 1\+1`, Format: formatMarkdown}, "*粗* 1+1"},
+    }
+    for _, tt := range tests {
+        if got := plainMessage(tt.post); got != tt.want {
+            t.Errorf("%s: plainMessage = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/PuerkitoBio/goquery"
+)
+
+// selfCheckTimeout 自检整体的超时时间
+const selfCheckTimeout = 2 * time.Minute
+
+// selfChecker 逐项执行自检并把结果写到 w，记录是否有失败项
+type selfChecker struct {
+    w      io.Writer
+    failed int
+}
+
+// pass 输出一条通过的检查项
+func (c *selfChecker) pass(name, format string, args ...any) {
+    fmt.Fprintf(c.w, "[PASS] %s: %s\n", name, fmt.Sprintf(format, args...))
+}
+
+// fail 输出一条失败的检查项
+func (c *selfChecker) fail(name string, err error) {
+    c.failed++
+    fmt.Fprintf(c.w, "[FAIL] %s: %v\n", name, err)
+}
+
+// runSelfCheck 依次检查 Telegram 机器人令牌、通知渠道和各论坛页面的抓取解析，
+// 配置本身已由 Validate 检查过。sendTest 为 true 时通过配置的通知渠道发送一条测试消息。
+// 全部通过时返回 true
+func runSelfCheck(ctx context.Context, cfg *Config, sendTest bool, w io.Writer) bool {
+    ctx, cancel := context.WithTimeout(ctx, selfCheckTimeout)
+    defer cancel()
+
+    c := &selfChecker{w: w}
+    c.pass("config", "配置有效")
+
+    if cfg.Token != "" && !cfg.DryRun {
+        if username, err := telegramGetMe(ctx, cfg.Token); err != nil {
+            c.fail("telegram", err)
+        } else {
+            c.pass("telegram", "令牌有效，机器人为 @%s", username)
+        }
+    }
+
+    if sendTest {
+        notifier, err := buildNotifier(cfg)
+        if err == nil {
+            err = notifier.Notify(ctx, Post{
+                URL:     cfg.forumLink(),
+                Title:   "yuc 自检测试消息",
+                Message: "收到这条消息说明通知渠道配置正确",
+            })
+        }
+        if err != nil {
+            c.fail("notify", err)
+        } else {
+            c.pass("notify", "测试消息已发送")
+        }
+    }
+
+    fetcher, err := newFetcher(cfg)
+    if err != nil {
+        c.fail("fetcher", err)
+    } else {
+        for _, forum := range cfg.forums() {
+            c.checkForum(ctx, cfg, fetcher, forum)
+        }
+    }
+
+    if c.failed > 0 {
+        fmt.Fprintf(w, "自检失败: %d 项未通过\n", c.failed)
+        return false
+    }
+    fmt.Fprintln(w, "自检通过")
+    return true
+}
+
+// checkForum 抓取并解析论坛列表页，再解析其中第一个帖子，输出找到的内容
+func (c *selfChecker) checkForum(ctx context.Context, cfg *Config, fetcher Fetcher, forum ForumConfig) {
+    name := "forum " + forum.URL
+    fetched, err := fetchPage(ctx, fetcher, forum.URL, page{}, cfg.listRequest())
+    if err != nil {
+        c.fail(name, err)
+        return
+    }
+    posts, selector, err := parseListPosts(fetched.Content, fetched.URL, forum.Selectors)
+    if err != nil {
+        c.fail(name, err)
+        return
+    }
+    if len(posts) == 0 {
+        c.fail(name, fmt.Errorf("list selectors %q matched no posts", forum.Selectors.lists()))
+        return
+    }
+    c.pass(name, "列表选择器 %q 找到 %d 个帖子", selector, len(posts))
+    for _, p := range posts[:min(len(posts), 5)] {
+        fmt.Fprintf(c.w, "       - %s %s\n", p.Title, p.URL)
+    }
+
+    post, err := parsePostContent(ctx, fetcher, posts[0].URL, forum.Selectors, cfg.Format)
+    switch {
+    case err != nil:
+        c.fail(name, err)
+    case post.Title == "":
+        c.fail(name, fmt.Errorf("title selector %q matched nothing on %s", forum.Selectors.Title, post.URL))
+    case post.Message == "" || post.Message == msg("content.missing"):
+        c.fail(name, fmt.Errorf("message selector %q matched nothing on %s", forum.Selectors.Message, post.URL))
+    default:
+        c.pass(name, "帖子《%s》内容 %d 字", post.Title, len([]rune(post.Message)))
+    }
+}
+
+// telegramGetMe 调用 getMe 检查机器人令牌，返回机器人的用户名
+func telegramGetMe(ctx context.Context, botToken string) (string, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, telegramAPIURL(botToken, "getMe"), nil)
+    if err != nil {
+        return "", err
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+
+    var result struct {
+        telegramResponse
+        Result struct {
+            Username string `json:"username"`
+        } `json:"result"`
+    }
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+    _ = json.Unmarshal(body, &result)
+    if resp.StatusCode != http.StatusOK || !result.OK {
+        return "", &telegramAPIError{
+            Method:      "getMe",
+            StatusCode:  resp.StatusCode,
+            ErrorCode:   result.ErrorCode,
+            Description: result.Description,
+        }
+    }
+    return result.Result.Username, nil
+}
+
+// selectorSnippets 每个选择器最多显示的匹配元素数量和每段文本的最大字符数
+const (
+    selectorSnippets   = 3
+    selectorSnippetLen = 60
+)
+
+// listSelectors 抓取每个论坛的列表页和其中第一个帖子，输出各选择器匹配的元素数量和前几段文本，
+// 列表选择器在列表页上匹配，标题和正文选择器在帖子页上匹配
+func listSelectors(ctx context.Context, cfg *Config, fetcher Fetcher, w io.Writer) error {
+    for _, forum := range cfg.forums() {
+        fmt.Fprintf(w, "%s\n", forum.URL)
+        fetched, err := fetchPage(ctx, fetcher, forum.URL, page{}, cfg.listRequest())
+        if err != nil {
+            return err
+        }
+        doc, err := goquery.NewDocumentFromReader(strings.NewReader(fetched.Content))
+        if err != nil {
+            return fmt.Errorf("parse html: %w", err)
+        }
+        for _, sel := range forum.Selectors.lists() {
+            printSelector(w, "list", sel, doc.Find(sel))
+        }
+
+        posts, _, err := parseListPosts(fetched.Content, fetched.URL, forum.Selectors)
+        if err != nil {
+            return err
+        }
+        if len(posts) == 0 {
+            fmt.Fprintln(w, "  列表选择器没有匹配到帖子，跳过帖子页")
+            continue
+        }
+        fetched, err = fetchPage(ctx, fetcher, posts[0].URL, page{}, requestSpec{})
+        if err != nil {
+            return err
+        }
+        doc, err = goquery.NewDocumentFromReader(strings.NewReader(fetched.Content))
+        if err != nil {
+            return fmt.Errorf("parse html: %w", err)
+        }
+        fmt.Fprintf(w, "%s\n", fetched.URL)
+        printSelector(w, "title", forum.Selectors.Title, doc.Find(forum.Selectors.Title))
+        printSelector(w, "message", forum.Selectors.Message, doc.Find(forum.Selectors.Message))
+    }
+    return nil
+}
+
+// printSelector 输出选择器匹配的元素数量和前 selectorSnippets 个元素的文本
+func printSelector(w io.Writer, name, selector string, sel *goquery.Selection) {
+    fmt.Fprintf(w, "  %s %q: %d 个\n", name, selector, sel.Length())
+    sel.Slice(0, min(sel.Length(), selectorSnippets)).Each(func(i int, s *goquery.Selection) {
+        text := []rune(strings.Join(strings.Fields(s.Text()), " "))
+        if len(text) > selectorSnippetLen {
+            text = append(text[:selectorSnippetLen], '…')
+        }
+        fmt.Fprintf(w, "    %d. %s\n", i+1, string(text))
+    })
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strings"
+    "testing"
+)
+
+// newTestForum 启动只有一个列表页和一个帖子页的论坛，post 为空时帖子页没有正文
+func newTestForum(t *testing.T, post string) string {
+    t.Helper()
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/forum.php":
+            fmt.Fprint(w, listPage(items("1", "2")...))
+        case "/thread-1.html":
+            fmt.Fprint(w, post)
+        default:
+            http.NotFound(w, r)
+        }
+    })
+    return srv.URL + "/forum.php"
+}
+
+// selfCheckConfig 返回检查 forumURL 的配置
+func selfCheckConfig(forumURL string) *Config {
+    cfg := defaultConfig()
+    cfg.Retries = 1
+    cfg.Forums = []ForumConfig{{URL: forumURL, Selectors: testSelectors}}
+    cfg.Selectors = testSelectors
+    return cfg
+}
+
+func TestRunSelfCheckPasses(t *testing.T) {
+    tg := newFakeTelegram(t)
+    tg.respond = func(w http.ResponseWriter, call telegramCall, _ int) bool {
+        if call.Method == "getMe" {
+            fmt.Fprint(w, `{"ok":true,"result":{"username":"yuc_bot"}}`)
+            return true
+        }
+        return false
+    }
+    cfg := selfCheckConfig(newTestForum(t, postPage("第一帖", "正文内容")))
+    cfg.Token = "token"
+    cfg.ChatIDs = []string{"1"}
+
+    var out strings.Builder
+    if !runSelfCheck(context.Background(), cfg, true, &out) {
+        t.Fatalf("self check failed:\n%s", out.String())
+    }
+    for _, want := range []string{
+        "[PASS] telegram: 令牌有效，机器人为 @yuc_bot",
+        "[PASS] notify: 测试消息已发送",
+        `列表选择器 "a.xst" 找到 2 个帖子`,
+        "帖子《第一帖》内容 4 字",
+        "自检通过",
+    } {
+        if !strings.Contains(out.String(), want) {
+            t.Errorf("output is missing %q:\n%s", want, out.String())
+        }
+    }
+    if got := strings.Join(tg.methods(), ","); got != "getMe,sendMessage" {
+        t.Errorf("telegram methods = %q", got)
+    }
+}
+
+func TestRunSelfCheckFailures(t *testing.T) {
+    tg := newFakeTelegram(t)
+    tg.respond = func(w http.ResponseWriter, call telegramCall, _ int) bool {
+        w.WriteHeader(http.StatusUnauthorized)
+        fmt.Fprint(w, `{"ok":false,"error_code":401,"description":"Unauthorized"}`)
+        return true
+    }
+    cfg := selfCheckConfig(newTestForum(t, "<h1>标题</h1>"))
+    cfg.Token = "token"
+    cfg.ChatIDs = []string{"1"}
+
+    var out strings.Builder
+    if runSelfCheck(context.Background(), cfg, false, &out) {
+        t.Fatalf("self check passed:\n%s", out.String())
+    }
+    for _, want := range []string{"[FAIL] telegram:", "Unauthorized", `message selector ".message" matched nothing`, "自检失败: 2 项未通过"} {
+        if !strings.Contains(out.String(), want) {
+            t.Errorf("output is missing %q:\n%s", want, out.String())
+        }
+    }
+}
+
+func TestRunSelfCheckNoPosts(t *testing.T) {
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        fmt.Fprint(w, "<p>nothing here</p>")
+    })
+    cfg := selfCheckConfig(srv.URL + "/")
+    cfg.DryRun = true
+
+    var out strings.Builder
+    if runSelfCheck(context.Background(), cfg, false, &out) {
+        t.Fatal("self check passed without posts")
+    }
+    if !strings.Contains(out.String(), "matched no posts") {
+        t.Errorf("output:\n%s", out.String())
+    }
+}
+
+func TestListSelectors(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(testForumURL, listPage(items("1", "2", "3", "4")...))
+    fetcher.set(postURL("1"), postPage("第一帖", strings.Repeat("长", 80)))
+    cfg := selfCheckConfig(testForumURL)
+
+    var out strings.Builder
+    if err := listSelectors(context.Background(), cfg, fetcher, &out); err != nil {
+        t.Fatal(err)
+    }
+    for _, want := range []string{
+        testForumURL + "\n",
+        `  list "a.xst": 4 个`,
+        "    3. 3\n",
+        postURL("1") + "\n",
+        `  title "h1": 1 个`,
+        "    1. " + strings.Repeat("长", selectorSnippetLen) + "…",
+    } {
+        if !strings.Contains(out.String(), want) {
+            t.Errorf("output is missing %q:\n%s", want, out.String())
+        }
+    }
+    if strings.Contains(out.String(), "    4. ") {
+        t.Errorf("output shows more than %d matches:\n%s", selectorSnippets, out.String())
+    }
+
+    fetcher.set(testForumURL, "<p>empty</p>")
+    out.Reset()
+    if err := listSelectors(context.Background(), cfg, fetcher, &out); err != nil {
+        t.Fatal(err)
+    }
+    if !strings.Contains(out.String(), "列表选择器没有匹配到帖子") {
+        t.Errorf("output:\n%s", out.String())
+    }
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+    "unicode/utf8"
+)
+
+// Slack Block Kit 的长度限制：header 文本最多 150 个字符，section 文本最多 3000 个字符，每条消息最多 50 个 block
+const (
+    slackHeaderLimit  = 150
+    slackSectionLimit = 3000
+    slackMaxBlocks    = 50
+)
+
+// slackMessageInterval 拆分发送时相邻两条消息的间隔，Slack Webhook 限制每秒约一条消息
+const slackMessageInterval = time.Second
+
+// slackReplacer 转义 Slack mrkdwn 中的控制字符
+var slackReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// SlackNotifier 通过 Slack Incoming Webhook 推送帖子，使用 Block Kit 排版
+type SlackNotifier struct {
+    WebhookURL string
+}
+
+// slackText Block Kit 的文本对象
+type slackText struct {
+    Type string `json:"type"`
+    Text string `json:"text"`
+}
+
+// slackButton section 右侧的链接按钮
+type slackButton struct {
+    Type string    `json:"type"`
+    Text slackText `json:"text"`
+    URL  string    `json:"url"`
+}
+
+// slackBlock Block Kit 的 block，只用到 header、section 和 divider
+type slackBlock struct {
+    Type      string       `json:"type"`
+    Text      *slackText   `json:"text,omitempty"`
+    Accessory *slackButton `json:"accessory,omitempty"`
+}
+
+// slackPayload Slack Webhook 的请求体，text 用于通知提醒和不支持 blocks 的客户端
+type slackPayload struct {
+    Text   string       `json:"text"`
+    Blocks []slackBlock `json:"blocks"`
+}
+
+// Notify 格式化帖子并发送到 Slack，block 超过数量限制时拆分为多条
+func (n *SlackNotifier) Notify(ctx context.Context, p Post) error {
+    return n.sendBlocks(ctx, p.Title, slackBlocks(p))
+}
+
+// NotifyBatch 将多个帖子合并发送，帖子之间用分隔线隔开，block 超过数量限制时拆分为多条
+func (n *SlackNotifier) NotifyBatch(ctx context.Context, posts []Post) error {
+    var blocks []slackBlock
+    titles := make([]string, len(posts))
+    for i, p := range posts {
+        if i > 0 {
+            blocks = append(blocks, slackBlock{Type: "divider"})
+        }
+        blocks = append(blocks, slackBlocks(p)...)
+        titles[i] = p.Title
+    }
+    return n.sendBlocks(ctx, strings.Join(titles, "\n"), blocks)
+}
+
+// sendBlocks 按每条消息的 block 数量限制拆分并依次发送，被限流时按 Retry-After 等待后重试
+func (n *SlackNotifier) sendBlocks(ctx context.Context, text string, blocks []slackBlock) error {
+    for first := true; len(blocks) > 0; first = false {
+        if !first && !sleepContext(ctx, slackMessageInterval) {
+            return ctx.Err()
+        }
+        part := blocks[:min(len(blocks), slackMaxBlocks)]
+        blocks = blocks[len(part):]
+        payload := slackPayload{Text: truncateRunes(text, slackSectionLimit), Blocks: part}
+        err := retryNotify(ctx, "Slack", func() (time.Duration, bool, error) {
+            return n.send(ctx, payload)
+        })
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// slackBlocks 把帖子格式化为 block：标题作为 header，链接按钮和作者、时间放在一个 section 中，
+// 正文按长度限制拆分为多个 section
+func slackBlocks(p Post) []slackBlock {
+    blocks := []slackBlock{{
+        Type: "header",
+        Text: &slackText{Type: "plain_text", Text: truncateRunes(p.Title, slackHeaderLimit)},
+    }}
+
+    info := fmt.Sprintf("<%s|%s>", p.URL, slackReplacer.Replace(p.URL))
+    if p.Author != "" {
+        info += fmt.Sprintf("\n*%s*: %s", msg("label.author"), slackReplacer.Replace(p.Author))
+    }
+    if p.Time != "" {
+        info += fmt.Sprintf("\n*%s*: %s", msg("label.time"), slackReplacer.Replace(p.Time))
+    }
+    blocks = append(blocks, slackBlock{
+        Type: "section",
+        Text: &slackText{Type: "mrkdwn", Text: truncateRunes(info, slackSectionLimit)},
+        Accessory: &slackButton{
+            Type: "button",
+            Text: slackText{Type: "plain_text", Text: msg("label.link")},
+            URL:  p.URL,
+        },
+    })
+
+    for _, part := range splitMessage(slackReplacer.Replace(plainMessage(p)), slackSectionLimit) {
+        if strings.TrimSpace(part) == "" {
+            continue
+        }
+        blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: part}})
+    }
+    return blocks
+}
+
+// truncateRunes 把 s 截断为最多 limit 个字符，截断时以省略号结尾
+func truncateRunes(s string, limit int) string {
+    if utf8.RuneCountInString(s) <= limit {
+        return s
+    }
+    return string([]rune(s)[:limit-1]) + "…"
+}
+
+// send 发送单条消息，返回建议的等待时间以及该错误是否值得重试
+func (n *SlackNotifier) send(ctx context.Context, payload slackPayload) (time.Duration, bool, error) {
+    resp, err := postJSON(ctx, n.WebhookURL, nil, payload)
+    if err != nil {
+        return 0, true, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+        return 0, false, nil
+    }
+
+    err = fmt.Errorf("failed to send message to Slack, status code: %d", resp.StatusCode)
+    if resp.StatusCode == http.StatusTooManyRequests {
+        return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), true, err
+    }
+    return 0, resp.StatusCode >= 500, err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "strings"
+    "testing"
+    "unicode/utf8"
+)
+
+func TestSlackBlocks(t *testing.T) {
+    blocks := slackBlocks(Post{
+        URL:     "https://fishc.com.cn/t?a=1&b=2",
+        Title:   strings.Repeat("长", 200),
+        Author:  "<鱼油>",
+        Message: strings.Repeat("正文。", 1200),
+    })
+    if blocks[0].Type != "header" || utf8.RuneCountInString(blocks[0].Text.Text) != slackHeaderLimit {
+        t.Errorf("header = %+v", blocks[0])
+    }
+    info := blocks[1]
+    if info.Accessory == nil || info.Accessory.URL != "https://fishc.com.cn/t?a=1&b=2" {
+        t.Errorf("button = %+v", info.Accessory)
+    }
+    if !strings.Contains(info.Text.Text, "a=1&amp;b=2") || !strings.Contains(info.Text.Text, "&lt;鱼油&gt;") {
+        t.Errorf("info = %q, mrkdwn control characters must be escaped", info.Text.Text)
+    }
+    if len(blocks) < 4 {
+        t.Fatalf("blocks = %d, want the message split into several sections", len(blocks))
+    }
+    for _, b := range blocks[2:] {
+        if utf8.RuneCountInString(b.Text.Text) > slackSectionLimit {
+            t.Errorf("section has %d characters", utf8.RuneCountInString(b.Text.Text))
+        }
+    }
+}
+
+func TestSlackNotifierSendsBlocks(t *testing.T) {
+    hook, url := newFakeWebhook(t)
+    n := &SlackNotifier{WebhookURL: url}
+    if err := n.NotifyBatch(context.Background(), []Post{{Title: "一", URL: "https://fishc.com.cn/1"}, {Title: "二", URL: "https://fishc.com.cn/2"}}); err != nil {
+        t.Fatal(err)
+    }
+    var p slackPayload
+    hook.decode(t, 0, &p)
+    if p.Text != "一\n二" {
+        t.Errorf("text = %q", p.Text)
+    }
+    dividers := 0
+    for _, b := range p.Blocks {
+        if b.Type == "divider" {
+            dividers++
+        }
+    }
+    if dividers != 1 {
+        t.Errorf("dividers = %d, want 1", dividers)
+    }
+}
+
+func TestTruncateRunes(t *testing.T) {
+    tests := []struct {
+        in    string
+        limit int
+        want  string
+    }{
+        {"abc", 3, "abc"},
+        {"abcd", 3, "ab…"},
+        {"一二三四", 2, "一…"},
+    }
+    for _, tt := range tests {
+        if got := truncateRunes(tt.in, tt.limit); got != tt.want {
+            t.Errorf("truncateRunes(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.want)
+        }
+    }
+}
+
+func TestSlackNotifierNotify(t *testing.T) {
+    hook, url := newFakeWebhook(t)
+    hook.status = func(n int) int {
+        if n == 1 {
+            return http.StatusBadGateway
+        }
+        return http.StatusOK
+    }
+    n := &SlackNotifier{WebhookURL: url}
+    if err := n.Notify(context.Background(), Post{Title: "标题", URL: "https://fishc.com.cn/1", Message: "正文"}); err != nil {
+        t.Fatal(err)
+    }
+    if len(hook.bodies) != 2 {
+        t.Fatalf("requests = %d, want a retry after 502", len(hook.bodies))
+    }
+    var p slackPayload
+    hook.decode(t, 1, &p)
+    if p.Text != "标题" || len(p.Blocks) != 3 || p.Blocks[2].Text.Text != "正文" {
+        t.Errorf("payload = %+v", p)
+    }
+}
+
+func TestSlackNotifierClientErrorNotRetried(t *testing.T) {
+    hook, url := newFakeWebhook(t)
+    hook.status = func(int) int { return http.StatusNotFound }
+    err := (&SlackNotifier{WebhookURL: url}).Notify(context.Background(), Post{Title: "标题"})
+    if err == nil || !strings.Contains(err.Error(), "404") {
+        t.Errorf("Notify() = %v, want the status code", err)
+    }
+    if len(hook.bodies) != 1 {
+        t.Errorf("requests = %d, want no retry for 404", len(hook.bodies))
+    }
+}
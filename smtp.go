@@ -0,0 +1,169 @@
+package main
+
+import (
+    "context"
+    "crypto/tls"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "html"
+    "mime"
+    "net"
+    "net/smtp"
+    "net/textproto"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// SMTP 连接的加密方式
+const (
+    smtpStartTLS = "starttls"
+    smtpTLS      = "tls"
+    smtpNone     = "none"
+)
+
+// smtpTimeout 发送单封邮件的超时时间，ctx 设置了更早的截止时间时以 ctx 为准
+const smtpTimeout = 30 * time.Second
+
+// SMTPNotifier 通过 SMTP 把每个帖子作为一封邮件发送，标题作为邮件主题
+type SMTPNotifier struct {
+    Host     string
+    Port     int
+    Username string
+    Password string
+    From     string
+    To       []string
+    // TLS 为 smtpStartTLS、smtpTLS（隐式 TLS，一般用于 465 端口）或 smtpNone
+    TLS string
+    // HTML 为 true 时发送 HTML 邮件，否则发送纯文本邮件
+    HTML bool
+}
+
+// Notify 发送邮件，网络错误和 4xx 临时错误时重试
+func (n *SMTPNotifier) Notify(ctx context.Context, p Post) error {
+    message := n.message(p, time.Now())
+    return retryNotify(ctx, "SMTP", func() (time.Duration, bool, error) {
+        err := n.send(ctx, message)
+        var tpErr *textproto.Error
+        if errors.As(err, &tpErr) {
+            return 0, tpErr.Code >= 400 && tpErr.Code < 500, err
+        }
+        return 0, err != nil, err
+    })
+}
+
+// send 建立连接并投递一封邮件
+func (n *SMTPNotifier) send(ctx context.Context, message []byte) error {
+    addr := net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+    ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+    defer cancel()
+
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return err
+    }
+    deadline, _ := ctx.Deadline()
+    conn.SetDeadline(deadline)
+    if n.TLS == smtpTLS {
+        conn = tls.Client(conn, &tls.Config{ServerName: n.Host})
+    }
+
+    c, err := smtp.NewClient(conn, n.Host)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer c.Close()
+
+    if n.TLS == smtpStartTLS {
+        if ok, _ := c.Extension("STARTTLS"); !ok {
+            return fmt.Errorf("smtp server %s does not support STARTTLS", addr)
+        }
+        if err := c.StartTLS(&tls.Config{ServerName: n.Host}); err != nil {
+            return err
+        }
+    }
+    if n.Username != "" {
+        if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+            return err
+        }
+    }
+    if err := c.Mail(n.From); err != nil {
+        return err
+    }
+    for _, to := range n.To {
+        if err := c.Rcpt(to); err != nil {
+            return err
+        }
+    }
+    w, err := c.Data()
+    if err != nil {
+        return err
+    }
+    if _, err := w.Write(message); err != nil {
+        return err
+    }
+    if err := w.Close(); err != nil {
+        return err
+    }
+    return c.Quit()
+}
+
+// message 生成完整的邮件内容，正文用 base64 编码以避免长行和非 ASCII 字符的问题
+func (n *SMTPNotifier) message(p Post, now time.Time) []byte {
+    contentType, body := "text/plain", emailText(p)
+    if n.HTML {
+        contentType, body = "text/html", emailHTML(p)
+    }
+
+    var b strings.Builder
+    fmt.Fprintf(&b, "From: %s\r\n", n.From)
+    fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.To, ", "))
+    fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", p.Title))
+    fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+    b.WriteString("MIME-Version: 1.0\r\n")
+    fmt.Fprintf(&b, "Content-Type: %s; charset=UTF-8\r\n", contentType)
+    b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+    encoded := base64.StdEncoding.EncodeToString([]byte(body))
+    for len(encoded) > 76 {
+        b.WriteString(encoded[:76] + "\r\n")
+        encoded = encoded[76:]
+    }
+    b.WriteString(encoded + "\r\n")
+    return []byte(b.String())
+}
+
+// emailText 把帖子格式化为纯文本邮件正文
+func emailText(p Post) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "%s\n%s\n", p.Title, p.URL)
+    if p.Author != "" {
+        fmt.Fprintf(&b, "%s: %s\n", msg("label.author"), p.Author)
+    }
+    if p.Time != "" {
+        fmt.Fprintf(&b, "%s: %s\n", msg("label.time"), p.Time)
+    }
+    fmt.Fprintf(&b, "\n%s\n", plainMessage(p))
+    return b.String()
+}
+
+// emailHTML 把帖子格式化为 HTML 邮件正文，html 格式的正文已清理过，直接保留其中的标签
+func emailHTML(p Post) string {
+    message := html.EscapeString(plainMessage(p))
+    if p.Format == formatHTML {
+        message = p.Message
+    }
+    var b strings.Builder
+    fmt.Fprintf(&b, `<p><a href="%s"><b>%s</b></a></p>`, html.EscapeString(p.URL), html.EscapeString(p.Title))
+    if p.Author != "" {
+        fmt.Fprintf(&b, "<p>%s: %s</p>", msg("label.author"), html.EscapeString(p.Author))
+    }
+    if p.Time != "" {
+        fmt.Fprintf(&b, "<p>%s: %s</p>", msg("label.time"), html.EscapeString(p.Time))
+    }
+    fmt.Fprintf(&b, "<div>%s</div>", strings.ReplaceAll(message, "\n", "<br>\n"))
+    return b.String()
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/base64"
+    "mime"
+    "net"
+    "net/textproto"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// fakeSMTP 只实现投递一封邮件所需命令的 SMTP 服务，记录收到的收件人和邮件内容。
+// rcptReply 不为空时用它回复 RCPT 命令
+type fakeSMTP struct {
+    mu        sync.Mutex
+    rcpts     []string
+    data      []string
+    sessions  int
+    rcptReply string
+}
+
+// newFakeSMTP 启动 fakeSMTP，返回监听的地址和端口
+func newFakeSMTP(t *testing.T) (*fakeSMTP, string, int) {
+    t.Helper()
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { ln.Close() })
+    s := &fakeSMTP{}
+    go func() {
+        for {
+            conn, err := ln.Accept()
+            if err != nil {
+                return
+            }
+            go s.serve(conn)
+        }
+    }()
+    addr := ln.Addr().(*net.TCPAddr)
+    return s, addr.IP.String(), addr.Port
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+    defer conn.Close()
+    s.mu.Lock()
+    s.sessions++
+    s.mu.Unlock()
+    tp := textproto.NewConn(conn)
+    tp.PrintfLine("220 localhost ESMTP")
+    for {
+        line, err := tp.ReadLine()
+        if err != nil {
+            return
+        }
+        cmd := strings.ToUpper(strings.Fields(line + " ")[0])
+        switch cmd {
+        case "EHLO", "HELO":
+            tp.PrintfLine("250-localhost")
+            tp.PrintfLine("250 8BITMIME")
+        case "MAIL":
+            tp.PrintfLine("250 OK")
+        case "RCPT":
+            s.mu.Lock()
+            reply := s.rcptReply
+            if reply == "" {
+                s.rcpts = append(s.rcpts, line)
+                reply = "250 OK"
+            }
+            s.mu.Unlock()
+            tp.PrintfLine("%s", reply)
+        case "DATA":
+            tp.PrintfLine("354 go ahead")
+            lines, err := tp.ReadDotLines()
+            if err != nil {
+                return
+            }
+            s.mu.Lock()
+            s.data = append(s.data, strings.Join(lines, "\n"))
+            s.mu.Unlock()
+            tp.PrintfLine("250 queued")
+        case "QUIT":
+            tp.PrintfLine("221 bye")
+            return
+        default:
+            tp.PrintfLine("502 not implemented")
+        }
+    }
+}
+
+func TestSMTPNotifierDeliversMail(t *testing.T) {
+    server, host, port := newFakeSMTP(t)
+    n := &SMTPNotifier{Host: host, Port: port, From: "bot@example.com", To: []string{"a@example.com", "b@example.com"}, TLS: smtpNone}
+    if err := n.Notify(context.Background(), Post{Title: "新帖 标题", URL: "https://fishc.com.cn/t", Message: "正文"}); err != nil {
+        t.Fatal(err)
+    }
+    server.mu.Lock()
+    defer server.mu.Unlock()
+    if len(server.rcpts) != 2 || len(server.data) != 1 {
+        t.Fatalf("rcpts = %v, data = %d", server.rcpts, len(server.data))
+    }
+    header, body := parseTestMail(t, server.data[0])
+    if subject, _ := new(mime.WordDecoder).DecodeHeader(header["Subject"]); subject != "新帖 标题" {
+        t.Errorf("Subject = %q", subject)
+    }
+    if !strings.Contains(body, "https://fishc.com.cn/t") || !strings.Contains(body, "正文") {
+        t.Errorf("body = %q", body)
+    }
+}
+
+func TestSMTPNotifierRetriesTemporaryErrors(t *testing.T) {
+    withFastRetry(t)
+    tests := []struct {
+        name         string
+        reply        string
+        wantSessions int
+    }{
+        {"temporary", "451 try again later", notifyMaxAttempts},
+        {"permanent", "550 no such user", 1},
+    }
+    for _, tt := range tests {
+        server, host, port := newFakeSMTP(t)
+        server.rcptReply = tt.reply
+        n := &SMTPNotifier{Host: host, Port: port, From: "bot@example.com", To: []string{"a@example.com"}, TLS: smtpNone}
+        if err := n.Notify(context.Background(), Post{Title: "t"}); err == nil {
+            t.Errorf("%s: Notify succeeded", tt.name)
+        }
+        server.mu.Lock()
+        if server.sessions != tt.wantSessions {
+            t.Errorf("%s: sessions = %d, want %d", tt.name, server.sessions, tt.wantSessions)
+        }
+        server.mu.Unlock()
+    }
+}
+
+func TestSMTPMessageFormats(t *testing.T) {
+    post := Post{Title: "标题", URL: "https://fishc.com.cn/t?a=1&b=2", Author: "鱼油", Message: "<b>粗体</b>\n第二行", Format: formatHTML}
+    now := time.Date(2024, 5, 12, 10, 20, 0, 0, time.UTC)
+
+    header, body := parseTestMail(t, string((&SMTPNotifier{From: "a@x", To: []string{"b@x"}}).message(post, now)))
+    if !strings.HasPrefix(header["Content-Type"], "text/plain") || header["Date"] != now.Format(time.RFC1123Z) {
+        t.Errorf("header = %v", header)
+    }
+    if !strings.Contains(body, "粗体\n第二行") || strings.Contains(body, "<b>") {
+        t.Errorf("plain body = %q", body)
+    }
+
+    header, body = parseTestMail(t, string((&SMTPNotifier{HTML: true}).message(post, now)))
+    if !strings.HasPrefix(header["Content-Type"], "text/html") {
+        t.Errorf("Content-Type = %q", header["Content-Type"])
+    }
+    if !strings.Contains(body, "<b>粗体</b><br>") || !strings.Contains(body, "a=1&amp;b=2") {
+        t.Errorf("html body = %q", body)
+    }
+    if _, body = parseTestMail(t, string((&SMTPNotifier{HTML: true}).message(Post{Message: "1 < 2"}, now))); !strings.Contains(body, "1 &lt; 2") {
+        t.Errorf("plain message must be escaped in html mail: %q", body)
+    }
+}
+
+// parseTestMail 拆分邮件的头部和 base64 编码的正文
+func parseTestMail(t *testing.T, mail string) (map[string]string, string) {
+    t.Helper()
+    r := textproto.NewReader(bufio.NewReader(strings.NewReader(strings.ReplaceAll(mail, "\r\n", "\n"))))
+    mh, err := r.ReadMIMEHeader()
+    if err != nil {
+        t.Fatalf("parse mail header: %v", err)
+    }
+    header := make(map[string]string)
+    for key := range mh {
+        header[key] = mh.Get(key)
+    }
+    var encoded strings.Builder
+    for {
+        line, err := r.ReadLine()
+        if err != nil {
+            break
+        }
+        encoded.WriteString(line)
+    }
+    body, err := base64.StdEncoding.DecodeString(encoded.String())
+    if err != nil {
+        t.Fatalf("decode body: %v", err)
+    }
+    return header, string(body)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "log/slog"
+    "time"
+
+    _ "modernc.org/sqlite"
+)
+
+// sqliteSchema 已处理帖子表，marked_at 为 Unix 秒，用于按时间清理
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS seen (
+    forum     TEXT    NOT NULL,
+    key       TEXT    NOT NULL,
+    url       TEXT    NOT NULL,
+    title     TEXT    NOT NULL,
+    marked_at INTEGER NOT NULL,
+    PRIMARY KEY (forum, key)
+);
+CREATE INDEX IF NOT EXISTS seen_marked_at ON seen (marked_at);
+`
+
+// sqlitePruneInterval 后台清理过期记录的间隔
+const sqlitePruneInterval = time.Hour
+
+// openSQLiteDB 打开或创建 SQLite 数据库并建表。多个论坛共用一个连接，避免并发写入时锁冲突
+func openSQLiteDB(path string) (*sql.DB, error) {
+    db, err := sql.Open("sqlite", path)
+    if err != nil {
+        return nil, fmt.Errorf("open sqlite %s: %w", path, err)
+    }
+    db.SetMaxOpenConns(1)
+    if _, err := db.Exec(sqliteSchema); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("init sqlite %s: %w", path, err)
+    }
+    return db, nil
+}
+
+// SQLiteStore 把某个论坛已处理的帖子保存在 SQLite 中，记录带有时间戳，可以查询和按时间清理
+type SQLiteStore struct {
+    db       *sql.DB
+    forumURL string
+}
+
+// Seen 判断 key 是否已记录，命中时更新记录时间，仍在列表页上出现的帖子不会因超过 -store-max-age 被清理后重复通知
+func (s *SQLiteStore) Seen(key string) (bool, error) {
+    res, err := s.db.Exec(`UPDATE seen SET marked_at = ? WHERE forum = ? AND key = ?`, time.Now().Unix(), s.forumURL, key)
+    if err != nil {
+        return false, fmt.Errorf("query seen: %w", err)
+    }
+    n, err := res.RowsAffected()
+    if err != nil {
+        return false, fmt.Errorf("query seen: %w", err)
+    }
+    return n > 0, nil
+}
+
+// Mark 记录 key 及帖子的链接和标题，已存在时更新记录时间
+func (s *SQLiteStore) Mark(key string, p Post) error {
+    _, err := s.db.Exec(`INSERT OR REPLACE INTO seen (forum, key, url, title, marked_at) VALUES (?, ?, ?, ?, ?)`,
+        s.forumURL, key, p.URL, p.Title, time.Now().Unix())
+    if err != nil {
+        return fmt.Errorf("mark seen: %w", err)
+    }
+    return nil
+}
+
+// Len 返回该论坛已记录的数量
+func (s *SQLiteStore) Len() (int, error) {
+    var n int
+    if err := s.db.QueryRow(`SELECT COUNT(*) FROM seen WHERE forum = ?`, s.forumURL).Scan(&n); err != nil {
+        return 0, fmt.Errorf("count seen: %w", err)
+    }
+    return n, nil
+}
+
+// pruneSeen 删除早于 maxAge 的记录，返回删除的数量
+func pruneSeen(db *sql.DB, maxAge time.Duration) (int64, error) {
+    res, err := db.Exec(`DELETE FROM seen WHERE marked_at < ?`, time.Now().Add(-maxAge).Unix())
+    if err != nil {
+        return 0, err
+    }
+    return res.RowsAffected()
+}
+
+// pruneLoop 立即并每隔 sqlitePruneInterval 清理一次过期记录，直到 ctx 被取消
+func pruneLoop(ctx context.Context, db *sql.DB, maxAge time.Duration) {
+    for {
+        if n, err := pruneSeen(db, maxAge); err != nil {
+            slog.Error("清理过期去重记录失败", "err", err)
+        } else if n > 0 {
+            slog.Info("已清理过期去重记录", "count", n)
+        }
+        if !sleepContext(ctx, sqlitePruneInterval) {
+            return
+        }
+    }
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sync"
+)
+
+// stateStore 将已通知的帖子持久化到 JSON 文件，按论坛 URL 分别记录，重启后不会重复通知
+type stateStore struct {
+    path   string
+    mu     sync.Mutex
+    forums map[string][]string
+}
+
+// stateFileContent 状态文件的 JSON 结构
+type stateFileContent struct {
+    Forums map[string][]string `json:"forums"`
+}
+
+// openStateStore 加载状态文件，文件不存在时从空状态开始
+func openStateStore(path string) (*stateStore, error) {
+    s := &stateStore{path: path, forums: make(map[string][]string)}
+
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return s, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var content stateFileContent
+    if err := json.Unmarshal(data, &content); err != nil {
+        return nil, fmt.Errorf("parse state file %s: %w", path, err)
+    }
+    if content.Forums != nil {
+        s.forums = content.Forums
+    }
+    return s, nil
+}
+
+// Seen 返回某个论坛已记录的帖子，s 为 nil 时表示不持久化
+func (s *stateStore) Seen(forumURL string) []string {
+    if s == nil {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]string(nil), s.forums[forumURL]...)
+}
+
+// Save 更新某个论坛已记录的帖子并写入文件，先写临时文件再重命名，避免崩溃时损坏状态文件
+func (s *stateStore) Save(forumURL string, keys []string) error {
+    if s == nil {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.forums[forumURL] = append([]string(nil), keys...)
+    data, err := json.MarshalIndent(stateFileContent{Forums: s.forums}, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), s.path)
+}
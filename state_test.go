@@ -0,0 +1,82 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "path/filepath"
+    "slices"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestStateStorePersists(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "state.json")
+    state, err := openStateStore(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := state.Save("https://a.example/", []string{"u1", "u2"}); err != nil {
+        t.Fatal(err)
+    }
+    if err := state.Save("https://b.example/", []string{"u3"}); err != nil {
+        t.Fatal(err)
+    }
+
+    reopened, err := openStateStore(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := reopened.Seen("https://a.example/"); !slices.Equal(got, []string{"u1", "u2"}) {
+        t.Errorf("Seen(a) = %q", got)
+    }
+    if got := reopened.Seen("https://b.example/"); !slices.Equal(got, []string{"u3"}) {
+        t.Errorf("Seen(b) = %q", got)
+    }
+}
+
+func TestMonitorForumSkipsPostsInState(t *testing.T) {
+    var mu sync.Mutex
+    var requested []string
+    listed := make(chan struct{}, 1)
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        requested = append(requested, r.URL.Path)
+        mu.Unlock()
+        if r.URL.Path == "/list" {
+            fmt.Fprint(w, `<a class="th_item" href="/thread-2.html">新帖</a><a class="th_item" href="/thread-1.html">旧帖</a>`)
+            select {
+            case listed <- struct{}{}:
+            default:
+            }
+            return
+        }
+        fmt.Fprint(w, `<div id="myshares"><a>帖子</a></div>`)
+    })
+
+    state, err := openStateStore(filepath.Join(t.TempDir(), "state.json"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    forumURL := srv.URL + "/list"
+    if err := state.Save(forumURL, []string{srv.URL + "/thread-1.html", srv.URL + "/thread-2.html"}); err != nil {
+        t.Fatal(err)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        monitorForum(ctx, monitorOptions{URL: forumURL, Fetcher: FastHTTPFetcher{UserAgent: defaultUserAgent, Attempts: 1}, Interval: time.Hour, Selectors: defaultSelectors, Store: newFileStore(forumURL, state), Notifier: &recordingNotifier{}})
+    }()
+    <-listed
+    cancel()
+    <-done
+
+    mu.Lock()
+    defer mu.Unlock()
+    if !slices.Equal(requested, []string{"/list"}) {
+        t.Errorf("requested %q, want only the list page for posts already in the state", requested)
+    }
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "time"
+)
+
+// SeenStore 记录某个论坛已处理过的帖子，用于去重，内存、状态文件、SQLite 和布隆过滤器都实现该接口
+type SeenStore interface {
+    // Seen 判断 key 是否已记录
+    Seen(key string) (bool, error)
+    // Mark 记录 key 及对应的帖子
+    Mark(key string, p Post) error
+    // Len 返回已记录的数量，为 0 时按首次运行处理
+    Len() (int, error)
+}
+
+// 支持的去重记录存储方式
+const (
+    storeMemory = "memory"
+    storeFile   = "file"
+    storeSQLite = "sqlite"
+    storeBloom  = "bloom"
+)
+
+// memoryStore 只在内存中保存最近的 maxSeenPosts 条记录，是未配置持久化时的默认实现
+type memoryStore struct {
+    set *seenSet
+}
+
+// newMemoryStore 创建空的 memoryStore
+func newMemoryStore() *memoryStore {
+    return &memoryStore{set: newSeenSet(maxSeenPosts)}
+}
+
+func (s *memoryStore) Seen(key string) (bool, error) {
+    return s.set.Has(key), nil
+}
+
+func (s *memoryStore) Mark(key string, _ Post) error {
+    s.set.Add(key)
+    return nil
+}
+
+func (s *memoryStore) Len() (int, error) {
+    return s.set.Len(), nil
+}
+
+// fileStore 在 memoryStore 的基础上每次记录后把该论坛的记录写入状态文件
+type fileStore struct {
+    *memoryStore
+    forumURL string
+    state    *stateStore
+}
+
+// newFileStore 创建 forumURL 的 fileStore 并载入状态文件中的历史记录
+func newFileStore(forumURL string, state *stateStore) *fileStore {
+    s := &fileStore{memoryStore: newMemoryStore(), forumURL: forumURL, state: state}
+    for _, key := range state.Seen(forumURL) {
+        s.set.Add(key)
+    }
+    return s
+}
+
+// Mark 记录 key 并写入状态文件，写入失败时内存中仍保留该记录
+func (s *fileStore) Mark(key string, _ Post) error {
+    s.set.Add(key)
+    if err := s.state.Save(s.forumURL, s.set.Keys()); err != nil {
+        return fmt.Errorf("save state file: %w", err)
+    }
+    return nil
+}
+
+// openStore 按 -store 打开去重记录的存储，返回为每个论坛创建 SeenStore 的函数。
+// 使用 SQLite 时会在后台定期清理超过 maxAge 的记录，直到 ctx 被取消。
+// 布隆过滤器只保存在内存中，每个论坛按 bloomCapacity 和 bloomFPRate 分配空间
+func openStore(ctx context.Context, kind, path string, maxAge time.Duration, bloomCapacity int, bloomFPRate float64) (func(forumURL string) SeenStore, error) {
+    switch kind {
+    case storeBloom:
+        return func(string) SeenStore { return newBloomStore(bloomCapacity, bloomFPRate) }, nil
+    case storeSQLite:
+        db, err := openSQLiteDB(path)
+        if err != nil {
+            return nil, err
+        }
+        if maxAge > 0 {
+            go pruneLoop(ctx, db, maxAge)
+        }
+        return func(forumURL string) SeenStore {
+            return &SQLiteStore{db: db, forumURL: forumURL}
+        }, nil
+    case storeFile:
+        if path == "" {
+            break
+        }
+        state, err := openStateStore(path)
+        if err != nil {
+            return nil, err
+        }
+        return func(forumURL string) SeenStore { return newFileStore(forumURL, state) }, nil
+    case storeMemory:
+    default:
+        return nil, fmt.Errorf("unsupported store %q", kind)
+    }
+    return func(string) SeenStore { return newMemoryStore() }, nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestOpenStoreKinds(t *testing.T) {
+    dir := t.TempDir()
+    tests := []struct {
+        kind string
+        path string
+    }{
+        {storeMemory, ""},
+        {storeFile, ""},
+        {storeFile, filepath.Join(dir, "state.json")},
+        {storeSQLite, filepath.Join(dir, "seen.db")},
+        {storeBloom, ""},
+    }
+    for _, tt := range tests {
+        t.Run(tt.kind+"/"+filepath.Base(tt.path), func(t *testing.T) {
+            storeFor, err := openStore(context.Background(), tt.kind, tt.path, 0, 1000, 0.001)
+            if err != nil {
+                t.Fatal(err)
+            }
+            a, b := storeFor("https://a.example/"), storeFor("https://b.example/")
+            if n, err := a.Len(); err != nil || n != 0 {
+                t.Fatalf("Len() = %d, %v, want empty store", n, err)
+            }
+            if err := a.Mark("k1", Post{URL: "u1", Title: "t1"}); err != nil {
+                t.Fatal(err)
+            }
+            if err := a.Mark("k1", Post{URL: "u1", Title: "t1"}); err != nil {
+                t.Fatal(err)
+            }
+            if seen, err := a.Seen("k1"); err != nil || !seen {
+                t.Errorf("Seen(k1) = %v, %v, want true", seen, err)
+            }
+            if seen, err := a.Seen("k2"); err != nil || seen {
+                t.Errorf("Seen(k2) = %v, %v, want false", seen, err)
+            }
+            if n, err := a.Len(); err != nil || n != 1 {
+                t.Errorf("Len() = %d, %v, want 1 after marking the same key twice", n, err)
+            }
+            if seen, _ := b.Seen("k1"); seen {
+                t.Error("key marked for one forum is seen by another")
+            }
+        })
+    }
+}
+
+func TestOpenStoreUnsupported(t *testing.T) {
+    if _, err := openStore(context.Background(), "redis", "", 0, 0, 0); err == nil || !strings.Contains(err.Error(), "unsupported store") {
+        t.Errorf("openStore(redis) = %v", err)
+    }
+}
+
+func TestFileStorePersists(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "state.json")
+    storeFor, err := openStore(context.Background(), storeFile, path, 0, 0, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := storeFor("https://a.example/").Mark("k1", Post{}); err != nil {
+        t.Fatal(err)
+    }
+
+    storeFor, err = openStore(context.Background(), storeFile, path, 0, 0, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    store := storeFor("https://a.example/")
+    if seen, _ := store.Seen("k1"); !seen {
+        t.Error("key was not loaded from the state file")
+    }
+    if n, _ := store.Len(); n != 1 {
+        t.Errorf("Len() = %d, want 1", n)
+    }
+    if n, _ := storeFor("https://b.example/").Len(); n != 0 {
+        t.Errorf("other forum Len() = %d, want 0", n)
+    }
+}
+
+func TestFileStoreKeepsRecentKeys(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "state.json")
+    state, err := openStateStore(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    store := newFileStore("https://a.example/", state)
+    for i := 0; i < maxSeenPosts+10; i++ {
+        if err := store.Mark(fmt.Sprintf("k%d", i), Post{}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    reopened, err := openStateStore(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    keys := reopened.Seen("https://a.example/")
+    if len(keys) != maxSeenPosts {
+        t.Fatalf("saved %d keys, want %d", len(keys), maxSeenPosts)
+    }
+    for _, k := range keys {
+        if k == "k0" {
+            t.Error("oldest key was kept in the state file")
+        }
+    }
+}
+
+func TestOpenStateStore(t *testing.T) {
+    dir := t.TempDir()
+    missing, err := openStateStore(filepath.Join(dir, "missing.json"))
+    if err != nil || len(missing.Seen("https://a.example/")) != 0 {
+        t.Errorf("missing file = %v, want empty state", err)
+    }
+
+    corrupt := filepath.Join(dir, "corrupt.json")
+    if err := os.WriteFile(corrupt, []byte("{not json"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := openStateStore(corrupt); err == nil || !strings.Contains(err.Error(), "parse state file") {
+        t.Errorf("corrupt file = %v", err)
+    }
+
+    var nilState *stateStore
+    if err := nilState.Save("https://a.example/", []string{"k"}); err != nil || nilState.Seen("https://a.example/") != nil {
+        t.Errorf("nil state = %v, want a no-op", err)
+    }
+}
+
+func TestStateStoreSaveLeavesNoTempFiles(t *testing.T) {
+    dir := t.TempDir()
+    state, err := openStateStore(filepath.Join(dir, "state.json"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := state.Save("https://a.example/", []string{"k1", "k2"}); err != nil {
+        t.Fatal(err)
+    }
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(entries) != 1 || entries[0].Name() != "state.json" {
+        t.Errorf("directory contains %d entries, want only state.json", len(entries))
+    }
+}
+
+func TestSQLiteStorePersistsAndPrunes(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "seen.db")
+    db, err := openSQLiteDB(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    store := &SQLiteStore{db: db, forumURL: "https://a.example/"}
+    if err := store.Mark("k1", Post{URL: "https://a.example/t1", Title: "标题"}); err != nil {
+        t.Fatal(err)
+    }
+    var url, title string
+    if err := db.QueryRow(`SELECT url, title FROM seen WHERE key = 'k1'`).Scan(&url, &title); err != nil {
+        t.Fatal(err)
+    }
+    if url != "https://a.example/t1" || title != "标题" {
+        t.Errorf("row = %q, %q", url, title)
+    }
+    db.Close()
+
+    db, err = openSQLiteDB(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    store = &SQLiteStore{db: db, forumURL: "https://a.example/"}
+    if seen, err := store.Seen("k1"); err != nil || !seen {
+        t.Fatalf("Seen(k1) after reopen = %v, %v", seen, err)
+    }
+
+    if _, err := db.Exec(`UPDATE seen SET marked_at = ?`, time.Now().Add(-48*time.Hour).Unix()); err != nil {
+        t.Fatal(err)
+    }
+    if err := store.Mark("k2", Post{}); err != nil {
+        t.Fatal(err)
+    }
+    n, err := pruneSeen(db, 24*time.Hour)
+    if err != nil || n != 1 {
+        t.Fatalf("pruneSeen() = %d, %v, want 1", n, err)
+    }
+    if seen, _ := store.Seen("k1"); seen {
+        t.Error("expired key was not pruned")
+    }
+    if seen, _ := store.Seen("k2"); !seen {
+        t.Error("recent key was pruned")
+    }
+}
+
+func TestSQLiteStoreSeenRefreshesTimestamp(t *testing.T) {
+    db, err := openSQLiteDB(filepath.Join(t.TempDir(), "seen.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    store := &SQLiteStore{db: db, forumURL: "https://a.example/"}
+    if err := store.Mark("k1", Post{}); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := db.Exec(`UPDATE seen SET marked_at = ?`, time.Now().Add(-48*time.Hour).Unix()); err != nil {
+        t.Fatal(err)
+    }
+
+    // 仍在列表页上出现的帖子每轮都会被查询，查询后不再被当作过期记录清理
+    if seen, err := store.Seen("k1"); err != nil || !seen {
+        t.Fatalf("Seen(k1) = %v, %v", seen, err)
+    }
+    if n, err := pruneSeen(db, 24*time.Hour); err != nil || n != 0 {
+        t.Errorf("pruneSeen() = %d, %v, want the refreshed key kept", n, err)
+    }
+    if seen, _ := store.Seen("missing"); seen {
+        t.Error("Seen(missing) = true")
+    }
+}
+
+func TestBloomStoreFalsePositiveRate(t *testing.T) {
+    const capacity = 10000
+    store := newBloomStore(capacity, 0.01)
+    for i := 0; i < capacity; i++ {
+        if err := store.Mark(fmt.Sprintf("https://a.example/thread-%d.html", i), Post{}); err != nil {
+            t.Fatal(err)
+        }
+    }
+    for i := 0; i < capacity; i++ {
+        if seen, _ := store.Seen(fmt.Sprintf("https://a.example/thread-%d.html", i)); !seen {
+            t.Fatalf("marked key %d reported as unseen", i)
+        }
+    }
+    falsePositives := 0
+    for i := capacity; i < 2*capacity; i++ {
+        if seen, _ := store.Seen(fmt.Sprintf("https://a.example/thread-%d.html", i)); seen {
+            falsePositives++
+        }
+    }
+    if rate := float64(falsePositives) / capacity; rate > 0.03 {
+        t.Errorf("false positive rate = %.3f, want about 0.01", rate)
+    }
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "log/slog"
+    "net/url"
+    "os"
+    "reflect"
+    "strings"
+    "time"
+)
+
+// targetsPollInterval 检查目标文件是否被修改的间隔
+const targetsPollInterval = 5 * time.Second
+
+// readTargets 读取目标文件，每行一个论坛 URL，空行和 # 开头的行被忽略。
+// URL 后可以用 | 分隔追加 list=、title=、message= 覆盖该论坛的选择器，未覆盖的取 defaults
+func readTargets(path string, defaults Selectors) ([]ForumConfig, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, fmt.Errorf("read targets %s: %w", path, err)
+    }
+    defer f.Close()
+
+    var forums []ForumConfig
+    seen := make(map[string]bool)
+    scanner := bufio.NewScanner(f)
+    for n := 1; scanner.Scan(); n++ {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        forum, err := parseTargetLine(line)
+        if err != nil {
+            return nil, fmt.Errorf("targets %s line %d: %w", path, n, err)
+        }
+        if seen[forum.URL] {
+            return nil, fmt.Errorf("targets %s line %d: duplicate forum url %q", path, n, forum.URL)
+        }
+        seen[forum.URL] = true
+        forum.Selectors = forum.Selectors.withDefaults(defaults)
+        if err := forum.Selectors.Validate(); err != nil {
+            return nil, fmt.Errorf("targets %s line %d: %w", path, n, err)
+        }
+        forums = append(forums, forum)
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("read targets %s: %w", path, err)
+    }
+    return forums, nil
+}
+
+// parseTargetLine 解析目标文件中的一行，例如 "https://example.com/forum-2-1.html | list=a.s.xst | title=#thread_subject"
+func parseTargetLine(line string) (ForumConfig, error) {
+    parts := strings.Split(line, "|")
+    forum := ForumConfig{URL: strings.TrimSpace(parts[0])}
+    if u, err := url.Parse(forum.URL); err != nil || u.Scheme == "" || u.Host == "" {
+        return ForumConfig{}, fmt.Errorf("invalid forum url %q", forum.URL)
+    }
+    for _, part := range parts[1:] {
+        key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+        value = strings.TrimSpace(value)
+        if !ok || value == "" {
+            return ForumConfig{}, fmt.Errorf("invalid selector override %q, expected key=selector", strings.TrimSpace(part))
+        }
+        switch strings.TrimSpace(key) {
+        case "list":
+            forum.Selectors.List = value
+        case "title":
+            forum.Selectors.Title = value
+        case "message":
+            forum.Selectors.Message = value
+        default:
+            return ForumConfig{}, fmt.Errorf("unknown selector %q, expected list, title or message", key)
+        }
+    }
+    return forum, nil
+}
+
+// runningTarget 目标文件中一个正在监控的论坛
+type runningTarget struct {
+    forum  ForumConfig
+    cancel context.CancelFunc
+    done   chan struct{}
+}
+
+// targetsWatcher 按目标文件启动论坛监控，定期检查文件的修改时间，
+// 文件变化后为新增的论坛启动监控、停止被删除的论坛，选择器变化的论坛重新启动
+type targetsWatcher struct {
+    path     string
+    defaults Selectors
+    // base 除 URL、选择器和存储外各论坛共用的监控参数
+    base     monitorOptions
+    storeFor func(string) SeenStore
+    // static 配置文件中直接列出的论坛，由配置方案本身监控，目标文件中重复的忽略
+    static map[string]bool
+
+    modTime time.Time
+    size    int64
+    running map[string]*runningTarget
+    // stores 每个论坛用过的去重记录，论坛重新启动或删除后再加入时继续使用，避免重复通知最新的帖子
+    stores map[string]SeenStore
+}
+
+// newTargetsWatcher 创建目标文件的监控，static 为配置文件中直接列出的论坛
+func newTargetsWatcher(path string, defaults Selectors, base monitorOptions, storeFor func(string) SeenStore, static []ForumConfig) *targetsWatcher {
+    w := &targetsWatcher{
+        path:     path,
+        defaults: defaults,
+        base:     base,
+        storeFor: storeFor,
+        static:   make(map[string]bool),
+        running:  make(map[string]*runningTarget),
+        stores:   make(map[string]SeenStore),
+    }
+    for _, forum := range static {
+        w.static[forum.URL] = true
+    }
+    return w
+}
+
+// monitors 返回目标文件中当前全部论坛的监控参数，供 -once 使用
+func (w *targetsWatcher) monitors() ([]monitorOptions, error) {
+    forums, err := readTargets(w.path, w.defaults)
+    if err != nil {
+        return nil, err
+    }
+    var monitors []monitorOptions
+    for _, forum := range forums {
+        if !w.static[forum.URL] {
+            monitors = append(monitors, w.base.forForum(forum, w.store))
+        }
+    }
+    return monitors, nil
+}
+
+// run 启动目标文件中的论坛监控并每隔 targetsPollInterval 检查文件，直到 ctx 被取消，返回前等待全部监控退出
+func (w *targetsWatcher) run(ctx context.Context) {
+    for {
+        w.sync(ctx)
+        if !sleepContext(ctx, targetsPollInterval) {
+            break
+        }
+    }
+    for u := range w.running {
+        w.stop(u)
+    }
+}
+
+// sync 目标文件的修改时间或大小变化时重新读取，按新的列表启动和停止监控。
+// 文件无法读取或有错误时保留正在运行的监控
+func (w *targetsWatcher) sync(ctx context.Context) {
+    info, err := os.Stat(w.path)
+    if err != nil {
+        if !w.modTime.IsZero() {
+            slog.Error("读取目标文件失败，继续使用原列表", "path", w.path, "err", err)
+            w.modTime, w.size = time.Time{}, 0
+        }
+        return
+    }
+    if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+        return
+    }
+    // 先记录修改时间，文件有错误时只报告一次，修改后再重新读取
+    w.modTime, w.size = info.ModTime(), info.Size()
+    forums, err := readTargets(w.path, w.defaults)
+    if err != nil {
+        slog.Error("目标文件有错误，继续使用原列表", "path", w.path, "err", err)
+        return
+    }
+
+    want := make(map[string]ForumConfig, len(forums))
+    for _, forum := range forums {
+        if w.static[forum.URL] {
+            slog.Warn("目标文件中的论坛已在配置文件中，忽略", "url", forum.URL)
+            continue
+        }
+        want[forum.URL] = forum
+    }
+    for u, t := range w.running {
+        forum, ok := want[u]
+        switch {
+        case !ok:
+            w.stop(u)
+            slog.Info("论坛已从目标文件删除，停止监控", "url", u)
+        case !reflect.DeepEqual(forum.Selectors, t.forum.Selectors):
+            w.stop(u)
+            slog.Info("目标文件中论坛的选择器已修改，重新启动监控", "url", u)
+        }
+    }
+    for _, forum := range forums {
+        if _, ok := want[forum.URL]; !ok {
+            continue
+        }
+        if _, ok := w.running[forum.URL]; !ok {
+            w.start(ctx, forum)
+            slog.Info("开始监控目标文件中的论坛", "url", forum.URL)
+        }
+    }
+}
+
+// start 在独立的 ctx 下启动一个论坛的监控
+func (w *targetsWatcher) start(ctx context.Context, forum ForumConfig) {
+    ctx, cancel := context.WithCancel(ctx)
+    t := &runningTarget{forum: forum, cancel: cancel, done: make(chan struct{})}
+    w.running[forum.URL] = t
+    opts := w.base.forForum(forum, w.store)
+    opts.Health.track(forum.URL)
+    go func() {
+        defer close(t.done)
+        runMonitor(ctx, opts)
+    }()
+}
+
+// store 返回论坛的去重记录，第一次启动时创建
+func (w *targetsWatcher) store(u string) SeenStore {
+    s, ok := w.stores[u]
+    if !ok {
+        s = w.storeFor(u)
+        w.stores[u] = s
+    }
+    return s
+}
+
+// stop 停止一个论坛的监控并等待其退出
+func (w *targetsWatcher) stop(u string) {
+    t := w.running[u]
+    t.cancel()
+    <-t.done
+    delete(w.running, u)
+    w.base.Health.forget(u)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseTargetLine(t *testing.T) {
+    tests := []struct {
+        line string
+        want ForumConfig
+        err  string
+    }{
+        {"https://a.example/forum-2-1.html", ForumConfig{URL: "https://a.example/forum-2-1.html"}, ""},
+        {"https://a.example/ | list=a.s.xst | title=#thread_subject | message = .t_f ",
+            ForumConfig{URL: "https://a.example/", Selectors: Selectors{List: "a.s.xst", Title: "#thread_subject", Message: ".t_f"}}, ""},
+        {"a.example/forum", ForumConfig{}, "invalid forum url"},
+        {"https://a.example/ | list", ForumConfig{}, "invalid selector override"},
+        {"https://a.example/ | list=", ForumConfig{}, "invalid selector override"},
+        {"https://a.example/ | author=.a", ForumConfig{}, "unknown selector"},
+    }
+    for _, tt := range tests {
+        got, err := parseTargetLine(tt.line)
+        if tt.err != "" {
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("parseTargetLine(%q) = %v, want error containing %q", tt.line, err, tt.err)
+            }
+            continue
+        }
+        if err != nil || got.URL != tt.want.URL || got.Selectors.List != tt.want.Selectors.List ||
+            got.Selectors.Title != tt.want.Selectors.Title || got.Selectors.Message != tt.want.Selectors.Message {
+            t.Errorf("parseTargetLine(%q) = %+v, %v, want %+v", tt.line, got, err, tt.want)
+        }
+    }
+}
+
+// writeTargets 写入目标文件并把修改时间设为 mod，保证每次写入都能被发现
+func writeTargets(t *testing.T, path, content string, mod time.Time) {
+    t.Helper()
+    if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Chtimes(path, mod, mod); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestReadTargets(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "targets.txt")
+    writeTargets(t, path, "# forums\n\nhttps://a.example/\nhttps://b.example/ | list=a.xst\n", time.Now())
+    forums, err := readTargets(path, defaultSelectors)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(forums) != 2 {
+        t.Fatalf("forums = %+v", forums)
+    }
+    if forums[0].Selectors.List != defaultSelectors.List || forums[1].Selectors.List != "a.xst" || forums[1].Selectors.Message != defaultSelectors.Message {
+        t.Errorf("selectors = %+v, %+v, want defaults filled in", forums[0].Selectors, forums[1].Selectors)
+    }
+
+    tests := []struct {
+        content string
+        err     string
+    }{
+        {"https://a.example/\nhttps://a.example/\n", "line 2: duplicate forum url"},
+        {"\nnot a url\n", "line 2: invalid forum url"},
+        {"https://a.example/ | list=[[\n", "line 1"},
+    }
+    for _, tt := range tests {
+        writeTargets(t, path, tt.content, time.Now())
+        if _, err := readTargets(path, defaultSelectors); err == nil || !strings.Contains(err.Error(), tt.err) {
+            t.Errorf("readTargets(%q) = %v, want error containing %q", tt.content, err, tt.err)
+        }
+    }
+    if _, err := readTargets(filepath.Join(t.TempDir(), "missing"), defaultSelectors); err == nil {
+        t.Error("missing file read without error")
+    }
+}
+
+// runningURLs 返回正在监控的论坛，按地址排序
+func runningURLs(w *targetsWatcher) string {
+    var urls []string
+    for u := range w.running {
+        urls = append(urls, u)
+    }
+    sort.Strings(urls)
+    return strings.Join(urls, ",")
+}
+
+func TestTargetsWatcherSync(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "targets.txt")
+    mod := time.Now().Add(-time.Hour)
+    writeTargets(t, path, "https://a.example/\nhttps://b.example/\nhttps://static.example/\n", mod)
+
+    fetcher := newSiteFetcher()
+    base := monitorOptions{Fetcher: fetcher, Interval: time.Hour, Dedup: dedupURL, CatchUpPages: 1, Concurrency: 1, Notifier: &recordingNotifier{}}
+    created := 0
+    storeFor := func(string) SeenStore {
+        created++
+        return newMemoryStore()
+    }
+    w := newTargetsWatcher(path, testSelectors, base, storeFor, []ForumConfig{{URL: "https://static.example/"}})
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    defer func() {
+        for u := range w.running {
+            w.stop(u)
+        }
+    }()
+
+    w.sync(ctx)
+    if got := runningURLs(w); got != "https://a.example/,https://b.example/" {
+        t.Fatalf("running = %q, want targets except the static forum", got)
+    }
+
+    mod = mod.Add(time.Minute)
+    writeTargets(t, path, "https://b.example/ | list=a.other\nhttps://c.example/\n", mod)
+    oldB := w.running["https://b.example/"]
+    w.sync(ctx)
+    if got := runningURLs(w); got != "https://b.example/,https://c.example/" {
+        t.Fatalf("running = %q after edit", got)
+    }
+    if w.running["https://b.example/"] == oldB {
+        t.Error("forum with changed selectors was not restarted")
+    }
+
+    // 文件有错误时保留正在运行的监控
+    mod = mod.Add(time.Minute)
+    writeTargets(t, path, "not a url\n", mod)
+    w.sync(ctx)
+    if got := runningURLs(w); got != "https://b.example/,https://c.example/" {
+        t.Errorf("running = %q after an invalid edit, want unchanged", got)
+    }
+
+    // 删除后再加入的论坛继续使用原来的去重记录
+    mod = mod.Add(time.Minute)
+    writeTargets(t, path, "https://a.example/\n", mod)
+    w.sync(ctx)
+    if got := runningURLs(w); got != "https://a.example/" {
+        t.Errorf("running = %q", got)
+    }
+    if created != 3 {
+        t.Errorf("created %d stores, want one per forum", created)
+    }
+}
+
+func TestTargetsWatcherMonitors(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "targets.txt")
+    writeTargets(t, path, "https://a.example/\nhttps://static.example/\n", time.Now())
+    w := newTargetsWatcher(path, testSelectors, monitorOptions{Interval: time.Minute}, func(string) SeenStore { return newMemoryStore() }, []ForumConfig{{URL: "https://static.example/"}})
+    monitors, err := w.monitors()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(monitors) != 1 || monitors[0].URL != "https://a.example/" || monitors[0].Store == nil || monitors[0].Interval != time.Minute {
+        t.Errorf("monitors = %+v", monitors)
+    }
+}
@@ -0,0 +1,563 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "html"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/url"
+    "regexp"
+    "strconv"
+    "strings"
+    "text/template"
+    "time"
+    "unicode/utf8"
+)
+
+// Telegram 支持的消息格式
+const (
+    parseModeMarkdown   = "Markdown"
+    parseModeMarkdownV2 = "MarkdownV2"
+    parseModeHTML       = "HTML"
+)
+
+// telegramMessageLimit Telegram 单条消息的最大字符数
+const telegramMessageLimit = 4096
+
+// telegramCaptionLimit 是图片说明的最大长度
+const telegramCaptionLimit = 1024
+
+// telegramAPIBase Telegram Bot API 地址
+var telegramAPIBase = "https://api.telegram.org"
+
+// telegramResponse Telegram Bot API 的通用响应结构
+type telegramResponse struct {
+    OK          bool   `json:"ok"`
+    ErrorCode   int    `json:"error_code"`
+    Description string `json:"description"`
+    Parameters  struct {
+        RetryAfter int `json:"retry_after"`
+    } `json:"parameters"`
+}
+
+// validParseMode 判断消息格式是否受支持，空字符串表示纯文本
+func validParseMode(parseMode string) bool {
+    switch parseMode {
+    case "", parseModeMarkdown, parseModeMarkdownV2, parseModeHTML:
+        return true
+    }
+    return false
+}
+
+// chatUsernameRe 匹配公开频道或群组的 @username，用户名为 5 到 32 位字母、数字或下划线
+var chatUsernameRe = regexp.MustCompile(`^@[A-Za-z][A-Za-z0-9_]{4,31}$`)
+
+// normalizeChatID 检查并规范化 Chat ID，接受数字 ID（超级群组和频道为 -100 开头的负数）和 @username，
+// 也接受 https://t.me/username 形式的链接
+func normalizeChatID(chatID string) (string, error) {
+    id := strings.TrimSpace(chatID)
+    for _, prefix := range []string{"https://t.me/", "http://t.me/", "t.me/"} {
+        if rest, ok := strings.CutPrefix(id, prefix); ok {
+            id = "@" + strings.TrimSuffix(rest, "/")
+            break
+        }
+    }
+
+    if strings.HasPrefix(id, "@") {
+        if !chatUsernameRe.MatchString(id) {
+            return "", fmt.Errorf("invalid chat id %q: usernames must be 5-32 letters, digits or underscores", chatID)
+        }
+        return id, nil
+    }
+
+    digits := strings.TrimPrefix(id, "-")
+    if _, err := strconv.ParseUint(digits, 10, 64); err != nil || digits == "" {
+        return "", fmt.Errorf("invalid chat id %q: expected a numeric id or @username", chatID)
+    }
+    // 超级群组和频道的 ID 形如 -1001234567890，漏掉负号是常见的配置错误
+    if !strings.HasPrefix(id, "-") && strings.HasPrefix(id, "100") && len(id) >= 13 {
+        return "", fmt.Errorf("invalid chat id %q: supergroup and channel ids start with -100, the leading '-' is missing", chatID)
+    }
+    return id, nil
+}
+
+// markdownV2Replacer 转义 MarkdownV2 中所有需要转义的字符
+var markdownV2Replacer = strings.NewReplacer(
+    `\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+    "~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`,
+    "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// markdownV2URLReplacer 转义 MarkdownV2 链接地址部分中的 ) 和 \
+var markdownV2URLReplacer = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// markdownReplacer 转义旧版 Markdown 中实体之外的特殊字符
+var markdownReplacer = strings.NewReplacer("_", `\_`, "*", `\*`, "`", "\\`", "[", `\[`)
+
+// escapeMessage 按消息格式转义帖子正文。html 格式的正文在 HTML 消息中原样保留，发送前会统一清理，
+// markdown 格式的正文在 MarkdownV2 消息中原样保留；在其它消息格式中只保留文本
+func escapeMessage(p Post, parseMode string) string {
+    switch {
+    case p.Format == formatHTML && parseMode == parseModeHTML:
+        return p.Message
+    case p.Format == formatMarkdown && parseMode == parseModeMarkdownV2:
+        return p.Message
+    }
+    return escapeTelegram(plainMessage(p), parseMode)
+}
+
+// escapeTelegram 按消息格式转义来自论坛的文本，避免特殊字符导致 Telegram 解析失败
+func escapeTelegram(text, parseMode string) string {
+    switch parseMode {
+    case parseModeMarkdownV2:
+        return markdownV2Replacer.Replace(text)
+    case parseModeMarkdown:
+        return markdownReplacer.Replace(text)
+    case parseModeHTML:
+        return html.EscapeString(text)
+    default:
+        return text
+    }
+}
+
+// formatBold 将文本转义后加粗
+func formatBold(text, parseMode string) string {
+    switch parseMode {
+    case parseModeMarkdownV2:
+        return "*" + escapeTelegram(text, parseMode) + "*"
+    case parseModeMarkdown:
+        // 旧版 Markdown 不支持在实体内部转义，只能去掉会提前结束加粗的 *
+        return "*" + strings.ReplaceAll(text, "*", "") + "*"
+    case parseModeHTML:
+        return "<b>" + escapeTelegram(text, parseMode) + "</b>"
+    default:
+        return text
+    }
+}
+
+// formatLink 生成可点击的链接，链接文字为 URL 本身
+func formatLink(link, parseMode string) string {
+    switch parseMode {
+    case parseModeMarkdownV2:
+        return "[" + escapeTelegram(link, parseMode) + "](" + markdownV2URLReplacer.Replace(link) + ")"
+    case parseModeMarkdown:
+        return "[" + strings.ReplaceAll(link, "]", "%5D") + "](" + strings.ReplaceAll(link, ")", "%29") + ")"
+    case parseModeHTML:
+        return `<a href="` + html.EscapeString(link) + `">` + html.EscapeString(link) + "</a>"
+    default:
+        return link
+    }
+}
+
+// parseTemplate 解析 -template 指定的消息模板，模板中的 \n 会被替换为换行以便在命令行中书写。
+// 解析后用空帖子试执行一次，提前发现引用了不存在字段等错误。text 为空时返回 nil
+func parseTemplate(text string) (*template.Template, error) {
+    if text == "" {
+        return nil, nil
+    }
+    tmpl, err := template.New("message").Parse(strings.ReplaceAll(text, `\n`, "\n"))
+    if err != nil {
+        return nil, fmt.Errorf("invalid template: %w", err)
+    }
+    if err := tmpl.Execute(io.Discard, Post{}); err != nil {
+        return nil, fmt.Errorf("invalid template: %w", err)
+    }
+    return tmpl, nil
+}
+
+// renderPost 使用自定义模板格式化帖子，帖子的各字段会按 parseMode 转义，模板本身的文字需要符合 parseMode 的语法。
+// tmpl 为 nil 或执行失败时使用默认格式
+func renderPost(tmpl *template.Template, p Post, parseMode string) string {
+    if tmpl == nil {
+        return formatPost(p, parseMode)
+    }
+    data := Post{
+        URL:     escapeTelegram(p.URL, parseMode),
+        Title:   escapeTelegram(p.Title, parseMode),
+        Message: escapeMessage(p, parseMode),
+        Author:  escapeTelegram(p.Author, parseMode),
+        Time:    escapeTelegram(p.Time, parseMode),
+        Images:  p.Images,
+        Replies: p.Replies,
+        Views:   p.Views,
+        Format:  p.Format,
+        Sticky:  p.Sticky,
+    }
+    var b strings.Builder
+    if err := tmpl.Execute(&b, data); err != nil {
+        slog.Warn("渲染消息模板失败，使用默认格式", "post_url", p.URL, "err", err)
+        return formatPost(p, parseMode)
+    }
+    return b.String()
+}
+
+// label 返回当前语言的标签并按 parseMode 转义
+func label(key, parseMode string) string {
+    return escapeTelegram(msg(key), parseMode)
+}
+
+// formatPost 将帖子格式化为发送到 Telegram 的文本，作者和时间为空时省略对应行
+func formatPost(p Post, parseMode string) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "%s: %s\n", label("label.title", parseMode), formatBold(p.Title, parseMode))
+    fmt.Fprintf(&b, "%s: %s\n", label("label.link", parseMode), formatLink(p.URL, parseMode))
+    if p.Author != "" {
+        fmt.Fprintf(&b, "%s: %s\n", label("label.author", parseMode), escapeTelegram(p.Author, parseMode))
+    }
+    if p.Time != "" {
+        fmt.Fprintf(&b, "%s: %s\n", label("label.time", parseMode), escapeTelegram(p.Time, parseMode))
+    }
+    fmt.Fprintf(&b, "%s: %s", label("label.content", parseMode), escapeMessage(p, parseMode))
+    return b.String()
+}
+
+// splitMessage 将超长消息按段落、换行、句子、空格的优先级拆分为不超过 limit 个字符的多段
+func splitMessage(text string, limit int) []string {
+    var parts []string
+    for utf8.RuneCountInString(text) > limit {
+        window := text[:runeOffset(text, limit)]
+        cut := splitPoint(window)
+        parts = append(parts, strings.TrimRight(window[:cut], " \n"))
+        text = strings.TrimLeft(text[cut:], " \n")
+    }
+    if text != "" {
+        parts = append(parts, text)
+    }
+    return parts
+}
+
+// runeOffset 返回第 n 个字符的字节偏移
+func runeOffset(text string, n int) int {
+    for i := range text {
+        if n == 0 {
+            return i
+        }
+        n--
+    }
+    return len(text)
+}
+
+// splitPoint 在 window 中寻找合适的拆分位置，优先选择靠后半段的段落或句子边界
+func splitPoint(window string) int {
+    separators := []string{"\n\n", "\n", "。", "！", "？", ". ", " "}
+    for _, sep := range separators {
+        if i := strings.LastIndex(window, sep); i > len(window)/2 {
+            return i + len(sep)
+        }
+    }
+    for _, sep := range separators {
+        if i := strings.LastIndex(window, sep); i > 0 {
+            return i + len(sep)
+        }
+    }
+
+    // 没有任何边界时硬拆，但不拆开 MarkdownV2 的转义符和 HTML 实体
+    cut := len(window)
+    if i := strings.LastIndexByte(window, '&'); i > 0 && len(window)-i < 10 && !strings.Contains(window[i:], ";") {
+        cut = i
+    }
+    trailing := len(window[:cut]) - len(strings.TrimRight(window[:cut], `\`))
+    if trailing%2 == 1 && cut > 1 {
+        cut--
+    }
+    return cut
+}
+
+// TelegramNotifier 通过 Telegram Bot 将帖子推送到一个或多个频道
+type TelegramNotifier struct {
+    BotToken  string
+    ChatIDs   []string
+    ParseMode string
+    // Template 自定义消息模板，为 nil 时使用 formatPost 的默认格式
+    Template *template.Template
+    // Silent 静默发送，接收者不会收到提醒
+    Silent bool
+    // NoPreview 关闭消息中链接的预览
+    NoPreview bool
+    // ThreadID 开启了话题的群组中发送到的话题 ID，0 表示不指定
+    ThreadID int
+    // Buttons 在消息下方附加打开帖子的按钮
+    Buttons bool
+}
+
+// telegramSendOptions 每次调用 Bot API 时附带的发送选项
+type telegramSendOptions struct {
+    ParseMode string
+    Silent    bool
+    NoPreview bool
+    ThreadID  int
+    // ButtonURL 非空时在消息下方附加一个打开该链接的按钮
+    ButtonURL string
+}
+
+// sendOptions 返回通知渠道配置的发送选项
+func (n *TelegramNotifier) sendOptions() telegramSendOptions {
+    return telegramSendOptions{ParseMode: n.ParseMode, Silent: n.Silent, NoPreview: n.NoPreview, ThreadID: n.ThreadID}
+}
+
+// apply 将各发送方法通用的选项写入表单
+func (o telegramSendOptions) apply(data url.Values) {
+    if o.Silent {
+        data.Set("disable_notification", "true")
+    }
+    if o.ThreadID > 0 {
+        data.Set("message_thread_id", strconv.Itoa(o.ThreadID))
+    }
+}
+
+// telegramInlineButton 内联键盘中的 URL 按钮
+type telegramInlineButton struct {
+    Text string `json:"text"`
+    URL  string `json:"url"`
+}
+
+// telegramReplyMarkup 对应 reply_markup 参数中的内联键盘
+type telegramReplyMarkup struct {
+    InlineKeyboard [][]telegramInlineButton `json:"inline_keyboard"`
+}
+
+// setReplyMarkup 设置了 ButtonURL 时写入只有一个按钮的内联键盘，sendMediaGroup 不支持该参数
+func (o telegramSendOptions) setReplyMarkup(data url.Values) error {
+    if o.ButtonURL == "" {
+        return nil
+    }
+    markup := telegramReplyMarkup{InlineKeyboard: [][]telegramInlineButton{{{Text: msg("button.open"), URL: o.ButtonURL}}}}
+    encoded, err := json.Marshal(markup)
+    if err != nil {
+        return fmt.Errorf("encode reply markup: %w", err)
+    }
+    data.Set("reply_markup", string(encoded))
+    return nil
+}
+
+// Notify 格式化帖子并发送到所有频道
+func (n *TelegramNotifier) Notify(ctx context.Context, p Post) error {
+    opts := n.sendOptions()
+    if n.Buttons {
+        opts.ButtonURL = p.URL
+    }
+    return broadcast(ctx, n.BotToken, n.ChatIDs, p.Images, renderPost(n.Template, p, n.ParseMode), opts)
+}
+
+// NotifyBatch 将多个帖子合并为一条消息发送到所有频道，超过长度限制时拆分，合并消息不附带图片
+func (n *TelegramNotifier) NotifyBatch(ctx context.Context, posts []Post) error {
+    return broadcast(ctx, n.BotToken, n.ChatIDs, nil, formatBatch(n.Template, posts, n.ParseMode), n.sendOptions())
+}
+
+// batchSeparator 合并消息中帖子之间的分隔线，不含任何 Markdown 或 HTML 特殊字符
+const batchSeparator = "\n\n━━━━━━━━━━\n\n"
+
+// formatBatch 将多个帖子格式化后用分隔线连接
+func formatBatch(tmpl *template.Template, posts []Post, parseMode string) string {
+    parts := make([]string, len(posts))
+    for i, p := range posts {
+        parts[i] = renderPost(tmpl, p, parseMode)
+    }
+    return strings.Join(parts, batchSeparator)
+}
+
+// broadcast 将消息发送到所有频道，某个频道失败不影响其他频道，返回汇总后的错误
+func broadcast(ctx context.Context, botToken string, chatIDs, images []string, message string, opts telegramSendOptions) error {
+    var errs []error
+    for _, chatID := range chatIDs {
+        if err := sendToTelegram(ctx, botToken, chatID, images, message, opts); err != nil {
+            errs = append(errs, fmt.Errorf("chat %s: %w", chatID, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// sendToTelegram 发送消息到Telegram频道，超过长度限制时拆分为多条依次发送。
+// 帖子带图片时先发送图片，文字不超过说明长度限制时作为图片说明，否则随后单独发送；图片发送失败时退回纯文字
+func sendToTelegram(ctx context.Context, botToken, chatID string, images []string, message string, opts telegramSendOptions) error {
+    if len(images) > 0 {
+        // 相册不支持按钮，需要按钮时正文和按钮改为单独发送
+        caption := message
+        if utf8.RuneCountInString(message) > telegramCaptionLimit || (len(images) > 1 && opts.ButtonURL != "") {
+            caption = ""
+        }
+        // 按钮只附加在最后一条消息上，图片后面还要发送正文时不带按钮
+        photoOpts := opts
+        if caption == "" {
+            photoOpts.ButtonURL = ""
+        }
+        err := sendTelegramPhotos(ctx, botToken, chatID, images, caption, photoOpts)
+        if err == nil && caption != "" {
+            return nil
+        }
+        if err != nil {
+            slog.Warn("发送图片失败，改为仅发送文字", "chat_id", chatID, "err", err)
+        }
+    }
+
+    // 消息被拆分时按钮只附加在最后一条上
+    parts := splitMessage(message, telegramMessageLimit)
+    for i, part := range parts {
+        partOpts := opts
+        if i < len(parts)-1 {
+            partOpts.ButtonURL = ""
+        }
+        if err := sendTelegramMessage(ctx, botToken, chatID, part, partOpts); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// sendTelegramMessage 调用 sendMessage 发送单条消息，遇到限流或临时错误时重试
+func sendTelegramMessage(ctx context.Context, botToken, chatID, message string, opts telegramSendOptions) error {
+    // 拆分或截断可能切断 HTML 标签，发送前统一清理，避免 Telegram 拒绝整条消息
+    if opts.ParseMode == parseModeHTML {
+        message = sanitizeTelegramHTML(message)
+    }
+    data := url.Values{}
+    data.Set("chat_id", chatID)
+    data.Set("text", message)
+    if opts.ParseMode != "" {
+        data.Set("parse_mode", opts.ParseMode)
+    }
+    if opts.NoPreview {
+        data.Set("disable_web_page_preview", "true")
+    }
+    opts.apply(data)
+    if err := opts.setReplyMarkup(data); err != nil {
+        return err
+    }
+
+    return retryNotify(ctx, "Telegram", func() (time.Duration, bool, error) {
+        return postTelegramForm(ctx, botToken, "sendMessage", data)
+    })
+}
+
+// telegramInputMedia 对应 sendMediaGroup 中 media 数组的元素
+type telegramInputMedia struct {
+    Type      string `json:"type"`
+    Media     string `json:"media"`
+    Caption   string `json:"caption,omitempty"`
+    ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// telegramAPIURL 返回指定 Bot API 方法的地址
+func telegramAPIURL(botToken, method string) string {
+    return fmt.Sprintf("%s/bot%s/%s", telegramAPIBase, botToken, method)
+}
+
+// telegramMethod 根据图片数量选择发送方法：无图片用 sendMessage，一张用 sendPhoto，多张用 sendMediaGroup
+func telegramMethod(images []string) string {
+    switch len(images) {
+    case 0:
+        return "sendMessage"
+    case 1:
+        return "sendPhoto"
+    default:
+        return "sendMediaGroup"
+    }
+}
+
+// sendTelegramPhotos 以图片地址发送一张或一组图片，caption 非空时附在第一张图片上
+func sendTelegramPhotos(ctx context.Context, botToken, chatID string, images []string, caption string, opts telegramSendOptions) error {
+    if opts.ParseMode == parseModeHTML {
+        caption = sanitizeTelegramHTML(caption)
+    }
+    method := telegramMethod(images)
+    data := url.Values{}
+    data.Set("chat_id", chatID)
+    opts.apply(data)
+
+    if method == "sendPhoto" {
+        if err := opts.setReplyMarkup(data); err != nil {
+            return err
+        }
+        data.Set("photo", images[0])
+        if caption != "" {
+            data.Set("caption", caption)
+            if opts.ParseMode != "" {
+                data.Set("parse_mode", opts.ParseMode)
+            }
+        }
+    } else {
+        media := make([]telegramInputMedia, len(images))
+        for i, image := range images {
+            media[i] = telegramInputMedia{Type: "photo", Media: image}
+        }
+        media[0].Caption = caption
+        if caption != "" {
+            media[0].ParseMode = opts.ParseMode
+        }
+        encoded, err := json.Marshal(media)
+        if err != nil {
+            return fmt.Errorf("encode media group: %w", err)
+        }
+        data.Set("media", string(encoded))
+    }
+
+    return retryNotify(ctx, "Telegram", func() (time.Duration, bool, error) {
+        return postTelegramForm(ctx, botToken, method, data)
+    })
+}
+
+// telegramAPIError 表示 Bot API 返回的错误，Description 是 Telegram 给出的原因，例如 "Bad Request: chat not found"
+type telegramAPIError struct {
+    Method      string
+    StatusCode  int
+    ErrorCode   int
+    Description string
+}
+
+func (e *telegramAPIError) Error() string {
+    if e.Description == "" {
+        return fmt.Sprintf("telegram %s failed, status code: %d", e.Method, e.StatusCode)
+    }
+    return fmt.Sprintf("telegram %s failed, status code: %d: %s", e.Method, e.StatusCode, e.Description)
+}
+
+// postTelegramForm 调用一次 Bot API 方法，返回建议的等待时间以及该错误是否值得重试
+func postTelegramForm(ctx context.Context, botToken, method string, data url.Values) (time.Duration, bool, error) {
+    apiURL := telegramAPIURL(botToken, method)
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(data.Encode()))
+    if err != nil {
+        return 0, false, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    resp, err := notifyClient.Do(req)
+    if err != nil {
+        return 0, true, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode == http.StatusOK {
+        return 0, false, nil
+    }
+
+    // 错误响应体形如 {"ok":false,"error_code":400,"description":"..."}，解析失败时只报告状态码
+    var result telegramResponse
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+    _ = json.Unmarshal(body, &result)
+
+    err = &telegramAPIError{
+        Method:      method,
+        StatusCode:  resp.StatusCode,
+        ErrorCode:   result.ErrorCode,
+        Description: result.Description,
+    }
+    if resp.StatusCode == http.StatusTooManyRequests {
+        return telegramRetryAfter(resp.Header, result), true, err
+    }
+    return 0, resp.StatusCode >= 500, err
+}
+
+// telegramRetryAfter 从 Retry-After 响应头或响应体的 parameters.retry_after 中读取需要等待的秒数
+func telegramRetryAfter(header http.Header, result telegramResponse) time.Duration {
+    if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+        return time.Duration(seconds) * time.Second
+    }
+    if result.Parameters.RetryAfter > 0 {
+        return time.Duration(result.Parameters.RetryAfter) * time.Second
+    }
+    return 0
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "path"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+    "unicode/utf8"
+)
+
+// telegramCall 假 Bot API 收到的一次调用
+type telegramCall struct {
+    Method string
+    Form   url.Values
+}
+
+// fakeTelegram 模拟 Bot API，记录每次调用，respond 不为 nil 时由它决定响应
+type fakeTelegram struct {
+    mu      sync.Mutex
+    calls   []telegramCall
+    respond func(w http.ResponseWriter, call telegramCall, n int) bool
+}
+
+// newFakeTelegram 启动假 Bot API 并让 telegramAPIBase 指向它，测试结束时恢复
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+    t.Helper()
+    f := &fakeTelegram{}
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        if !strings.HasPrefix(r.URL.Path, "/bottoken/") {
+            t.Errorf("unexpected path %s", r.URL.Path)
+        }
+        r.ParseForm()
+        call := telegramCall{Method: path.Base(r.URL.Path), Form: r.PostForm}
+        f.mu.Lock()
+        f.calls = append(f.calls, call)
+        n := len(f.calls)
+        respond := f.respond
+        f.mu.Unlock()
+        if respond != nil && respond(w, call, n) {
+            return
+        }
+        fmt.Fprint(w, `{"ok":true}`)
+    })
+    old := telegramAPIBase
+    telegramAPIBase = srv.URL
+    t.Cleanup(func() { telegramAPIBase = old })
+    withFastRetry(t)
+    return f
+}
+
+// methods 返回按顺序调用的方法名
+func (f *fakeTelegram) methods() []string {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    var methods []string
+    for _, c := range f.calls {
+        methods = append(methods, c.Method)
+    }
+    return methods
+}
+
+// buttons 返回带有内联按钮的调用序号
+func (f *fakeTelegram) buttons() []int {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    var idx []int
+    for i, c := range f.calls {
+        if c.Form.Get("reply_markup") != "" {
+            idx = append(idx, i)
+        }
+    }
+    return idx
+}
+
+func TestSplitMessage(t *testing.T) {
+    tests := []struct {
+        name  string
+        text  string
+        limit int
+        want  []string
+    }{
+        {"fits", "short", 10, []string{"short"}},
+        {"paragraph", "aaaa\n\nbbbb", 8, []string{"aaaa", "bbbb"}},
+        {"sentence", "第一句。第二句。", 6, []string{"第一句。", "第二句。"}},
+        {"hard cut", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+        {"keeps escapes", `abc\.def`, 4, []string{"abc", `\.de`, "f"}},
+        {"keeps entities", "ab &amp; cd", 6, []string{"ab", "&amp;", "cd"}},
+    }
+    for _, tt := range tests {
+        got := splitMessage(tt.text, tt.limit)
+        if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+            t.Errorf("%s: splitMessage = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestFormatPostParseModes(t *testing.T) {
+    p := Post{URL: "https://fishc.com.cn/t_(1)", Title: "a_b *c*", Author: "鱼油", Message: "1+1=2"}
+    tests := []struct {
+        parseMode string
+        contains  []string
+    }{
+        {"", []string{"a_b *c*", "https://fishc.com.cn/t_(1)", "1+1=2"}},
+        {parseModeHTML, []string{"<b>a_b *c*</b>", `<a href="https://fishc.com.cn/t_(1)">`}},
+        {parseModeMarkdownV2, []string{`*a\_b \*c\**`, `(https://fishc.com.cn/t_(1\))`, `1\+1\=2`}},
+        {parseModeMarkdown, []string{"*a_b c*", `1+1=2`}},
+    }
+    for _, tt := range tests {
+        got := formatPost(p, tt.parseMode)
+        for _, want := range tt.contains {
+            if !strings.Contains(got, want) {
+                t.Errorf("formatPost(%q) = %q, want it to contain %q", tt.parseMode, got, want)
+            }
+        }
+        if !strings.Contains(got, "鱼油") || strings.Contains(got, msg("label.time")) {
+            t.Errorf("formatPost(%q) = %q, author shown and empty time omitted", tt.parseMode, got)
+        }
+    }
+}
+
+func TestFormatPost(t *testing.T) {
+    p := Post{URL: "https://fishc.com.cn/thread-1-1-1.html", Title: "新手求助", Message: "代码报错了"}
+    want := "标题: 新手求助\n链接: https://fishc.com.cn/thread-1-1-1.html\n帖子内容: 代码报错了"
+    if got := formatPost(p, ""); got != want {
+        t.Errorf("formatPost = %q, want %q", got, want)
+    }
+
+    p.Author, p.Time = "小甲鱼", "2024-5-12 10:30"
+    want = "标题: 新手求助\n链接: https://fishc.com.cn/thread-1-1-1.html\n作者: 小甲鱼\n时间: 2024-5-12 10:30\n帖子内容: 代码报错了"
+    if got := formatPost(p, ""); got != want {
+        t.Errorf("formatPost with author = %q, want %q", got, want)
+    }
+}
+
+func TestEscapeTelegram(t *testing.T) {
+    tests := []struct {
+        text      string
+        parseMode string
+        want      string
+    }{
+        {"snake_case", parseModeMarkdownV2, `snake\_case`},
+        {"*bold*", parseModeMarkdownV2, `\*bold\*`},
+        {"[link](x)", parseModeMarkdownV2, `\[link\]\(x\)`},
+        {"v1.2!", parseModeMarkdownV2, `v1\.2\!`},
+        {`a\b`, parseModeMarkdownV2, `a\\b`},
+        {"snake_case *x* [y]", parseModeMarkdown, `snake\_case \*x\* \[y]`},
+        {"a < b & c", parseModeHTML, "a &lt; b &amp; c"},
+        {"a_b.c", "", "a_b.c"},
+    }
+    for _, tt := range tests {
+        if got := escapeTelegram(tt.text, tt.parseMode); got != tt.want {
+            t.Errorf("escapeTelegram(%q, %q) = %q, want %q", tt.text, tt.parseMode, got, tt.want)
+        }
+    }
+}
+
+func TestValidParseMode(t *testing.T) {
+    for _, mode := range []string{"", parseModeMarkdown, parseModeMarkdownV2, parseModeHTML} {
+        if !validParseMode(mode) {
+            t.Errorf("validParseMode(%q) = false", mode)
+        }
+    }
+    if validParseMode("markdown") {
+        t.Error("parse modes are case-sensitive in the Bot API")
+    }
+}
+
+func TestSplitMessageLongPost(t *testing.T) {
+    p := Post{URL: "https://fishc.com.cn/thread-1-1-1.html", Title: "长帖", Message: strings.Repeat("鱼C论坛的帖子内容。", 1000)}
+    message := formatPost(p, "")
+    if n := utf8.RuneCountInString(message); n < 10000 {
+        t.Fatalf("message has %d runes, want a 10k-character message", n)
+    }
+
+    parts := splitMessage(message, telegramMessageLimit)
+    if len(parts) < 3 {
+        t.Fatalf("got %d parts, want at least 3", len(parts))
+    }
+    if !strings.HasPrefix(parts[0], "标题: 长帖\n链接: "+p.URL) {
+        t.Errorf("first part does not start with the header: %.40q", parts[0])
+    }
+    for i, part := range parts {
+        if n := utf8.RuneCountInString(part); n > telegramMessageLimit {
+            t.Errorf("part %d has %d runes, limit is %d", i, n, telegramMessageLimit)
+        }
+        if !utf8.ValidString(part) {
+            t.Errorf("part %d is not valid UTF-8", i)
+        }
+    }
+    if got := strings.Join(parts, ""); strings.Count(got, "鱼C论坛的帖子内容。") != 1000 {
+        t.Error("splitting lost part of the message")
+    }
+}
+
+func TestTelegramImageMethods(t *testing.T) {
+    tests := []struct {
+        name    string
+        images  []string
+        message string
+        methods string
+    }{
+        {"text only", nil, "短消息", "sendMessage"},
+        {"photo with caption", []string{"https://img/a.png"}, "短消息", "sendPhoto"},
+        {"caption overflow", []string{"https://img/a.png"}, strings.Repeat("长", telegramCaptionLimit+1), "sendPhoto sendMessage"},
+        {"media group", []string{"https://img/a.png", "https://img/b.png"}, "短消息", "sendMediaGroup"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tg := newFakeTelegram(t)
+            if err := sendToTelegram(context.Background(), "token", "1", tt.images, tt.message, telegramSendOptions{}); err != nil {
+                t.Fatal(err)
+            }
+            if got := strings.Join(tg.methods(), " "); got != tt.methods {
+                t.Errorf("methods = %q, want %q", got, tt.methods)
+            }
+        })
+    }
+}
+
+func TestTelegramMediaGroupCaptionWithoutButton(t *testing.T) {
+    tg := newFakeTelegram(t)
+    images := []string{"https://img/a.png", "https://img/b.png"}
+    if err := sendToTelegram(context.Background(), "token", "1", images, "说明", telegramSendOptions{}); err != nil {
+        t.Fatal(err)
+    }
+    if got := strings.Join(tg.methods(), " "); got != "sendMediaGroup" {
+        t.Fatalf("methods = %q", got)
+    }
+    if media := tg.calls[0].Form.Get("media"); !strings.Contains(media, `"caption":"说明"`) {
+        t.Errorf("media = %s", media)
+    }
+}
+
+func TestTelegramPhotoFailureFallsBackToText(t *testing.T) {
+    tg := newFakeTelegram(t)
+    tg.respond = func(w http.ResponseWriter, call telegramCall, _ int) bool {
+        if call.Method == "sendPhoto" {
+            w.WriteHeader(http.StatusBadRequest)
+            fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: wrong file identifier"}`)
+            return true
+        }
+        return false
+    }
+    if err := sendToTelegram(context.Background(), "token", "1", []string{"https://img/a.png"}, "正文", telegramSendOptions{}); err != nil {
+        t.Fatal(err)
+    }
+    if got := strings.Join(tg.methods(), " "); got != "sendPhoto sendMessage" {
+        t.Errorf("methods = %q", got)
+    }
+}
+
+func TestTelegramRetriesServerErrors(t *testing.T) {
+    tg := newFakeTelegram(t)
+    tg.respond = func(w http.ResponseWriter, _ telegramCall, n int) bool {
+        if n < 3 {
+            w.WriteHeader(http.StatusBadGateway)
+            return true
+        }
+        return false
+    }
+    if err := sendTelegramMessage(context.Background(), "token", "1", "hi", telegramSendOptions{}); err != nil {
+        t.Fatal(err)
+    }
+    if len(tg.calls) != 3 {
+        t.Errorf("calls = %d, want 3", len(tg.calls))
+    }
+}
+
+func TestTelegramWaitsForRetryAfter(t *testing.T) {
+    tg := newFakeTelegram(t)
+    tg.respond = func(w http.ResponseWriter, _ telegramCall, n int) bool {
+        if n == 1 {
+            w.Header().Set("Retry-After", "1")
+            w.WriteHeader(http.StatusTooManyRequests)
+            fmt.Fprint(w, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1"}`)
+            return true
+        }
+        return false
+    }
+    start := time.Now()
+    if err := sendTelegramMessage(context.Background(), "token", "1", "hi", telegramSendOptions{}); err != nil {
+        t.Fatal(err)
+    }
+    if elapsed := time.Since(start); elapsed < time.Second {
+        t.Errorf("retried after %v, want the 1s Retry-After respected", elapsed)
+    }
+    if len(tg.calls) != 2 {
+        t.Errorf("calls = %d, want 2", len(tg.calls))
+    }
+}
+
+func TestTelegramReportsAPIErrorDescription(t *testing.T) {
+    tg := newFakeTelegram(t)
+    tg.respond = func(w http.ResponseWriter, _ telegramCall, _ int) bool {
+        w.WriteHeader(http.StatusBadRequest)
+        fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+        return true
+    }
+    err := sendTelegramMessage(context.Background(), "token", "1", "hi", telegramSendOptions{})
+    var apiErr *telegramAPIError
+    if !errors.As(err, &apiErr) || apiErr.Description != "Bad Request: chat not found" || apiErr.ErrorCode != 400 {
+        t.Fatalf("err = %v", err)
+    }
+    if !strings.Contains(err.Error(), "chat not found") {
+        t.Errorf("Error() = %q", err.Error())
+    }
+    if len(tg.calls) != 1 {
+        t.Errorf("calls = %d, client errors must not be retried", len(tg.calls))
+    }
+}
+
+func TestPostTelegramFormTimesOut(t *testing.T) {
+    withNotifyTimeout(t, 50*time.Millisecond)
+    srv := newStalledServer(t)
+    old := telegramAPIBase
+    telegramAPIBase = srv.URL
+    t.Cleanup(func() { telegramAPIBase = old })
+
+    _, retry, err := postTelegramForm(context.Background(), "token", "sendMessage", url.Values{"text": {"hi"}})
+    if err == nil || !retry {
+        t.Errorf("postTelegramForm() = %v, retry %v, want a retryable timeout", err, retry)
+    }
+}
+
+func TestBroadcastReachesEveryChat(t *testing.T) {
+    tg := newFakeTelegram(t)
+    tg.respond = func(w http.ResponseWriter, call telegramCall, _ int) bool {
+        if call.Form.Get("chat_id") == "bad" {
+            w.WriteHeader(http.StatusBadRequest)
+            return true
+        }
+        return false
+    }
+    err := broadcast(context.Background(), "token", []string{"1", "bad", "2"}, nil, "hi", telegramSendOptions{})
+    if err == nil || !strings.Contains(err.Error(), "chat bad") {
+        t.Errorf("err = %v, want the failing chat reported", err)
+    }
+    var chats []string
+    for _, c := range tg.calls {
+        chats = append(chats, c.Form.Get("chat_id"))
+    }
+    if strings.Join(chats, ",") != "1,bad,2" {
+        t.Errorf("sent to %q, want every chat", chats)
+    }
+}
+
+func TestTelegramNotifySendsOptionsToEveryChat(t *testing.T) {
+    tg := newFakeTelegram(t)
+    n := &TelegramNotifier{
+        BotToken:  "token",
+        ChatIDs:   []string{"-1001", "@fishc_news"},
+        ParseMode: parseModeHTML,
+        Silent:    true,
+        NoPreview: true,
+        ThreadID:  42,
+    }
+    if err := n.Notify(context.Background(), Post{URL: "https://fishc.com.cn/t?a=1&b=2", Title: "<Go>", Message: "正文"}); err != nil {
+        t.Fatal(err)
+    }
+    if len(tg.calls) != 2 {
+        t.Fatalf("calls = %v", tg.methods())
+    }
+    for i, chat := range []string{"-1001", "@fishc_news"} {
+        form := tg.calls[i].Form
+        if tg.calls[i].Method != "sendMessage" || form.Get("chat_id") != chat {
+            t.Errorf("call %d = %s %s", i, tg.calls[i].Method, form.Get("chat_id"))
+        }
+        if form.Get("parse_mode") != "HTML" || form.Get("disable_notification") != "true" ||
+            form.Get("disable_web_page_preview") != "true" || form.Get("message_thread_id") != "42" {
+            t.Errorf("call %d options = %v", i, form)
+        }
+        if !strings.Contains(form.Get("text"), "<b>&lt;Go&gt;</b>") || !strings.Contains(form.Get("text"), "a=1&amp;b=2") {
+            t.Errorf("text = %q, fields must be escaped", form.Get("text"))
+        }
+    }
+}
+
+func TestTelegramNotifySplitsLongMessages(t *testing.T) {
+    tg := newFakeTelegram(t)
+    n := &TelegramNotifier{BotToken: "token", ChatIDs: []string{"1"}, Buttons: true}
+    long := strings.Repeat("一段很长的内容。", 1000)
+    if err := n.Notify(context.Background(), Post{URL: "https://fishc.com.cn/t", Title: "长帖", Message: long}); err != nil {
+        t.Fatal(err)
+    }
+    if len(tg.calls) < 2 {
+        t.Fatalf("calls = %v, want the message split", tg.methods())
+    }
+    for i, c := range tg.calls {
+        if l := utf8.RuneCountInString(c.Form.Get("text")); l > telegramMessageLimit {
+            t.Errorf("part %d has %d characters", i, l)
+        }
+    }
+    if got := tg.buttons(); len(got) != 1 || got[0] != len(tg.calls)-1 {
+        t.Errorf("buttons on calls %v, want only the last", got)
+    }
+}
+
+func TestTelegramButtonOnFinalMessage(t *testing.T) {
+    tests := []struct {
+        name    string
+        images  []string
+        message string
+        methods string
+    }{
+        {"photo with caption", []string{"https://img/a.png"}, "短消息", "sendPhoto"},
+        {"caption overflow", []string{"https://img/a.png"}, strings.Repeat("长", telegramCaptionLimit+1), "sendPhoto sendMessage"},
+        {"media group", []string{"https://img/a.png", "https://img/b.png"}, "短消息", "sendMediaGroup sendMessage"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tg := newFakeTelegram(t)
+            opts := telegramSendOptions{ButtonURL: "https://fishc.com.cn/t"}
+            if err := sendToTelegram(context.Background(), "token", "1", tt.images, tt.message, opts); err != nil {
+                t.Fatal(err)
+            }
+            if got := strings.Join(tg.methods(), " "); got != tt.methods {
+                t.Errorf("methods = %q, want %q", got, tt.methods)
+            }
+            if got := tg.buttons(); len(got) != 1 || got[0] != len(tg.calls)-1 {
+                t.Errorf("buttons on calls %v, want exactly one on the last message", got)
+            }
+        })
+    }
+}
+
+func TestTelegramRetryAfter(t *testing.T) {
+    withBody := telegramResponse{}
+    withBody.Parameters.RetryAfter = 3
+    tests := []struct {
+        name   string
+        header string
+        result telegramResponse
+        want   int
+    }{
+        {"header", "5", telegramResponse{}, 5},
+        {"body", "", withBody, 3},
+        {"header wins", "2", withBody, 2},
+        {"none", "", telegramResponse{}, 0},
+    }
+    for _, tt := range tests {
+        header := http.Header{}
+        if tt.header != "" {
+            header.Set("Retry-After", tt.header)
+        }
+        if got := telegramRetryAfter(header, tt.result); int(got.Seconds()) != tt.want {
+            t.Errorf("%s: telegramRetryAfter = %v, want %ds", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestNormalizeChatID(t *testing.T) {
+    tests := []struct {
+        in      string
+        want    string
+        wantErr bool
+    }{
+        {"123456", "123456", false},
+        {" -1001234567890 ", "-1001234567890", false},
+        {"@fishc_news", "@fishc_news", false},
+        {"https://t.me/fishc_news/", "@fishc_news", false},
+        {"t.me/fishc_news", "@fishc_news", false},
+        {"1001234567890", "", true},
+        {"@abc", "", true},
+        {"@1abcdef", "", true},
+        {"abc", "", true},
+        {"-", "", true},
+    }
+    for _, tt := range tests {
+        got, err := normalizeChatID(tt.in)
+        if got != tt.want || (err != nil) != tt.wantErr {
+            t.Errorf("normalizeChatID(%q) = %q, %v, want %q, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+        }
+    }
+}
+
+func TestEscapeMessageKeepsMatchingFormat(t *testing.T) {
+    tests := []struct {
+        name      string
+        post      Post
+        parseMode string
+        want      string
+    }{
+        {"html in html", Post{Format: formatHTML, Message: "<b>x</b>"}, parseModeHTML, "<b>x</b>"},
+        {"html in plain", Post{Format: formatHTML, Message: "<b>x</b> &amp; y"}, "", "x & y"},
+        {"markdown in v2", Post{Format: formatMarkdown, Message: `*x\.*`}, parseModeMarkdownV2, `*x\.*`},
+        {"markdown in html", Post{Format: formatMarkdown, Message: `*x\.*`}, parseModeHTML, "*x.*"},
+        {"plain in html", Post{Message: "a<b"}, parseModeHTML, "a&lt;b"},
+    }
+    for _, tt := range tests {
+        if got := escapeMessage(tt.post, tt.parseMode); got != tt.want {
+            t.Errorf("%s: escapeMessage = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestParseTemplate(t *testing.T) {
+    if tmpl, err := parseTemplate(""); tmpl != nil || err != nil {
+        t.Errorf("empty template = %v, %v", tmpl, err)
+    }
+    for _, bad := range []string{"{{.Title", "{{.Missing}}"} {
+        if _, err := parseTemplate(bad); err == nil {
+            t.Errorf("parseTemplate(%q) succeeded", bad)
+        }
+    }
+
+    tmpl, err := parseTemplate(`<b>{{.Title}}</b>\n{{.URL}}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    got := renderPost(tmpl, Post{Title: "a<b", URL: "https://fishc.com.cn/t"}, parseModeHTML)
+    if want := "<b>a&lt;b</b>\nhttps://fishc.com.cn/t"; got != want {
+        t.Errorf("renderPost = %q, want %q", got, want)
+    }
+}
+
+func TestRenderPostCounts(t *testing.T) {
+    tmpl, err := parseTemplate(`{{.Title}} ({{.Replies}}/{{.Views}}){{if .Sticky}} top{{end}}`)
+    if err != nil {
+        t.Fatal(err)
+    }
+    got := renderPost(tmpl, Post{Title: "t", Replies: 3, Views: 120, Sticky: true}, "")
+    if want := "t (3/120) top"; got != want {
+        t.Errorf("renderPost = %q, want %q", got, want)
+    }
+}
+
+func TestFormatBatch(t *testing.T) {
+    got := formatBatch(nil, []Post{{Title: "一"}, {Title: "二"}}, "")
+    if strings.Count(got, batchSeparator) != 1 || !strings.Contains(got, "一") || !strings.Contains(got, "二") {
+        t.Errorf("formatBatch = %q", got)
+    }
+}
+
+func TestTelegramNotifyBatchSendsOneMessage(t *testing.T) {
+    tg := newFakeTelegram(t)
+    n := &TelegramNotifier{BotToken: "token", ChatIDs: []string{"1"}, Buttons: true}
+    posts := []Post{{URL: "https://fishc.com.cn/1", Title: "一", Images: []string{"https://img/a.png"}}, {URL: "https://fishc.com.cn/2", Title: "二"}}
+    if err := n.NotifyBatch(context.Background(), posts); err != nil {
+        t.Fatal(err)
+    }
+    if got := strings.Join(tg.methods(), " "); got != "sendMessage" {
+        t.Errorf("methods = %q, want a single text message", got)
+    }
+    if len(tg.buttons()) != 0 {
+        t.Error("batched messages must not carry a single post button")
+    }
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+    "log/slog"
+    "strings"
+
+    "github.com/valyala/fasthttp"
+)
+
+// traceFetch 为 true 时在 debug 级别记录每次抓取的完整请求和响应，由 -trace 设置
+var traceFetch bool
+
+// traceBodyLimit 记录响应体的最大字节数
+const traceBodyLimit = 500
+
+// sensitiveHeaders 记录时隐藏值的请求头和响应头（小写）
+var sensitiveHeaders = map[string]bool{
+    "cookie":              true,
+    "set-cookie":          true,
+    "authorization":       true,
+    "proxy-authorization": true,
+}
+
+// traceExchange 记录一次请求和响应，请求中的 Cookie、Authorization 等敏感头只保留名称
+func traceExchange(req *fasthttp.Request, resp *fasthttp.Response) {
+    if !traceFetch {
+        return
+    }
+    var reqHeaders, respHeaders []string
+    req.Header.VisitAll(func(key, value []byte) {
+        reqHeaders = append(reqHeaders, traceHeader(string(key), string(value)))
+    })
+    resp.Header.VisitAll(func(key, value []byte) {
+        respHeaders = append(respHeaders, traceHeader(string(key), string(value)))
+    })
+    body, err := responseBody(resp)
+    if err != nil {
+        body = resp.Body()
+    }
+    slog.Debug("抓取请求",
+        "method", string(req.Header.Method()),
+        "url", req.URI().String(),
+        "headers", reqHeaders)
+    slog.Debug("抓取响应",
+        "url", req.URI().String(),
+        "status", resp.StatusCode(),
+        "headers", respHeaders,
+        "body_len", len(body),
+        "body", string(body[:min(len(body), traceBodyLimit)]))
+}
+
+// traceHeader 格式化一个头部，敏感头的值替换为 [REDACTED]
+func traceHeader(key, value string) string {
+    if sensitiveHeaders[strings.ToLower(key)] {
+        value = "[REDACTED]"
+    }
+    return key + ": " + value
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestTraceHeader(t *testing.T) {
+    tests := []struct {
+        key, value, want string
+    }{
+        {"User-Agent", "yuc", "User-Agent: yuc"},
+        {"Cookie", "sid=abc", "Cookie: [REDACTED]"},
+        {"set-cookie", "sid=abc", "set-cookie: [REDACTED]"},
+        {"Authorization", "Basic dXNlcjpwYXNz", "Authorization: [REDACTED]"},
+        {"Proxy-Authorization", "Basic x", "Proxy-Authorization: [REDACTED]"},
+    }
+    for _, tt := range tests {
+        if got := traceHeader(tt.key, tt.value); got != tt.want {
+            t.Errorf("traceHeader(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+        }
+    }
+}
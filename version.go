@@ -0,0 +1,17 @@
+package main
+
+import "fmt"
+
+// 构建信息，发布时通过 -ldflags 注入，例如：
+//
+//    go build -ldflags "-X main.version=v1.0.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+    version   = "dev"
+    commit    = "unknown"
+    buildDate = "unknown"
+)
+
+// versionString 返回包含版本号、提交和构建时间的一行文本
+func versionString() string {
+    return fmt.Sprintf("yuc %s (commit %s, built %s)", version, commit, buildDate)
+}
@@ -0,0 +1,13 @@
+package main
+
+import "testing"
+
+func TestVersionString(t *testing.T) {
+    saved := []string{version, commit, buildDate}
+    t.Cleanup(func() { version, commit, buildDate = saved[0], saved[1], saved[2] })
+
+    version, commit, buildDate = "v1.2.3", "abc1234", "2024-05-12T00:00:00Z"
+    if got, want := versionString(), "yuc v1.2.3 (commit abc1234, built 2024-05-12T00:00:00Z)"; got != want {
+        t.Errorf("versionString() = %q, want %q", got, want)
+    }
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "strings"
+)
+
+// editWatcher 定期重新获取关注的帖子，首帖内容变化时发送通知，通知正文只包含新增的行。
+// 内容的哈希记录在去重存储中，重启后沿用；第一次获取某个帖子只作为比较基准
+type editWatcher struct {
+    urls      []string
+    fetcher   Fetcher
+    selectors Selectors
+    format    string
+    maxLen    int
+    // live 提供可重新加载的检查间隔和通知渠道
+    live *liveSettings
+    // store 记录每个帖子出现过的内容哈希，last 为本次运行中上次获取到的内容，用于计算新增的行
+    store SeenStore
+    last  map[string]Post
+}
+
+// watchStoreKey 关注帖子在去重存储中使用的论坛键，与论坛 URL 区分开
+const watchStoreKey = "watch:"
+
+// newEditWatcher 创建关注 urls 中帖子的 editWatcher
+func newEditWatcher(urls []string, fetcher Fetcher, selectors Selectors, format string, maxLen int, live *liveSettings, store SeenStore) *editWatcher {
+    return &editWatcher{
+        urls:      urls,
+        fetcher:   fetcher,
+        selectors: selectors,
+        format:    format,
+        maxLen:    maxLen,
+        live:      live,
+        store:     store,
+        last:      make(map[string]Post),
+    }
+}
+
+// run 每隔 live 中的检查间隔检查一次所有关注的帖子，直到 ctx 被取消
+func (w *editWatcher) run(ctx context.Context) {
+    for ctx.Err() == nil {
+        w.check(ctx)
+        interval, _ := w.live.watch()
+        sleepContext(ctx, interval)
+    }
+}
+
+// check 获取每个关注的帖子，内容哈希未出现过时发送更新通知，通知成功（包括静默时段暂存）后才记录新的哈希，
+// 发送失败时下次检查会重试
+func (w *editWatcher) check(ctx context.Context) {
+    _, notifier := w.live.watch()
+    for _, postURL := range w.urls {
+        post, err := parsePostContent(ctx, w.fetcher, postURL, w.selectors, w.format)
+        if err != nil {
+            slog.Error("获取帖子内容失败", "post_url", postURL, "err", err)
+            continue
+        }
+        if post.Message == msg("content.missing") {
+            // 获取失败时保留上次的内容，避免恢复后误报
+            continue
+        }
+        hashKey := postURL + "#" + contentHash(post)
+        known, err := w.store.Seen(hashKey)
+        if err != nil {
+            slog.Error("读取帖子内容记录失败", "post_url", postURL, "err", err)
+            continue
+        }
+        if known {
+            w.last[postURL] = post
+            continue
+        }
+        baseline, err := w.store.Seen(postURL)
+        if err != nil {
+            slog.Error("读取帖子内容记录失败", "post_url", postURL, "err", err)
+            continue
+        }
+        if !baseline {
+            // 第一次获取该帖子，记录为比较基准
+            w.remember(postURL, hashKey, post)
+            continue
+        }
+
+        prev := w.last[postURL]
+        changed := post
+        changed.Title = fmt.Sprintf(msg("edit.title"), post.Title)
+        changed.Message = truncateMessage(addedLines(prev.Message, post.Message), post.URL, w.maxLen)
+        if err := notifier.Notify(ctx, changed); err != nil && !errors.Is(err, errHeld) {
+            slog.Error("发送帖子更新通知失败", "post_url", postURL, "err", err)
+            continue
+        }
+        w.remember(postURL, hashKey, post)
+        slog.Info("帖子内容已更新", "post_url", postURL)
+    }
+}
+
+// remember 记录帖子当前的内容，postURL 本身也会被记录，表示该帖子已有比较基准
+func (w *editWatcher) remember(postURL, hashKey string, post Post) {
+    w.last[postURL] = post
+    for _, key := range []string{postURL, hashKey} {
+        if err := w.store.Mark(key, post); err != nil {
+            slog.Error("保存帖子内容记录失败", "post_url", postURL, "err", err)
+        }
+    }
+}
+
+// addedLines 返回 new 中 old 没有的行，只有删减时返回完整的新内容
+func addedLines(old, new string) string {
+    seen := make(map[string]bool)
+    for _, line := range strings.Split(old, "\n") {
+        seen[strings.TrimSpace(line)] = true
+    }
+    var added []string
+    for _, line := range strings.Split(new, "\n") {
+        if trimmed := strings.TrimSpace(line); trimmed != "" && !seen[trimmed] {
+            added = append(added, line)
+        }
+    }
+    if len(added) == 0 {
+        return new
+    }
+    return strings.Join(added, "\n")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+)
+
+const watchedURL = "https://forum.example/thread-7.html"
+
+// newTestWatcher 返回关注 watchedURL 的 editWatcher，通知发往 notifier
+func newTestWatcher(fetcher Fetcher, notifier Notifier, store SeenStore) *editWatcher {
+    live := &liveSettings{}
+    live.set(time.Minute, 0, time.Minute, nil, notifier, nil)
+    return newEditWatcher([]string{watchedURL}, fetcher, testSelectors, formatPlain, 0, live, store)
+}
+
+func TestEditWatcherNotifiesAddedLines(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(watchedURL, postPage("标题", "第一行"))
+    notifier := &recordingNotifier{}
+    w := newTestWatcher(fetcher, notifier, newMemoryStore())
+
+    w.check(context.Background())
+    w.check(context.Background())
+    if len(notifier.posts) != 0 {
+        t.Fatalf("unchanged post notified: %+v", notifier.posts)
+    }
+
+    fetcher.set(watchedURL, postPage("标题", "第一行\n第二行"))
+    w.check(context.Background())
+    if len(notifier.posts) != 1 {
+        t.Fatalf("posts = %d, want one edit notification", len(notifier.posts))
+    }
+    p := notifier.posts[0]
+    if !strings.Contains(p.Title, "标题") || p.Title == "标题" {
+        t.Errorf("title = %q, want the edit title format", p.Title)
+    }
+    if p.Message != "第二行" {
+        t.Errorf("message = %q, want only the added line", p.Message)
+    }
+}
+
+func TestEditWatcherBaselinePersists(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(watchedURL, postPage("标题", "原文"))
+    store := newMemoryStore()
+    newTestWatcher(fetcher, &recordingNotifier{}, store).check(context.Background())
+
+    // 重启后沿用存储中的基准，停机期间的修改仍会通知
+    fetcher.set(watchedURL, postPage("标题", "修改后"))
+    notifier := &recordingNotifier{}
+    newTestWatcher(fetcher, notifier, store).check(context.Background())
+    if len(notifier.posts) != 1 {
+        t.Fatalf("posts = %d, want the edit made while stopped", len(notifier.posts))
+    }
+
+    notifier = &recordingNotifier{}
+    newTestWatcher(fetcher, notifier, store).check(context.Background())
+    if len(notifier.posts) != 0 {
+        t.Error("already notified content was notified again after a restart")
+    }
+}
+
+func TestEditWatcherRetriesFailedNotification(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(watchedURL, postPage("标题", "原文"))
+    notifier := &recordingNotifier{err: errors.New("down")}
+    w := newTestWatcher(fetcher, notifier, newMemoryStore())
+    w.check(context.Background())
+
+    fetcher.set(watchedURL, postPage("标题", "修改后"))
+    w.check(context.Background())
+    notifier.err = nil
+    w.check(context.Background())
+    if len(notifier.posts) != 2 {
+        t.Errorf("posts = %d, want the failed notification retried", len(notifier.posts))
+    }
+    w.check(context.Background())
+    if len(notifier.posts) != 2 {
+        t.Error("edit was notified again after succeeding")
+    }
+}
+
+func TestEditWatcherHeldCountsAsSent(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(watchedURL, postPage("标题", "原文"))
+    notifier := &recordingNotifier{err: errHeld}
+    w := newTestWatcher(fetcher, notifier, newMemoryStore())
+    w.check(context.Background())
+    fetcher.set(watchedURL, postPage("标题", "修改后"))
+    w.check(context.Background())
+    w.check(context.Background())
+    if len(notifier.posts) != 1 {
+        t.Errorf("posts = %d, want a held notification not to be repeated", len(notifier.posts))
+    }
+}
+
+func TestEditWatcherIgnoresFetchFailures(t *testing.T) {
+    fetcher := newSiteFetcher()
+    fetcher.set(watchedURL, postPage("标题", "原文"))
+    notifier := &recordingNotifier{}
+    w := newTestWatcher(fetcher, notifier, newMemoryStore())
+    w.check(context.Background())
+
+    fetcher.fail(watchedURL, errors.New("timeout"))
+    w.check(context.Background())
+    fetcher.fail(watchedURL, nil)
+    fetcher.set(watchedURL, "<h1>标题</h1><p>登录后可见</p>")
+    w.check(context.Background())
+    fetcher.set(watchedURL, postPage("标题", "原文"))
+    w.check(context.Background())
+    if len(notifier.posts) != 0 {
+        t.Errorf("temporary failures caused notifications: %+v", notifier.posts)
+    }
+}
+
+func TestAddedLines(t *testing.T) {
+    tests := []struct {
+        old, new, want string
+    }{
+        {"a\nb", "a\nb\nc", "c"},
+        {"a\nb", "x\na\ny", "x\ny"},
+        {"a\nb\nc", "a\nc", "a\nc"},
+        {"a", "  a  \n\nb", "b"},
+        {"", "a", "a"},
+    }
+    for _, tt := range tests {
+        if got := addedLines(tt.old, tt.new); got != tt.want {
+            t.Errorf("addedLines(%q, %q) = %q, want %q", tt.old, tt.new, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// WebhookNotifier 将帖子以 JSON 格式推送到自定义的 Webhook 地址
+type WebhookNotifier struct {
+    URL     string
+    Headers http.Header
+}
+
+// webhookPayload Webhook 请求体的 JSON 结构
+type webhookPayload struct {
+    URL       string `json:"url"`
+    Title     string `json:"title"`
+    Message   string `json:"message"`
+    Author    string `json:"author"`
+    Timestamp string `json:"timestamp"`
+}
+
+// newWebhookPayload 把帖子转换为 Webhook 的 JSON 结构，正文去掉 Telegram 格式
+func newWebhookPayload(p Post) webhookPayload {
+    return webhookPayload{
+        URL:       p.URL,
+        Title:     p.Title,
+        Message:   plainMessage(p),
+        Author:    p.Author,
+        Timestamp: p.Time,
+    }
+}
+
+// Notify 推送帖子，任何 2xx 状态码视为成功，5xx 时重试
+func (n *WebhookNotifier) Notify(ctx context.Context, p Post) error {
+    payload := newWebhookPayload(p)
+
+    return retryNotify(ctx, "Webhook", func() (time.Duration, bool, error) {
+        resp, err := postJSON(ctx, n.URL, n.Headers, payload)
+        if err != nil {
+            return 0, true, err
+        }
+        defer resp.Body.Close()
+
+        if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+            return 0, false, nil
+        }
+        err = fmt.Errorf("failed to send webhook, status code: %d", resp.StatusCode)
+        return 0, resp.StatusCode >= 500, err
+    })
+}
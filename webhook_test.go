@@ -0,0 +1,93 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "sync"
+    "testing"
+)
+
+// fakeWebhook 记录收到的 JSON 请求体和请求头，status 不为 nil 时按第 n 次请求返回状态码
+type fakeWebhook struct {
+    mu      sync.Mutex
+    bodies  [][]byte
+    headers []http.Header
+    status  func(n int) int
+}
+
+// newFakeWebhook 启动接收 JSON 的测试服务，返回服务地址
+func newFakeWebhook(t *testing.T) (*fakeWebhook, string) {
+    t.Helper()
+    f := &fakeWebhook{}
+    srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        f.mu.Lock()
+        f.bodies = append(f.bodies, body)
+        f.headers = append(f.headers, r.Header.Clone())
+        n := len(f.bodies)
+        f.mu.Unlock()
+        if r.Header.Get("Content-Type") != "application/json" {
+            t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+        }
+        if f.status != nil {
+            w.WriteHeader(f.status(n))
+        }
+    })
+    withFastRetry(t)
+    return f, srv.URL
+}
+
+// decode 把第 i 次请求的请求体解析到 v
+func (f *fakeWebhook) decode(t *testing.T, i int, v any) {
+    t.Helper()
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if i >= len(f.bodies) {
+        t.Fatalf("only %d requests received", len(f.bodies))
+    }
+    if err := json.Unmarshal(f.bodies[i], v); err != nil {
+        t.Fatalf("decode %s: %v", f.bodies[i], err)
+    }
+}
+
+func TestWebhookNotifierPostsPayload(t *testing.T) {
+    hook, url := newFakeWebhook(t)
+    n := &WebhookNotifier{URL: url, Headers: http.Header{"Authorization": {"Bearer secret"}}}
+    post := Post{URL: "https://fishc.com.cn/t", Title: "标题", Message: "<b>正文</b> &amp; 更多", Author: "鱼油", Time: "2024-5-12 10:20", Format: formatHTML}
+    if err := n.Notify(context.Background(), post); err != nil {
+        t.Fatal(err)
+    }
+
+    var got webhookPayload
+    hook.decode(t, 0, &got)
+    want := webhookPayload{URL: post.URL, Title: "标题", Message: "正文 & 更多", Author: "鱼油", Timestamp: "2024-5-12 10:20"}
+    if got != want {
+        t.Errorf("payload = %+v, want %+v", got, want)
+    }
+    if auth := hook.headers[0].Get("Authorization"); auth != "Bearer secret" {
+        t.Errorf("Authorization = %q", auth)
+    }
+}
+
+func TestWebhookNotifierRetries(t *testing.T) {
+    tests := []struct {
+        name      string
+        status    int
+        wantCalls int
+    }{
+        {"server error", http.StatusInternalServerError, notifyMaxAttempts},
+        {"client error", http.StatusBadRequest, 1},
+    }
+    for _, tt := range tests {
+        hook, url := newFakeWebhook(t)
+        hook.status = func(int) int { return tt.status }
+        if err := (&WebhookNotifier{URL: url}).Notify(context.Background(), Post{}); err == nil {
+            t.Errorf("%s: Notify succeeded", tt.name)
+        }
+        if len(hook.bodies) != tt.wantCalls {
+            t.Errorf("%s: calls = %d, want %d", tt.name, len(hook.bodies), tt.wantCalls)
+        }
+    }
+}
@@ -1,170 +1,1251 @@
 package main
 
 import (
-    "flag"
+    "context"
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/hex"
+    "errors"
     "fmt"
-    "log"
+    "log/slog"
+    "math/rand"
+    "mime"
+    "net"
     "net/http"
     "net/url"
+    "os"
+    "os/signal"
+    "regexp"
+    "slices"
+    "strconv"
     "strings"
+    "sync"
+    "syscall"
     "time"
+    "unicode"
+    "unicode/utf8"
 
     "github.com/PuerkitoBio/goquery"
+    "github.com/andybalholm/cascadia"
     "github.com/valyala/fasthttp"
+    "github.com/valyala/fasthttp/fasthttpproxy"
+    "golang.org/x/text/encoding/htmlindex"
 )
 
-// fetchPageContent 发送 HTTP 请求并获取页面内容
-func fetchPageContent(pageURL string) (string, error) {
+// httpClient 全局共享的 fasthttp 客户端，复用长连接，可被多个 goroutine 并发使用
+var httpClient = &fasthttp.Client{
+    MaxConnsPerHost:     16,
+    ReadTimeout:         15 * time.Second,
+    WriteTimeout:        15 * time.Second,
+    MaxResponseBodySize: defaultMaxBody,
+}
+
+// defaultMaxBody 响应体的默认大小上限，避免 URL 配错指向大文件时耗尽内存
+const defaultMaxBody = 8 << 20
+
+// proxyDialer 根据代理地址创建拨号函数，支持 http:// 和 socks5:// 代理
+func proxyDialer(proxyURL string) (fasthttp.DialFunc, error) {
+    u, err := url.Parse(proxyURL)
+    if err != nil {
+        return nil, fmt.Errorf("invalid proxy %q: %w", proxyURL, err)
+    }
+    if u.Host == "" {
+        return nil, fmt.Errorf("invalid proxy %q: missing host", proxyURL)
+    }
+
+    switch u.Scheme {
+    case "http", "https":
+        addr := u.Host
+        if u.User != nil {
+            addr = u.User.String() + "@" + addr
+        }
+        return fasthttpproxy.FasthttpHTTPDialer(addr), nil
+    case "socks5", "socks5h":
+        return fasthttpproxy.FasthttpSocksDialer(proxyURL), nil
+    default:
+        return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+    }
+}
+
+// -ip-version 的取值
+const (
+    ipAuto = "auto"
+    ipV4   = "4"
+    ipV6   = "6"
+)
+
+// ipDialer 返回只连接指定地址族的拨号函数，version 为 ipAuto 时返回 nil，保留 fasthttp 默认的拨号方式
+func ipDialer(version string) fasthttp.DialFunc {
+    network := map[string]string{ipV4: "tcp4", ipV6: "tcp6"}[version]
+    if network == "" {
+        return nil
+    }
+    dialer := &net.Dialer{Timeout: fasthttp.DefaultDialTimeout}
+    return func(addr string) (net.Conn, error) {
+        return dialer.Dial(network, addr)
+    }
+}
+
+// tlsConfig 根据自定义 CA 文件和是否跳过证书校验创建 TLS 配置，caFile 中的证书会加入系统根证书之外
+func tlsConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+    cfg := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+    if caFile == "" {
+        return cfg, nil
+    }
+
+    pem, err := os.ReadFile(caFile)
+    if err != nil {
+        return nil, fmt.Errorf("read ca file: %w", err)
+    }
+    pool, err := x509.SystemCertPool()
+    if err != nil {
+        pool = x509.NewCertPool()
+    }
+    if !pool.AppendCertsFromPEM(pem) {
+        return nil, fmt.Errorf("no certificates found in ca file %s", caFile)
+    }
+    cfg.RootCAs = pool
+    return cfg, nil
+}
+
+// fetchTimeout 单次页面请求的超时时间，防止服务器无响应时阻塞整个监控循环
+var fetchTimeout = 15 * time.Second
+
+// defaultUserAgent 默认使用的浏览器 User-Agent，避免被论坛识别为爬虫
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36"
+
+// requestHeaders 附加到每个论坛请求上的自定义请求头，例如 Referer 或 Accept-Language
+var requestHeaders http.Header
+
+// defaultForumURL 默认监控的鱼C论坛最新帖子页面
+const defaultForumURL = "https://fishc.com.cn/forum.php?mod=guide&view=newthread&mobile=2"
+
+// retryBaseDelay 重试的初始等待时间，之后每次重试翻倍
+var retryBaseDelay = 500 * time.Millisecond
+
+// maxRedirects 跟随重定向的最大次数
+const maxRedirects = 10
+
+// page 表示一次成功获取的页面
+type page struct {
+    // URL 是跟随重定向后的最终地址，用于解析页面中的相对链接
+    URL     string
+    Content string
+
+    // ETag 和 LastModified 是响应携带的缓存验证信息，下次请求时用于条件 GET
+    ETag         string
+    LastModified string
+    // NotModified 表示服务器返回 304，页面自上次请求以来没有变化，Content 为空
+    NotModified bool
+}
+
+// statusError 表示服务器返回了非 2xx 状态码
+type statusError struct {
+    URL        string
+    StatusCode int
+    // RetryAfter 429/503 响应中 Retry-After 要求等待的时间，没有时为 0
+    RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+    return fmt.Sprintf("unexpected status code %d for %s", e.StatusCode, e.URL)
+}
+
+// throttled 判断是否是论坛限流（429 或 503）
+func (e *statusError) throttled() bool {
+    return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和 HTTP 日期两种格式，无法解析或已过期时返回 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+    value = strings.TrimSpace(value)
+    if seconds, err := strconv.Atoi(value); err == nil {
+        return max(0, time.Duration(seconds)*time.Second)
+    }
+    if t, err := http.ParseTime(value); err == nil {
+        return max(0, t.Sub(now))
+    }
+    return 0
+}
+
+// fetchPageContent 发送 HTTP 请求并获取页面内容，ctx 被取消时立即返回 ctx 的错误。
+// cached 携带上次响应的 ETag/Last-Modified 时发送条件请求，不需要时传入零值；spec 为零值时发送 GET 请求
+func fetchPageContent(ctx context.Context, pageURL, userAgent string, cached page, spec requestSpec) (page, error) {
+    if err := ctx.Err(); err != nil {
+        return page{}, err
+    }
+    if err := robotsPolicy.Wait(ctx, pageURL, userAgent); err != nil {
+        return page{}, err
+    }
+    if err := rateLimit.Wait(ctx, pageURL); err != nil {
+        return page{}, err
+    }
+    if err := acquireRequest(ctx); err != nil {
+        return page{}, err
+    }
+
+    // fasthttp 不支持 context，超时取 fetchTimeout 与 ctx 截止时间中较早的一个
+    timeout := fetchTimeout
+    if deadline, ok := ctx.Deadline(); ok {
+        if remaining := time.Until(deadline); remaining < timeout {
+            timeout = remaining
+        }
+    }
+
+    fetchesTotal.Inc()
+    type result struct {
+        page page
+        err  error
+    }
+    done := make(chan result, 1)
+    go func() {
+        // ctx 取消后请求仍在进行，名额在请求真正结束时才归还
+        defer releaseRequest()
+        p, err := doFetchWithLogin(pageURL, userAgent, timeout, cached, spec)
+        done <- result{p, err}
+    }()
+
+    select {
+    case <-ctx.Done():
+        fetchErrorsTotal.Inc()
+        return page{}, ctx.Err()
+    case r := <-done:
+        if r.err != nil {
+            fetchErrorsTotal.Inc()
+        }
+        return r.page, r.err
+    }
+}
+
+// doFetch 使用共享客户端执行一次请求并返回解码后的页面内容，最多跟随 maxRedirects 次重定向
+func doFetch(pageURL, userAgent string, timeout time.Duration, cached page, spec requestSpec) (page, error) {
     req := fasthttp.AcquireRequest()
     defer fasthttp.ReleaseRequest(req)
     req.SetRequestURI(pageURL)
+    if spec.Method != "" {
+        req.Header.SetMethod(spec.Method)
+    }
+    if spec.Body != "" {
+        req.Header.SetContentType(spec.ContentType)
+        req.SetBodyString(spec.Body)
+    }
+    req.Header.Set("User-Agent", userAgent)
+    req.Header.Set("Accept-Encoding", "gzip, deflate")
+    for key, values := range requestHeaders {
+        for _, value := range values {
+            req.Header.Add(key, value)
+        }
+    }
+    fetchAuth.apply(req)
+    forumSession.apply(req)
+    if cached.ETag != "" {
+        req.Header.Set("If-None-Match", cached.ETag)
+    }
+    if cached.LastModified != "" {
+        req.Header.Set("If-Modified-Since", cached.LastModified)
+    }
+    req.SetTimeout(timeout)
 
     resp := fasthttp.AcquireResponse()
     defer fasthttp.ReleaseResponse(resp)
 
-    client := &fasthttp.Client{}
-    if err := client.Do(req, resp); err != nil {
-        return "", err
+    if err := httpClient.DoRedirects(req, resp, maxRedirects); err != nil {
+        switch {
+        case errors.Is(err, fasthttp.ErrTimeout):
+            return page{}, fmt.Errorf("fetch timeout for %s: %w", pageURL, err)
+        case errors.Is(err, fasthttp.ErrTooManyRedirects):
+            return page{}, fmt.Errorf("more than %d redirects for %s: %w", maxRedirects, pageURL, err)
+        case errors.Is(err, fasthttp.ErrBodyTooLarge):
+            return page{}, fmt.Errorf("response body of %s exceeds %d bytes (-max-body): %w", pageURL, httpClient.MaxResponseBodySize, err)
+        }
+        return page{}, err
+    }
+
+    // DoRedirects 会把每一跳的地址写回 req，请求结束后即为最终地址
+    traceExchange(req, resp)
+    finalURL := req.URI().String()
+    forumSession.store(string(req.URI().Host()), resp)
+    if resp.StatusCode() == fasthttp.StatusNotModified {
+        return page{URL: finalURL, ETag: cached.ETag, LastModified: cached.LastModified, NotModified: true}, nil
+    }
+    if code := resp.StatusCode(); code < 200 || code >= 300 {
+        se := &statusError{URL: finalURL, StatusCode: code}
+        if se.throttled() {
+            se.RetryAfter = parseRetryAfter(string(resp.Header.Peek("Retry-After")), time.Now())
+        }
+        return page{}, se
+    }
+
+    raw, err := responseBody(resp)
+    if err != nil {
+        return page{}, fmt.Errorf("decompress %s: %w", finalURL, err)
+    }
+
+    body, err := decodeToUTF8(raw, string(resp.Header.ContentType()))
+    if err != nil {
+        return page{}, fmt.Errorf("decode %s: %w", finalURL, err)
+    }
+    return page{
+        URL:          finalURL,
+        Content:      string(body),
+        ETag:         string(resp.Header.Peek("ETag")),
+        LastModified: string(resp.Header.Peek("Last-Modified")),
+    }, nil
+}
+
+// responseBody 根据 Content-Encoding 返回解压后的响应内容
+func responseBody(resp *fasthttp.Response) ([]byte, error) {
+    switch strings.ToLower(string(resp.Header.ContentEncoding())) {
+    case "gzip":
+        return resp.BodyGunzip()
+    case "deflate":
+        return resp.BodyInflate()
+    default:
+        return resp.Body(), nil
+    }
+}
+
+// metaCharsetRe 匹配 HTML 中 <meta charset> 或 http-equiv 声明的字符集
+var metaCharsetRe = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.-]+)`)
+
+// detectCharset 优先从 Content-Type 响应头获取字符集，其次从页面的 <meta charset> 获取
+func detectCharset(body []byte, contentType string) string {
+    if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+        return params["charset"]
+    }
+
+    head := body
+    if len(head) > 1024 {
+        head = head[:1024]
     }
+    if m := metaCharsetRe.FindSubmatch(head); m != nil {
+        return string(m[1])
+    }
+    return ""
+}
+
+// decodeToUTF8 将 GBK 等非 UTF-8 编码的页面内容转换为 UTF-8，已是 UTF-8 或无法识别时原样返回
+func decodeToUTF8(body []byte, contentType string) ([]byte, error) {
+    name := detectCharset(body, contentType)
+    if name == "" {
+        return body, nil
+    }
+
+    enc, err := htmlindex.Get(name)
+    if err != nil {
+        slog.Warn("未知的页面编码，按 UTF-8 处理", "charset", name)
+        return body, nil
+    }
+    if canonical, _ := htmlindex.Name(enc); canonical == "utf-8" {
+        return body, nil
+    }
+
+    return enc.NewDecoder().Bytes(body)
+}
+
+// isRetryableFetchError 判断请求错误是否值得重试：网络错误和 5xx 重试，4xx 直接失败
+func isRetryableFetchError(err error) bool {
+    if errors.Is(err, errRobotsDisallowed) || errors.Is(err, fasthttp.ErrTooManyRedirects) || errors.Is(err, fasthttp.ErrBodyTooLarge) {
+        return false
+    }
+    // 被限流时立即重试只会加重限流，交给监控循环退避
+    var se *statusError
+    if errors.As(err, &se) {
+        return se.StatusCode >= 500 && !se.throttled()
+    }
+    return true
+}
 
-    body := resp.Body()
-    return string(body), nil
+// fetchWithRetry 获取页面内容，遇到临时错误时按指数退避加随机抖动重试，最多尝试 attempts 次
+func fetchWithRetry(ctx context.Context, pageURL, userAgent string, attempts int, cached page, spec requestSpec) (page, error) {
+    for i := 1; ; i++ {
+        p, err := fetchPageContent(ctx, pageURL, userAgent, cached, spec)
+        if err == nil {
+            return p, nil
+        }
+        if i >= attempts || ctx.Err() != nil || !isRetryableFetchError(err) {
+            return page{}, err
+        }
+        if !allowRetry() {
+            slog.Warn("重试预算已用完，放弃重试", "url", pageURL, "attempt", i, "err", err)
+            return page{}, err
+        }
+
+        delay := retryBaseDelay<<(i-1) + time.Duration(rand.Int63n(int64(retryBaseDelay)))
+        slog.Warn("获取页面失败，稍后重试", "url", pageURL, "attempt", i, "delay", delay, "err", err)
+        if !sleepContext(ctx, delay) {
+            return page{}, ctx.Err()
+        }
+    }
 }
 
-// cleanText 清理文本内容，去除多余的空白字符
+// splitList 按逗号拆分列表参数，去掉空白和空项
+func splitList(s string) []string {
+    var items []string
+    for _, item := range strings.Split(s, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+// parseHeaders 解析 "Key: Value" 形式的请求头列表
+func parseHeaders(lines []string) (http.Header, error) {
+    headers := make(http.Header)
+    for _, line := range lines {
+        key, value, ok := strings.Cut(line, ":")
+        key = strings.TrimSpace(key)
+        if !ok || key == "" || strings.ContainsAny(key, " \t") {
+            return nil, fmt.Errorf("invalid header %q, expected \"Key: Value\"", line)
+        }
+        headers.Add(key, strings.TrimSpace(value))
+    }
+    return headers, nil
+}
+
+// cleanText 清理文本内容：合并行内连续的空白字符并去掉行首尾空白，保留换行，
+// 连续多个空行只保留一个，使分段的帖子保持可读
 func cleanText(text string) string {
-    // 去除所有多余的空白字符，包括空格和空行
-    return strings.Join(strings.Fields(text), " ")
+    text = strings.ReplaceAll(text, "\r\n", "\n")
+    text = strings.ReplaceAll(text, "\r", "\n")
+
+    var lines []string
+    blank := false
+    for _, line := range strings.Split(text, "\n") {
+        line = strings.Join(strings.Fields(line), " ")
+        if line == "" {
+            blank = len(lines) > 0
+            continue
+        }
+        if blank {
+            lines = append(lines, "")
+            blank = false
+        }
+        lines = append(lines, line)
+    }
+    return strings.Join(lines, "\n")
+}
+
+// truncateMessage 将内容截断为最多 maxLen 个字符（按 rune 计算），截断时追加省略号和帖子链接。maxLen 为 0 时不截断
+func truncateMessage(message, link string, maxLen int) string {
+    if maxLen <= 0 || utf8.RuneCountInString(message) <= maxLen {
+        return message
+    }
+    runes := []rune(message)
+    return strings.TrimRightFunc(string(runes[:maxLen]), unicode.IsSpace) + "…\n" + link
+}
+
+// Selectors 解析论坛页面使用的 CSS 选择器
+type Selectors struct {
+    List string `yaml:"list"`
+    // ListFallback List 没有匹配到帖子时依次尝试的备用列表选择器，用于同一论坛有多种页面布局的情况
+    ListFallback []string `yaml:"list_fallback"`
+    Title        string   `yaml:"title"`
+    // TitleFallback Title 没有匹配到标题时依次尝试的备用标题选择器，都没有时使用 <title> 中的标题
+    TitleFallback []string `yaml:"title_fallback"`
+    Message       string   `yaml:"message"`
+}
+
+// defaultSelectors 鱼C论坛使用的选择器
+var defaultSelectors = Selectors{
+    List:          "a.th_item",
+    Title:         "#myshares a",
+    TitleFallback: []string{"#thread_subject", "h1"},
+    Message:       ".message",
+}
+
+// withDefaults 用 def 中的值填充未设置的选择器
+func (s Selectors) withDefaults(def Selectors) Selectors {
+    // 备用选择器对应主选择器的页面布局，只在主选择器也取默认值时一起继承
+    if s.List == "" {
+        s.List = def.List
+        if len(s.ListFallback) == 0 {
+            s.ListFallback = def.ListFallback
+        }
+    }
+    if s.Title == "" {
+        s.Title = def.Title
+        if len(s.TitleFallback) == 0 {
+            s.TitleFallback = def.TitleFallback
+        }
+    }
+    if s.Message == "" {
+        s.Message = def.Message
+    }
+    return s
+}
+
+// Validate 检查选择器非空且语法正确
+func (s Selectors) Validate() error {
+    named := []struct{ name, sel string }{{"list", s.List}, {"title", s.Title}, {"message", s.Message}}
+    for _, sel := range s.ListFallback {
+        named = append(named, struct{ name, sel string }{"list fallback", sel})
+    }
+    for _, sel := range s.TitleFallback {
+        named = append(named, struct{ name, sel string }{"title fallback", sel})
+    }
+    for _, n := range named {
+        if strings.TrimSpace(n.sel) == "" {
+            return fmt.Errorf("%s selector must not be empty", n.name)
+        }
+        if _, err := cascadia.ParseGroup(n.sel); err != nil {
+            return fmt.Errorf("invalid %s selector %q: %w", n.name, n.sel, err)
+        }
+    }
+    return nil
+}
+
+// lists 返回按优先级排列的全部列表选择器
+func (s Selectors) lists() []string {
+    return append([]string{s.List}, s.ListFallback...)
 }
 
-// parsePostContent 解析帖子内容并获取第一个 id="myshares" 标签内的标题和第一个 class="message" 标签内的文本内容
-func parsePostContent(postURL string) (string, string) {
-    htmlContent, err := fetchPageContent(postURL)
+// parseListPosts 依次用各列表选择器解析论坛页面，返回第一个匹配到帖子的结果和所用的选择器，
+// 都没有匹配到时返回空结果和主选择器。返回的错误包装了 ErrParse
+func parseListPosts(htmlContent, baseURL string, selectors Selectors) ([]Post, string, error) {
+    for _, sel := range selectors.lists() {
+        posts, err := parseForumPosts(htmlContent, baseURL, sel)
+        if err != nil || len(posts) > 0 {
+            return posts, sel, wrapStage(ErrParse, baseURL, err)
+        }
+    }
+    return nil, selectors.List, nil
+}
+
+// parsePostContent 解析帖子内容并获取第一个标题选择器匹配元素的标题和第一个内容选择器匹配元素的文本内容。
+// 获取或解析失败时返回只有 URL 的帖子和包装了 ErrFetch 或 ErrParse 的错误
+func parsePostContent(ctx context.Context, fetcher Fetcher, postURL string, selectors Selectors, format string) (Post, error) {
+    post := Post{URL: postURL}
+
+    fetched, err := fetchPage(ctx, fetcher, postURL, page{}, requestSpec{})
     if err != nil {
-        log.Printf("获取帖子内容失败: %v", err)
-        return "", ""
+        return post, err
     }
 
-    doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+    doc, err := goquery.NewDocumentFromReader(strings.NewReader(fetched.Content))
     if err != nil {
-        log.Printf("解析帖子 HTML 失败: %v", err)
-        return "", ""
+        return post, wrapStage(ErrParse, postURL, fmt.Errorf("parse html: %w", err))
     }
 
-    // 提取第一个 id="myshares" 标签内的标题
-    title := doc.Find("#myshares a").First().Text()
+    // 依次用各标题选择器提取标题，都没有匹配到时使用 <title> 中的标题
+    title := ""
+    for _, sel := range append([]string{selectors.Title}, selectors.TitleFallback...) {
+        if title = strings.TrimSpace(doc.Find(sel).First().Text()); title != "" {
+            break
+        }
+    }
+    if title == "" {
+        title = pageTitle(doc)
+    }
 
-    // 提取第一个 class="message" 标签内的文本内容
-    message := doc.Find(".message").First().Text()
-    cleanedMessage := cleanText(message)
+    // 提取第一个内容元素内的文本内容，按配置转换为纯文本、Markdown 或 Telegram HTML
+    messageSel := doc.Find(selectors.Message).First()
+    base, _ := url.Parse(fetched.URL)
+    var cleanedMessage string
+    switch format {
+    case formatMarkdown:
+        cleanedMessage = htmlToMarkdown(messageSel, base)
+        post.Format = formatMarkdown
+    case formatHTML:
+        cleanedMessage = htmlToTelegram(messageSel, base)
+        post.Format = formatHTML
+    default:
+        cleanedMessage = cleanText(messageSel.Text())
+    }
 
     if cleanedMessage == "" {
-        cleanedMessage = "未找到内容"
+        cleanedMessage = msg("content.missing")
     }
 
-    return strings.TrimSpace(title), cleanedMessage
+    post.Title = strings.TrimSpace(title)
+    post.Message = cleanedMessage
+    post.Author, post.Time = parsePostAuthor(doc)
+    post.Images = parsePostImages(messageSel, base)
+    return post, nil
 }
 
-// parseForumPage 解析论坛页面内容并获取第一个 .th_item 元素中的链接
-func parseForumPage(htmlContent string, baseURL string) (string, string) {
-    doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
-    if err != nil {
-        log.Fatalf("解析 HTML 失败: %v", err)
+// titleSeparator 页面 <title> 中帖子标题与版块名、站点名之间的分隔符
+const titleSeparator = " - "
+
+// discuzTitleMarkers Discuz 页面 <title> 末尾的固定后缀
+var discuzTitleMarkers = []string{"Powered by", "手机版"}
+
+// pageTitle 从 <title> 中去掉站点名等后缀得到帖子标题。Discuz 电脑版的格式为
+// "帖子标题 - 版块名 - 站点名 - Powered by Discuz!"，手机版为 "帖子标题 - 站点名 - 手机版"，
+// 其它页面只去掉最后一段站点名
+func pageTitle(doc *goquery.Document) string {
+    parts := strings.Split(strings.TrimSpace(doc.Find("title").First().Text()), titleSeparator)
+    discuz := false
+    for len(parts) > 1 && slices.ContainsFunc(discuzTitleMarkers, func(m string) bool {
+        return strings.HasPrefix(strings.TrimSpace(parts[len(parts)-1]), m)
+    }) {
+        parts = parts[:len(parts)-1]
+        discuz = true
     }
+    drop := 1
+    if discuz && len(parts) > 2 {
+        drop = 2
+    }
+    if len(parts) > drop {
+        parts = parts[:len(parts)-drop]
+    }
+    return strings.TrimSpace(strings.Join(parts, titleSeparator))
+}
 
-    // 提取第一个 .th_item 元素中的链接
-    firstPost := doc.Find("a.th_item").First()
-    link, exists := firstPost.Attr("href")
-    if exists {
-        // 确保链接是完整的 URL
-        postURL := link
-        if !strings.HasPrefix(link, "http") {
-            base, err := url.Parse(baseURL)
-            if err != nil {
-                log.Fatalf("解析 baseURL 失败: %v", err)
-            }
-            relative, err := url.Parse(link)
-            if err != nil {
-                log.Fatalf("解析相对链接失败: %v", err)
+// parsePostAuthor 提取楼主的用户名和发帖时间，找不到时返回空字符串
+func parsePostAuthor(doc *goquery.Document) (string, string) {
+    authi := doc.Find(".authi")
+    author := strings.TrimSpace(authi.Find("a").First().Text())
+
+    // Discuz 对较新的帖子显示"3 天前"等相对时间，完整时间放在 span 的 title 属性中
+    span := authi.Find("em span").First()
+    postTime, _ := span.Attr("title")
+    if postTime == "" {
+        postTime = span.Text()
+    }
+    if postTime == "" {
+        postTime = strings.TrimPrefix(strings.TrimSpace(authi.Find("em").First().Text()), "发表于")
+    }
+
+    return author, strings.TrimSpace(postTime)
+}
+
+// forumLocation 解析发帖时间使用的时区，Discuz 论坛默认按北京时间显示
+var forumLocation = time.FixedZone("CST", 8*3600)
+
+// 发帖时间的绝对格式和相对格式
+var (
+    postTimeLayouts = []string{"2006-1-2 15:04:05", "2006-1-2 15:04", "2006-1-2"}
+    relativeTimeRe  = regexp.MustCompile(`^(\d+|半)\s*(秒|分钟|小时|天)前$`)
+    dayTimeRe       = regexp.MustCompile(`^(今天|昨天|前天)\s*(\d{1,2}):(\d{2})$`)
+)
+
+// parsePostTime 解析 parsePostAuthor 提取的发帖时间，支持 "2024-5-12 10:20" 这类绝对时间
+// 和 "3 天前"、"昨天 10:20"、"刚刚" 等 Discuz 的相对时间，无法解析时返回 false
+func parsePostTime(s string, now time.Time) (time.Time, bool) {
+    s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "发表于"))
+    for _, layout := range postTimeLayouts {
+        if t, err := time.ParseInLocation(layout, s, forumLocation); err == nil {
+            return t, true
+        }
+    }
+    if s == "刚刚" {
+        return now, true
+    }
+    if m := relativeTimeRe.FindStringSubmatch(s); m != nil {
+        n := 0.5
+        if m[1] != "半" {
+            v, _ := strconv.Atoi(m[1])
+            n = float64(v)
+        }
+        unit := map[string]time.Duration{"秒": time.Second, "分钟": time.Minute, "小时": time.Hour, "天": 24 * time.Hour}[m[2]]
+        return now.Add(-time.Duration(n * float64(unit))), true
+    }
+    if m := dayTimeRe.FindStringSubmatch(s); m != nil {
+        days := map[string]int{"今天": 0, "昨天": 1, "前天": 2}[m[1]]
+        hour, _ := strconv.Atoi(m[2])
+        minute, _ := strconv.Atoi(m[3])
+        local := now.In(forumLocation)
+        return time.Date(local.Year(), local.Month(), local.Day()-days, hour, minute, 0, 0, forumLocation), true
+    }
+    return time.Time{}, false
+}
+
+// maxPostImages 每个帖子最多转发的图片数量
+const maxPostImages = 4
+
+// minImageSize 宽或高小于该像素值的图片视为表情或图标，不转发
+const minImageSize = 64
+
+// parsePostImages 收集内容元素中的图片地址并解析为绝对 URL，跳过表情、占位图和过小的图片
+func parsePostImages(sel *goquery.Selection, base *url.URL) []string {
+    var images []string
+    seen := make(map[string]bool)
+    sel.Find("img").EachWithBreak(func(_ int, img *goquery.Selection) bool {
+        // Discuz 的附件图片延迟加载，真实地址放在 zoomfile 或 file 属性中，src 只是占位图
+        var src string
+        for _, key := range []string{"zoomfile", "file", "src"} {
+            if v, ok := img.Attr(key); ok && strings.TrimSpace(v) != "" {
+                src = v
+                break
             }
-            postURL = base.ResolveReference(relative).String()
         }
+        if _, ok := img.Attr("smilieid"); ok || strings.Contains(src, "/smiley/") || strings.HasSuffix(src, "none.gif") {
+            return true
+        }
+        if isTinyImage(img) {
+            return true
+        }
+
+        link := resolveMarkdownLink(base, src)
+        if link == "" || seen[link] {
+            return true
+        }
+        seen[link] = true
+        images = append(images, link)
+        return len(images) < maxPostImages
+    })
+    return images
+}
 
-        return postURL, firstPost.Text()
+// isTinyImage 根据 width/height 属性判断图片是否过小，没有尺寸信息时返回 false
+func isTinyImage(img *goquery.Selection) bool {
+    for _, key := range []string{"width", "height"} {
+        v, ok := img.Attr(key)
+        if !ok {
+            continue
+        }
+        if size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(v), "px")); err == nil && size < minImageSize {
+            return true
+        }
     }
-    return "", ""
+    return false
 }
 
-// sendToTelegram 发送消息到Telegram频道
-func sendToTelegram(botToken, chatID, message string) error {
-    apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
-    data := url.Values{}
-    data.Set("chat_id", chatID)
-    data.Set("text", message)
+// Post 表示一个论坛帖子，列表页只填充 URL 和 Title，帖子页会补充其余字段
+type Post struct {
+    URL     string
+    Title   string
+    Message string
+    Author  string
+    Time    string
+    Images  []string
+    // Replies 和 Views 是列表页上显示的回复数和查看数，-1 表示页面上没有或无法解析
+    Replies int
+    Views   int
+    // Format 为 formatHTML 时 Message 是 Telegram HTML，为 formatMarkdown 时是 Telegram MarkdownV2，否则是纯文本
+    Format string
+    // Sticky 为 true 表示帖子在列表页上置顶
+    Sticky bool
+}
 
-    resp, err := http.PostForm(apiURL, data)
+// parseForumPage 解析论坛页面内容并获取第一个列表项中的帖子，页面中没有帖子时返回 nil
+func parseForumPage(htmlContent, baseURL, listSelector string) (*Post, error) {
+    posts, err := parseForumPosts(htmlContent, baseURL, listSelector)
     if err != nil {
-        return err
+        return nil, err
     }
-    defer resp.Body.Close()
-
-    if resp.StatusCode != http.StatusOK {
-        return fmt.Errorf("failed to send message to Telegram, status code: %d", resp.StatusCode)
+    if len(posts) == 0 {
+        return nil, nil
     }
-
-    return nil
+    return &posts[0], nil
 }
 
-// monitorForum 持续监控论坛页面
-func monitorForum(botToken, chatID string, interval time.Duration) {
-    baseURL := "https://fishc.com.cn/forum.php?mod=guide&view=newthread&mobile=2" // 固定的鱼C论坛 URL
-    var lastPostURL string
+// parseForumPosts 解析论坛页面内容，按页面顺序返回列表选择器匹配的所有帖子。
+// 整个页面无法解析时返回错误，单个链接无法解析时跳过该帖子
+func parseForumPosts(htmlContent, baseURL, listSelector string) ([]Post, error) {
+    doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+    if err != nil {
+        return nil, fmt.Errorf("parse html: %w", err)
+    }
+    base, err := url.Parse(baseURL)
+    if err != nil {
+        return nil, fmt.Errorf("parse base url %s: %w", baseURL, err)
+    }
 
-    for {
-        // 获取页面内容
-        htmlContent, err := fetchPageContent(baseURL)
+    var posts []Post
+    doc.Find(listSelector).Each(func(_ int, item *goquery.Selection) {
+        link, exists := item.Attr("href")
+        if !exists {
+            return
+        }
+
+        postURL, err := resolveLink(base, link)
         if err != nil {
-            log.Printf("获取页面内容失败: %v", err)
-            time.Sleep(interval)
-            continue
+            slog.Warn("跳过无法解析的帖子链接", "url", baseURL, "link", link, "err", err)
+            return
         }
 
-        // 解析页面内容并获取第一个 .th_item 元素中的链接
-        postURL, _ := parseForumPage(htmlContent, baseURL)
-        if postURL != "" && postURL != lastPostURL {
-            lastPostURL = postURL
+        replies, views := parseCounts(item)
+        posts = append(posts, Post{
+            URL:     postURL,
+            Title:   strings.TrimSpace(item.Text()),
+            Time:    parseListTime(item),
+            Replies: replies,
+            Views:   views,
+            Sticky:  item.Closest(stickySelector).Length() > 0,
+        })
+    })
+    return posts, nil
+}
 
-            // 获取帖子内容
-            title, message := parsePostContent(postURL)
-            telegramMessage := fmt.Sprintf("标题: %s\n链接: %s\n帖子内容: %s", title, postURL, message)
-            err := sendToTelegram(botToken, chatID, telegramMessage)
-            if err != nil {
-                log.Printf("发送消息到Telegram失败: %v", err)
-            } else {
-                log.Printf("消息已发送到Telegram: %s", telegramMessage)
+// 列表项中回复数和查看数所在元素的选择器，依次兼容电脑版 (td.num) 和手机版页面
+const (
+    replyCountSelector = "td.num a, .num a, .replies, .reply"
+    viewCountSelector  = "td.num em, .num em, .views, .view"
+)
+
+// stickySelector 匹配置顶帖列表项或其所在的行，Discuz 电脑版置顶帖位于 id 为 stickthread_ 开头的 tbody 中
+const stickySelector = ".sticky, .stick, tbody[id^=stickthread_]"
+
+// 列表项中发帖时间所在元素的选择器，Discuz 电脑版最近的帖子显示相对时间，完整时间在 span 的 title 中
+const (
+    listTimeTitleSelector = "td.by em span[title]"
+    listTimeSelector      = "td.by em, .time, .date"
+)
+
+// parseListTime 在列表项所在的行中查找发帖时间，找不到时返回空字符串
+func parseListTime(item *goquery.Selection) string {
+    row := item.Closest("tr, li")
+    if row.Length() == 0 {
+        row = item
+    }
+    if title, ok := row.Find(listTimeTitleSelector).First().Attr("title"); ok {
+        return strings.TrimSpace(title)
+    }
+    return strings.TrimSpace(row.Find(listTimeSelector).First().Text())
+}
+
+// parseCounts 在列表项所在的行中查找回复数和查看数，找不到或无法解析的返回 -1
+func parseCounts(item *goquery.Selection) (replies, views int) {
+    row := item.Closest("tr, li")
+    if row.Length() == 0 {
+        row = item
+    }
+    return parseCount(row.Find(replyCountSelector).First().Text()), parseCount(row.Find(viewCountSelector).First().Text())
+}
+
+// parseCount 解析 "1,234"、"1.2万"、"3k" 这类计数，无法解析时返回 -1
+func parseCount(s string) int {
+    s = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), ",", ""))
+    multiplier := 1.0
+    switch {
+    case strings.HasSuffix(s, "万"):
+        s, multiplier = strings.TrimSuffix(s, "万"), 10000
+    case strings.HasSuffix(s, "k"):
+        s, multiplier = strings.TrimSuffix(s, "k"), 1000
+    }
+    n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+    if err != nil || n < 0 {
+        return -1
+    }
+    return int(n * multiplier)
+}
+
+// nextPageURL 返回列表的第 n 页地址：优先使用 Discuz 分页栏中的“下一页”链接，
+// 找不到时在当前地址上设置 page=n 参数
+func nextPageURL(htmlContent, pageURL string, n int) string {
+    base, err := url.Parse(pageURL)
+    if err != nil {
+        return ""
+    }
+    if doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent)); err == nil {
+        if href, ok := doc.Find("a.nxt").First().Attr("href"); ok {
+            if next, err := resolveLink(base, href); err == nil {
+                return next
             }
         }
+    }
 
-        time.Sleep(interval)
+    q := base.Query()
+    q.Set("page", strconv.Itoa(n))
+    next := *base
+    next.RawQuery = q.Encode()
+    return next.String()
+}
+
+// resolveLink 确保链接是完整的 URL，相对链接基于 base 解析
+func resolveLink(base *url.URL, link string) (string, error) {
+    if strings.HasPrefix(link, "http") {
+        return link, nil
+    }
+    relative, err := url.Parse(link)
+    if err != nil {
+        return "", err
     }
+    return base.ResolveReference(relative).String(), nil
+}
+
+// 去重方式
+const (
+    dedupURL  = "url"
+    dedupHash = "hash"
+)
+
+// contentHash 计算帖子标题和内容的 SHA-256，用于按内容去重
+func contentHash(p Post) string {
+    sum := sha256.Sum256([]byte(p.Title + "\x00" + p.Message))
+    return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// maxSeenPosts 最多记住的已通知帖子数量，用于限制内存占用
+const maxSeenPosts = 200
+
+// seenSet 记录已通知过的帖子 URL，超过容量时淘汰最早加入的记录
+type seenSet struct {
+    max   int
+    order []string
+    items map[string]struct{}
+}
+
+// newSeenSet 创建容量为 max 的 seenSet
+func newSeenSet(max int) *seenSet {
+    return &seenSet{max: max, items: make(map[string]struct{})}
+}
+
+// Has 判断 key 是否已记录
+func (s *seenSet) Has(key string) bool {
+    _, ok := s.items[key]
+    return ok
+}
+
+// Len 返回已记录的 key 数量
+func (s *seenSet) Len() int {
+    return len(s.order)
+}
+
+// Keys 按加入顺序返回所有已记录的 key
+func (s *seenSet) Keys() []string {
+    return append([]string(nil), s.order...)
+}
+
+// Add 记录 key，超出容量时移除最早的记录
+func (s *seenSet) Add(key string) {
+    if s.Has(key) {
+        return
+    }
+    s.items[key] = struct{}{}
+    s.order = append(s.order, key)
+    if len(s.order) > s.max {
+        delete(s.items, s.order[0])
+        s.order = s.order[1:]
+    }
+}
+
+// sleepContext 等待指定时间，若 ctx 先被取消则提前返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+
+    select {
+    case <-ctx.Done():
+        return false
+    case <-timer.C:
+        return true
+    }
+}
+
+// fatal 记录错误日志后退出程序
+func fatal(msg string, args ...any) {
+    slog.Error(msg, args...)
+    os.Exit(1)
+}
+
+// setupLogger 设置全局结构化日志的输出级别
+func setupLogger(level slog.Level) {
+    handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+    slog.SetDefault(slog.New(handler))
 }
 
 func main() {
-    // 定义命令行参数
-    botToken := flag.String("token", "", "Telegram Bot API Token")
-    chatID := flag.String("chatid", "", "Telegram Chat ID")
+    // 合并配置文件和命令行参数
+    cfg, err := loadConfig(os.Args[1:])
+    if err != nil {
+        fatal("加载配置失败", "err", err)
+    }
+    if cfg.ShowVersion {
+        fmt.Println(versionString())
+        return
+    }
+    if err := cfg.Validate(); err != nil {
+        fatal("配置错误", "err", err)
+    }
+    profiles, err := cfg.profiles()
+    if err != nil {
+        fatal("配置错误", "err", err)
+    }
+    if cfg.PrintConfig {
+        if err := printConfig(os.Stdout, cfg, profiles); err != nil {
+            fatal("输出配置失败", "err", err)
+        }
+        return
+    }
+    setupLogger(cfg.logLevel())
+    lang = cfg.Lang
+    traceFetch = cfg.Trace
+    slog.Info(msg("log.started"), "version", version, "commit", commit, "build_date", buildDate)
+
+    // 配置代理
+    if cfg.Proxy != "" {
+        dial, err := proxyDialer(cfg.Proxy)
+        if err != nil {
+            fatal("代理配置错误", "proxy", cfg.Proxy, "err", err)
+        }
+        httpClient.Dial = dial
+    }
+    if dial := ipDialer(cfg.IPVersion); dial != nil {
+        httpClient.Dial = dial
+    }
+
+    // 配置 TLS，跳过证书校验会让中间人攻击无法被发现，只应在测试或可信网络中使用
+    if cfg.CAFile != "" || cfg.InsecureSkipVerify {
+        tc, err := tlsConfig(cfg.CAFile, cfg.InsecureSkipVerify)
+        if err != nil {
+            fatal("TLS 配置错误", "err", err)
+        }
+        httpClient.TLSConfig = tc
+    }
+    if cfg.InsecureSkipVerify {
+        slog.Warn("已关闭 TLS 证书校验，连接可能被中间人窃听或篡改，请勿在生产环境使用")
+    }
+
+    httpClient.MaxResponseBodySize = cfg.MaxBody
+
+    // 自定义请求头已在 Validate 中检查过
+    requestHeaders, _ = parseHeaders(cfg.Headers)
+
+    // 配置访问需要登录的版块所用的认证信息，Validate 已检查过格式
+    fetchAuth.Cookie = cfg.Cookie
+    if cfg.BasicAuth != "" {
+        fetchAuth.Username, fetchAuth.Password, _ = parseBasicAuth(cfg.BasicAuth)
+    }
+
+    if cfg.ForumUsername != "" {
+        forumSession = newDiscuzSession(cfg.ForumUsername, cfg.ForumPassword)
+    }
+
+    // 遵守 robots.txt
+    if !cfg.IgnoreRobots {
+        robotsPolicy = newRobotsCache()
+    }
+    if cfg.Rate > 0 {
+        rateLimit = newHostLimiter(cfg.Rate)
+    }
+    if cfg.RetryBudget > 0 {
+        retryBudget = newRetryBudget(cfg.RetryBudget)
+    }
+    if cfg.MaxConcurrency > 0 {
+        requestSlots = make(chan struct{}, cfg.MaxConcurrency)
+    }
+
+    if cfg.SelfCheck {
+        if !runSelfCheck(context.Background(), cfg, cfg.SelfCheckSend, os.Stdout) {
+            os.Exit(1)
+        }
+        return
+    }
+
+    if cfg.ReplayDeadLetter {
+        notifier, err := buildNotifier(cfg)
+        if err != nil {
+            fatal("创建通知渠道失败", "err", err)
+        }
+        sent, failed, err := replayDeadLetters(context.Background(), cfg.DeadLetter, deadLetterTargets(notifier))
+        if err != nil {
+            fatal("重新发送死信失败", "path", cfg.DeadLetter, "err", err)
+        }
+        fmt.Printf("重新发送成功 %d 条，失败 %d 条\n", sent, failed)
+        if failed > 0 {
+            os.Exit(1)
+        }
+        return
+    }
+
+    // 收到 SIGINT/SIGTERM 时取消 ctx，让监控循环正常退出
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    if cfg.ListSelectors {
+        fetcher, err := newFetcher(cfg)
+        if err != nil {
+            fatal("创建页面缓存失败", "err", err)
+        }
+        if err := listSelectors(ctx, cfg, fetcher, os.Stdout); err != nil {
+            fatal("检查选择器失败", "err", err)
+        }
+        return
+    }
+
+    // 启动指标、健康检查和订阅源服务，监听地址相同时共用一个服务
+    var health *healthTracker
+    var feed *feedNotifier
+    muxes := make(map[string]*http.ServeMux)
+    muxFor := func(addr string) *http.ServeMux {
+        if muxes[addr] == nil {
+            muxes[addr] = http.NewServeMux()
+        }
+        return muxes[addr]
+    }
+    if cfg.MetricsAddr != "" {
+        muxFor(cfg.MetricsAddr).Handle("/metrics", metricsHandler())
+    }
+    if cfg.HealthAddr != "" {
+        var urls []string
+        for _, p := range profiles {
+            for _, forum := range p.Config.forums() {
+                urls = append(urls, forum.URL)
+            }
+        }
+        health = newHealthTracker(urls, healthThreshold(cfg, profiles))
+        muxFor(cfg.HealthAddr).Handle("/healthz", health)
+    }
+    if cfg.FeedAddr != "" {
+        feed = newFeedNotifier("yuc", profiles[0].Config.forumLink(), cfg.FeedSize)
+        muxFor(cfg.FeedAddr).Handle("/feed", feed)
+    }
+    for addr, mux := range muxes {
+        serveHTTP(ctx, addr, mux)
+    }
 
-    // 解析命令行参数
-    flag.Parse()
+    // 多个配置方案使用同一个状态文件时共用一个存储，避免互相覆盖
+    stores := make(map[string]func(string) SeenStore)
+    storeFor := func(c *Config) (func(string) SeenStore, error) {
+        key := c.Store + ":" + c.State
+        if stores[key] == nil {
+            s, err := openStore(ctx, c.Store, c.State, c.StoreMaxAge, c.BloomCapacity, c.BloomFPRate)
+            if err != nil {
+                return nil, err
+            }
+            stores[key] = s
+        }
+        return stores[key], nil
+    }
 
-    // 检查必需的参数是否已提供
-    if *botToken == "" || *chatID == "" {
-        log.Fatalf("必须提供Telegram Bot API Token和Chat ID")
+    // 开始监控所有配置方案中的论坛页面
+    var monitors []monitorOptions
+    var watchers []*editWatcher
+    var targets []*targetsWatcher
+    running := make(map[string]*runningProfile)
+    for _, p := range profiles {
+        store, err := storeFor(p.Config)
+        if err != nil {
+            fatal("打开去重记录存储失败", "profile", p.Name, "store", p.Config.Store, "path", p.Config.State, "err", err)
+        }
+        live := &liveSettings{}
+        running[p.Name] = &runningProfile{cfg: p.Config, live: live}
+        ms, watcher, tw, err := buildMonitors(p.Config, store, feed, health, live)
+        if err != nil {
+            fatal("创建监控失败", "profile", p.Name, "err", err)
+        }
+        monitors = append(monitors, ms...)
+        if watcher != nil {
+            watchers = append(watchers, watcher)
+        }
+        if tw != nil {
+            targets = append(targets, tw)
+        }
+    }
+    if cfg.Once {
+        for _, tw := range targets {
+            ms, err := tw.monitors()
+            if err != nil {
+                fatal("读取目标文件失败", "err", err)
+            }
+            monitors = append(monitors, ms...)
+        }
+        err := runOnce(ctx, monitors)
+        // 免打扰时段内暂存的通知在退出前发出
+        stop()
+        quietFlushes.Wait()
+        if err != nil {
+            fatal("单次检查失败", "err", err)
+        }
+        return
+    }
+    for _, w := range watchers {
+        go w.run(ctx)
     }
 
-    // 设置监控间隔时间
-    interval := 30 * time.Second
+    // 收到 SIGHUP 时重新加载配置，已记录的帖子等内存状态保持不变
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+    go func() {
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-hup:
+                slog.Info("收到 SIGHUP，重新加载配置")
+                reloadConfig(os.Args[1:], running, feed, health)
+            }
+        }
+    }()
+    // 目标文件中的论坛由 targetsWatcher 启动和停止，退出前等待其中的监控全部结束
+    var wg sync.WaitGroup
+    for _, tw := range targets {
+        wg.Add(1)
+        go func(tw *targetsWatcher) {
+            defer wg.Done()
+            tw.run(ctx)
+        }(tw)
+    }
+    runMonitors(ctx, monitors)
+    wg.Wait()
+    quietFlushes.Wait()
+    slog.Info(msg("log.stopped"))
+}
 
-    // 开始监控论坛页面
-    monitorForum(*botToken, *chatID, interval)
-}
\ No newline at end of file
+// buildMonitors 按一个配置方案创建配置文件中每个论坛的监控参数，以及配置了关注帖子时的 editWatcher
+// 和配置了目标文件时的 targetsWatcher。方案内的论坛共用通知渠道、过滤规则和页面获取方式，
+// 以及可重新加载的 live；feed 和 health 不为 nil 时所有方案共用
+func buildMonitors(cfg *Config, storeFor func(string) SeenStore, feed *feedNotifier, health *healthTracker, live *liveSettings) ([]monitorOptions, *editWatcher, *targetsWatcher, error) {
+    fetcher, err := newFetcher(cfg)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    notifier, err := buildNotifier(cfg)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    operator, err := buildOperatorNotifier(cfg)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    notifier = withQuietHours(cfg, notifier)
+    if feed != nil {
+        notifier = multiNotifier{notifier, feed}
+    }
+    filter, err := newFilter(cfg.Include, cfg.Exclude, cfg.CaseSensitive)
+    if err != nil {
+        return nil, nil, nil, err
+    }
+    live.set(cfg.Interval, cfg.Jitter, cfg.WatchInterval, filter, notifier, operator)
+    live.setPostRules(cfg)
+    var watcher *editWatcher
+    if len(cfg.Watch) > 0 {
+        watcher = newEditWatcher(cfg.Watch, fetcher, cfg.Selectors, cfg.Format, cfg.MaxLen, live, storeFor(watchStoreKey))
+    }
+    base := monitorOptions{
+        Fetcher:          fetcher,
+        Interval:         cfg.Interval,
+        Jitter:           cfg.Jitter,
+        Format:           cfg.Format,
+        MaxLen:           cfg.MaxLen,
+        Filter:           filter,
+        Dedup:            cfg.Dedup,
+        Batch:            cfg.Batch,
+        CanonicalStrip:   cfg.CanonicalStrip,
+        BatchSort:        cfg.BatchSort,
+        MinReplies:       cfg.MinReplies,
+        MinAge:           cfg.MinAge,
+        SkipSticky:       cfg.SkipSticky,
+        MaxAge:           cfg.MaxAge,
+        DropUnknownAge:   cfg.AgeUnknown == ageDrop,
+        SkipInitial:      cfg.SkipInitial,
+        CatchUpPages:     cfg.CatchUpPages,
+        Since:            cfg.sinceTime(),
+        BreakerThreshold: cfg.BreakerThreshold,
+        BreakerCooldown:  cfg.BreakerCooldown,
+        EmptyAlertCycles: cfg.EmptyAlertCycles,
+        Concurrency:      cfg.Concurrency,
+        Request:          cfg.listRequest(),
+        CycleTimeout:     cfg.CycleTimeout,
+        MinSleep:         cfg.MinSleep,
+        Notifier:         notifier,
+        Operator:         operator,
+        Health:           health,
+        Live:             live,
+    }
+    var monitors []monitorOptions
+    static := cfg.staticForums()
+    for _, forum := range static {
+        monitors = append(monitors, base.forForum(forum, storeFor))
+    }
+    var targets *targetsWatcher
+    if cfg.TargetsFile != "" {
+        targets = newTargetsWatcher(cfg.TargetsFile, cfg.Selectors, base, storeFor, static)
+    }
+    return monitors, watcher, targets, nil
+}